@@ -0,0 +1,181 @@
+package abi
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// word left pads a hex value to an abi word.
+func word(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		signature     string
+		wantSignature string
+		wantSelector  string
+	}{
+		{signature: "transfer(address,uint256)", wantSignature: "transfer(address,uint256)", wantSelector: "0xa9059cbb"},
+		{signature: "transfer(address, uint)", wantSignature: "transfer(address,uint256)", wantSelector: "0xa9059cbb"},
+		{signature: "approve(address,uint256)", wantSignature: "approve(address,uint256)", wantSelector: "0x095ea7b3"},
+		{
+			signature:     "fill((address,uint256)[],bytes32)",
+			wantSignature: "fill((address,uint256)[],bytes32)",
+			wantSelector:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.signature, func(t *testing.T) {
+			method, err := ParseSignature(tt.signature)
+			if err != nil {
+				t.Fatalf("ParseSignature() error = %v, want nil", err)
+			}
+
+			if method.Signature != tt.wantSignature {
+				t.Errorf("Signature = %v, want %v", method.Signature, tt.wantSignature)
+			}
+
+			if tt.wantSelector != "" && method.Selector.String() != tt.wantSelector {
+				t.Errorf("Selector = %v, want %v", method.Selector, tt.wantSelector)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"transfer", "transfer(address,uint7)", "transfer((address)", "(address)"} {
+		if _, err := ParseSignature(invalid); err == nil {
+			t.Errorf("ParseSignature(%q) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestDecodeCalldata(t *testing.T) {
+	registry, err := NewRegistry("register(string,int256,(bool,bytes2))")
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		calldata string
+		want     string
+	}{
+		{
+			name:     "erc20 transfer",
+			calldata: "0xa9059cbb" + word("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed") + word("de0b6b3a7640000"),
+			want: `{"method":"transfer","signature":"transfer(address,uint256)","selector":"0xa9059cbb",` +
+				`"arguments":[{"type":"address","value":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},` +
+				`{"type":"uint256","value":"1000000000000000000"}]}`,
+		},
+		{
+			name: "swap with dynamic address path",
+			calldata: "0x38ed1739" + word("64") + word("1") + word("a0") +
+				word("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed") + word("ff") +
+				word("2") + word("fb6916095ca1df60bb79ce92ce3ea74c37c5d359") + word("dbf03b407c01e7cd3cbea99509d93f8dddc8c6fb"),
+			want: `{"method":"swapExactTokensForTokens",` +
+				`"signature":"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",` +
+				`"selector":"0x38ed1739","arguments":[{"type":"uint256","value":"100"},{"type":"uint256","value":"1"},` +
+				`{"type":"address[]","value":["0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",` +
+				`"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"]},` +
+				`{"type":"address","value":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},` +
+				`{"type":"uint256","value":"255"}]}`,
+		},
+		{
+			name: "string, negative int and static tuple",
+			calldata: "0x" + mustSelector(t, "register(string,int256,(bool,bytes2))") + word("80") +
+				strings.Repeat("f", 64) + word("1") + "abcd" + strings.Repeat("0", 60) +
+				word("3") + "646f67" + strings.Repeat("0", 58),
+			want: `{"method":"register","signature":"register(string,int256,(bool,bytes2))",` +
+				`"selector":"0x` + mustSelector(t, "register(string,int256,(bool,bytes2))") + `",` +
+				`"arguments":[{"type":"string","value":"dog"},{"type":"int256","value":"-1"},` +
+				`{"type":"(bool,bytes2)","value":[true,"0xabcd"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := registry.DecodeCalldata(tt.calldata)
+			if err != nil {
+				t.Fatalf("DecodeCalldata() error = %v, want nil", err)
+			}
+
+			got, err := json.Marshal(call)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("DecodeCalldata() = %s, %v\nwant %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidCalldata(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		calldata string
+		wantErr  error
+	}{
+		{name: "unknown selector", calldata: "0x12345678", wantErr: ErrUnknownSelector},
+		{name: "no selector", calldata: "0x", wantErr: ErrInvalidData},
+		{name: "truncated arguments", calldata: "0xa9059cbb" + word("1"), wantErr: ErrInvalidData},
+		{name: "huge slice length", calldata: "0x5ae401dc" + word("1") + word("40") + word("ffffff"), wantErr: ErrInvalidData},
+		{
+			// 64 bytes values whose offsets all point at the same 1 KB value.
+			name: "aliased offsets",
+			calldata: "0x5ae401dc" + word("1") + word("40") + word("40") + strings.Repeat(word("800"), 64) +
+				word("400") + strings.Repeat("ab", 1024),
+			wantErr: ErrInvalidData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := registry.DecodeCalldata(tt.calldata); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodeCalldata() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signatures.json")
+
+	content := `{"0x2e1a7d4d": "withdraw(uint256)", "0xd0e30db0": "deposit()", "0x70a08231": "balanceOf(address)"}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v, want nil", err)
+	}
+
+	call, err := registry.DecodeCalldata("0x70a08231" + word("1"))
+	if err != nil || call.Method != "balanceOf" {
+		t.Errorf("DecodeCalldata() = %v, %v, want balanceOf call", call, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"0x12345678": "deposit()"}`), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if _, err := LoadRegistry(path); !errors.Is(err, ErrSelectorMismatch) {
+		t.Errorf("LoadRegistry() error = %v, want %v", err, ErrSelectorMismatch)
+	}
+}
+
+func mustSelector(t *testing.T, signature string) string {
+	t.Helper()
+
+	method, err := ParseSignature(signature)
+	if err != nil {
+		t.Fatalf("ParseSignature() error = %v", err)
+	}
+
+	return method.Selector.String()[2:]
+}
@@ -0,0 +1,260 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+var (
+	ErrInvalidSignature = errors.New("abi: invalid function signature")
+	ErrInvalidData      = errors.New("abi: invalid encoded data")
+	ErrSelectorMismatch = errors.New("abi: selector mismatch")
+)
+
+// SelectorLength is the length of a function selector in bytes.
+const SelectorLength = 4
+
+// maxDecodedLength bounds the length of decoded slices and byte strings so malformed
+// calldata cannot make the decoder allocate huge amounts of memory.
+const maxDecodedLength = 1 << 20
+
+// Selector is the first 4 bytes of the Keccak-256 hash of a function signature.
+type Selector [SelectorLength]byte
+
+// String returns the "0x" prefixed hex encoding of the selector.
+func (s Selector) String() string {
+	return "0x" + hex.EncodeToString(s[:])
+}
+
+// Method is a contract function parsed from its signature, e.g. transfer(address,uint256).
+type Method struct {
+	Name      string
+	Inputs    []Type
+	Signature string
+	Selector  Selector
+}
+
+// ParseSignature parses a function signature of the form name(type1,type2,...).
+func ParseSignature(signature string) (Method, error) {
+	signature = strings.ReplaceAll(signature, " ", "")
+
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return Method{}, fmt.Errorf("%w: %q", ErrInvalidSignature, signature)
+	}
+
+	inputs, err := parseTypeList(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return Method{}, fmt.Errorf("%w: %q: %w", ErrInvalidSignature, signature, err)
+	}
+
+	name := signature[:open]
+	canonical := name + "(" + joinTypes(inputs) + ")"
+
+	return Method{
+		Name:      name,
+		Inputs:    inputs,
+		Signature: canonical,
+		Selector:  Selector(blockchain.Keccak256([]byte(canonical))[:SelectorLength]),
+	}, nil
+}
+
+// DecodeInput decodes the arguments of calldata made of the method's selector followed
+// by its abi encoded arguments.
+func (m Method) DecodeInput(calldata []byte) ([]any, error) {
+	if len(calldata) < SelectorLength || Selector(calldata[:SelectorLength]) != m.Selector {
+		return nil, ErrSelectorMismatch
+	}
+
+	return DecodeArguments(m.Inputs, calldata[SelectorLength:])
+}
+
+// DecodeArguments decodes abi encoded values of the given types. values are returned as
+// *big.Int for integers, blockchain.Address for addresses, bool, []byte for fixed and
+// dynamic bytes, string, and []any for arrays and tuples.
+func DecodeArguments(types []Type, data []byte) ([]any, error) {
+	d := &decoder{budget: len(data)}
+
+	return d.decodeTuple(types, data)
+}
+
+// decoder decodes abi encoded values. every decoded value is charged the size of its
+// encoding against a budget of the size of the data, so offsets pointing at the same
+// encoded value cannot make the decoder allocate more than the data it was given.
+type decoder struct {
+	budget int
+}
+
+// charge takes the size of a decoded value from the budget of the decoder.
+func (d *decoder) charge(size int) error {
+	if size > d.budget {
+		return fmt.Errorf("%w: decoded values are larger than the data", ErrInvalidData)
+	}
+
+	d.budget -= size
+
+	return nil
+}
+
+// decodeTuple decodes the values of a tuple whose encoding starts at data[0]. offsets of
+// dynamic values are relative to the start of the tuple.
+func (d *decoder) decodeTuple(types []Type, data []byte) ([]any, error) {
+	values := make([]any, 0, len(types))
+	head := 0
+
+	for _, t := range types {
+		var (
+			value any
+			err   error
+		)
+
+		if t.isDynamic() {
+			offset, offsetErr := readLength(data, head)
+			if offsetErr != nil {
+				return nil, offsetErr
+			}
+
+			if offset > len(data) {
+				return nil, fmt.Errorf("%w: offset %d out of range", ErrInvalidData, offset)
+			}
+
+			value, err = d.decodeValue(t, data[offset:])
+		} else {
+			if head+t.headSize() > len(data) {
+				return nil, fmt.Errorf("%w: data too short for %s", ErrInvalidData, t)
+			}
+
+			value, err = d.decodeValue(t, data[head:])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+		head += t.headSize()
+	}
+
+	return values, nil
+}
+
+// decodeValue decodes a single value of type t encoded at the start of data.
+func (d *decoder) decodeValue(t Type, data []byte) (any, error) {
+	switch t.Kind {
+	case TupleKind:
+		return d.decodeTuple(t.Components, data)
+	case ArrayKind:
+		return d.decodeTuple(repeatType(*t.Elem, t.Size), data)
+	case SliceKind:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if length*t.Elem.headSize() > len(data)-wordSize {
+			return nil, fmt.Errorf("%w: %s length %d out of range", ErrInvalidData, t, length)
+		}
+
+		if err := d.charge(wordSize); err != nil {
+			return nil, err
+		}
+
+		return d.decodeTuple(repeatType(*t.Elem, length), data[wordSize:])
+	case BytesKind, StringKind:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if wordSize+length > len(data) {
+			return nil, fmt.Errorf("%w: %s length %d out of range", ErrInvalidData, t, length)
+		}
+
+		if err := d.charge(wordSize + length); err != nil {
+			return nil, err
+		}
+
+		value := append([]byte{}, data[wordSize:wordSize+length]...)
+		if t.Kind == StringKind {
+			return string(value), nil
+		}
+
+		return value, nil
+	case UintKind, IntKind, AddressKind, BoolKind, FixedBytesKind:
+		if len(data) < wordSize {
+			return nil, fmt.Errorf("%w: data too short for %s", ErrInvalidData, t)
+		}
+
+		if err := d.charge(wordSize); err != nil {
+			return nil, err
+		}
+
+		return decodeWord(t, data[:wordSize])
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidType, t)
+	}
+}
+
+// decodeWord decodes a static elementary value from its 32 byte word.
+func decodeWord(t Type, word []byte) (any, error) {
+	switch t.Kind {
+	case UintKind:
+		value := new(big.Int).SetBytes(word)
+		if value.BitLen() > t.Size {
+			return nil, fmt.Errorf("%w: value overflows %s", ErrInvalidData, t)
+		}
+
+		return value, nil
+	case IntKind:
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			// two's complement negative value.
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), wordSize*8))
+		}
+
+		return value, nil
+	case AddressKind:
+		return blockchain.BytesToAddress(word), nil
+	case BoolKind:
+		value := new(big.Int).SetBytes(word)
+		if value.BitLen() > 1 {
+			return nil, fmt.Errorf("%w: invalid bool", ErrInvalidData)
+		}
+
+		return value.Sign() == 1, nil
+	case FixedBytesKind:
+		return append([]byte{}, word[:t.Size]...), nil
+	case BytesKind, StringKind, SliceKind, ArrayKind, TupleKind:
+		return nil, fmt.Errorf("%w: %s is not an elementary type", ErrInvalidType, t)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidType, t)
+	}
+}
+
+// readLength reads the word at position as a length or offset.
+func readLength(data []byte, position int) (int, error) {
+	if position+wordSize > len(data) {
+		return 0, fmt.Errorf("%w: data too short", ErrInvalidData)
+	}
+
+	value := new(big.Int).SetBytes(data[position : position+wordSize])
+	if !value.IsInt64() || value.Int64() > maxDecodedLength {
+		return 0, fmt.Errorf("%w: length or offset %s too large", ErrInvalidData, value)
+	}
+
+	return int(value.Int64()), nil
+}
+
+func repeatType(t Type, count int) []Type {
+	types := make([]Type, count)
+	for i := range types {
+		types[i] = t
+	}
+
+	return types
+}
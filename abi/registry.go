@@ -0,0 +1,217 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+var ErrUnknownSelector = errors.New("abi: unknown function selector")
+
+// defaultSignatures are the signatures of common token and wallet functions known to
+// every registry.
+var defaultSignatures = []string{
+	// ERC-20.
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	// ERC-721 and ERC-1155.
+	"safeTransferFrom(address,address,uint256)",
+	"safeTransferFrom(address,address,uint256,bytes)",
+	"setApprovalForAll(address,bool)",
+	"safeTransferFrom(address,address,uint256,uint256,bytes)",
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)",
+	// WETH.
+	"deposit()",
+	"withdraw(uint256)",
+	// multicall and uniswap routers.
+	"multicall(bytes[])",
+	"multicall(uint256,bytes[])",
+	"execute(bytes,bytes[],uint256)",
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+}
+
+// Registry maps function selectors to the methods known to have them. different
+// signatures can share a selector, in which case the first one that decodes the
+// calldata wins.
+type Registry struct {
+	mu      sync.RWMutex
+	methods map[Selector][]Method
+}
+
+// NewRegistry creates a registry of the common default signatures and the given ones.
+func NewRegistry(signatures ...string) (*Registry, error) {
+	registry := &Registry{methods: make(map[Selector][]Method)}
+
+	for _, signature := range append(append([]string{}, defaultSignatures...), signatures...) {
+		if err := registry.Register(signature); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// LoadRegistry creates a registry of the default signatures and the signatures in a
+// local JSON file mapping hex selectors to signatures, e.g. {"0xa9059cbb": "transfer(address,uint256)"}.
+// entries whose selector does not match their signature are rejected.
+func LoadRegistry(path string) (*Registry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read abi signatures file: %w", err)
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse abi signatures file: %w", err)
+	}
+
+	registry, err := NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	for selector, signature := range entries {
+		method, err := ParseSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.EqualFold(method.Selector.String(), selector) {
+			return nil, fmt.Errorf("%w: %s is the selector of %s, not %s",
+				ErrSelectorMismatch, method.Selector, method.Signature, selector)
+		}
+
+		registry.add(method)
+	}
+
+	return registry, nil
+}
+
+// Register adds the method with the signature to the registry.
+func (r *Registry) Register(signature string) error {
+	method, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	r.add(method)
+
+	return nil
+}
+
+func (r *Registry) add(method Method) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, known := range r.methods[method.Selector] {
+		if known.Signature == method.Signature {
+			return
+		}
+	}
+
+	r.methods[method.Selector] = append(r.methods[method.Selector], method)
+}
+
+// Call is decoded calldata: the method called and its arguments.
+type Call struct {
+	Method    string     `json:"method"`
+	Signature string     `json:"signature"`
+	Selector  string     `json:"selector"`
+	Arguments []Argument `json:"arguments"`
+}
+
+// Argument is a decoded argument of a call.
+type Argument struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// MarshalJSON renders integers as decimal strings, bytes as hex and addresses checksummed.
+func (a Argument) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Type  string `json:"type"`
+		Value any    `json:"value"`
+	}{Type: a.Type, Value: jsonValue(a.Value)})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal argument: %w", err)
+	}
+
+	return data, nil
+}
+
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case blockchain.Address:
+		return v.Hex()
+	case []any:
+		values := make([]any, 0, len(v))
+		for _, item := range v {
+			values = append(values, jsonValue(item))
+		}
+
+		return values
+	default:
+		return v
+	}
+}
+
+// DecodeCalldata decodes hex encoded calldata (e.g. Transaction.Input) using the methods
+// registered for its selector.
+func (r *Registry) DecodeCalldata(input string) (*Call, error) {
+	calldata, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: calldata is not hex encoded", ErrInvalidData)
+	}
+
+	if len(calldata) < SelectorLength {
+		return nil, fmt.Errorf("%w: calldata has no selector", ErrInvalidData)
+	}
+
+	selector := Selector(calldata[:SelectorLength])
+
+	r.mu.RLock()
+	methods := r.methods[selector]
+	r.mu.RUnlock()
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSelector, selector)
+	}
+
+	var decodeErr error
+
+	for _, method := range methods {
+		values, err := method.DecodeInput(calldata)
+		if err != nil {
+			decodeErr = fmt.Errorf("could not decode %s: %w", method.Signature, err)
+			continue
+		}
+
+		arguments := make([]Argument, 0, len(values))
+		for i, value := range values {
+			arguments = append(arguments, Argument{Type: method.Inputs[i].String(), Value: value})
+		}
+
+		return &Call{
+			Method:    method.Name,
+			Signature: method.Signature,
+			Selector:  selector.String(),
+			Arguments: arguments,
+		}, nil
+	}
+
+	return nil, decodeErr
+}
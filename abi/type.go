@@ -0,0 +1,229 @@
+// Package abi decodes contract calldata encoded with the solidity contract ABI.
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidType = errors.New("abi: invalid type")
+
+// wordSize is the size in bytes of an ABI word.
+const wordSize = 32
+
+// Kind is the kind of an ABI type.
+type Kind int
+
+const (
+	UintKind Kind = iota
+	IntKind
+	AddressKind
+	BoolKind
+	FixedBytesKind
+	BytesKind
+	StringKind
+	SliceKind
+	ArrayKind
+	TupleKind
+)
+
+// Type is a parsed ABI type such as uint256, bytes32[], or (address,uint256).
+type Type struct {
+	Kind Kind
+	// Size is the number of bits of integers, the number of bytes of fixed bytes
+	// and the length of fixed size arrays.
+	Size int
+	// Elem is the element type of slices and arrays.
+	Elem *Type
+	// Components are the types of the fields of tuples.
+	Components []Type
+
+	raw string
+}
+
+// String returns the canonical name of the type.
+func (t Type) String() string {
+	return t.raw
+}
+
+// ParseType parses the canonical name of an ABI type.
+func ParseType(name string) (Type, error) {
+	name = strings.TrimSpace(name)
+
+	// arrays and slices: the last bracket pair applies to the whole preceding type.
+	if strings.HasSuffix(name, "]") {
+		open := strings.LastIndex(name, "[")
+		if open < 0 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, name)
+		}
+
+		elem, err := ParseType(name[:open])
+		if err != nil {
+			return Type{}, err
+		}
+
+		length := name[open+1 : len(name)-1]
+		if length == "" {
+			return Type{Kind: SliceKind, Elem: &elem, raw: elem.raw + "[]"}, nil
+		}
+
+		size, err := strconv.Atoi(length)
+		if err != nil || size <= 0 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, name)
+		}
+
+		return Type{Kind: ArrayKind, Size: size, Elem: &elem, raw: fmt.Sprintf("%s[%d]", elem.raw, size)}, nil
+	}
+
+	if strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")") {
+		components, err := parseTypeList(name[1 : len(name)-1])
+		if err != nil {
+			return Type{}, err
+		}
+
+		return Type{Kind: TupleKind, Components: components, raw: "(" + joinTypes(components) + ")"}, nil
+	}
+
+	return parseElementaryType(name)
+}
+
+func parseElementaryType(name string) (Type, error) {
+	switch name {
+	case "address":
+		return Type{Kind: AddressKind, Size: 160, raw: name}, nil
+	case "bool":
+		return Type{Kind: BoolKind, raw: name}, nil
+	case "string":
+		return Type{Kind: StringKind, raw: name}, nil
+	case "bytes":
+		return Type{Kind: BytesKind, raw: name}, nil
+	case "uint", "int":
+		// uint and int are aliases of uint256 and int256.
+		return parseElementaryType(name + "256")
+	case "function":
+		// a function is an address followed by a selector, encoded as bytes24.
+		return Type{Kind: FixedBytesKind, Size: 24, raw: name}, nil
+	}
+
+	for _, prefix := range []struct {
+		name     string
+		kind     Kind
+		max, div int
+	}{
+		{name: "uint", kind: UintKind, max: 256, div: 8},
+		{name: "int", kind: IntKind, max: 256, div: 8},
+		{name: "bytes", kind: FixedBytesKind, max: 32, div: 1},
+	} {
+		sizeStr, ok := strings.CutPrefix(name, prefix.name)
+		if !ok {
+			continue
+		}
+
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil || size <= 0 || size > prefix.max || size%prefix.div != 0 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, name)
+		}
+
+		return Type{Kind: prefix.kind, Size: size, raw: name}, nil
+	}
+
+	return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, name)
+}
+
+// parseTypeList parses a comma separated list of types, respecting nested tuples.
+func parseTypeList(list string) ([]Type, error) {
+	types := make([]Type, 0)
+	if strings.TrimSpace(list) == "" {
+		return types, nil
+	}
+
+	depth, start := 0, 0
+
+	for i, char := range list + "," {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth != 0 {
+				continue
+			}
+
+			parsed, err := ParseType(list[start:i])
+			if err != nil {
+				return nil, err
+			}
+
+			types = append(types, parsed)
+			start = i + 1
+		}
+
+		if depth < 0 {
+			return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidType, list)
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidType, list)
+	}
+
+	return types, nil
+}
+
+func joinTypes(types []Type) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.raw)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// isDynamic reports whether the encoding of the type has a variable size, in which
+// case it is encoded in the tail and referenced by an offset in the head.
+func (t Type) isDynamic() bool {
+	switch t.Kind {
+	case BytesKind, StringKind, SliceKind:
+		return true
+	case ArrayKind:
+		return t.Elem.isDynamic()
+	case TupleKind:
+		for _, component := range t.Components {
+			if component.isDynamic() {
+				return true
+			}
+		}
+
+		return false
+	case UintKind, IntKind, AddressKind, BoolKind, FixedBytesKind:
+		return false
+	default:
+		return false
+	}
+}
+
+// headSize returns the number of bytes the type takes in the head of its enclosing tuple.
+func (t Type) headSize() int {
+	if t.isDynamic() {
+		return wordSize
+	}
+
+	switch t.Kind {
+	case ArrayKind:
+		return t.Size * t.Elem.headSize()
+	case TupleKind:
+		size := 0
+		for _, component := range t.Components {
+			size += component.headSize()
+		}
+
+		return size
+	case UintKind, IntKind, AddressKind, BoolKind, FixedBytesKind, BytesKind, StringKind, SliceKind:
+		return wordSize
+	default:
+		return wordSize
+	}
+}
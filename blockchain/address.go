@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidChecksum = errors.New("invalid address checksum")
+
+// AddressLength is the length of an ethereum address in bytes.
+const AddressLength = 20
+
+// Address is a 20 byte ethereum address.
+type Address [AddressLength]byte
+
+// ParseAddress parses a "0x" prefixed hex encoded address. all lowercase and all
+// uppercase addresses are accepted as is, mixed case addresses must carry a valid
+// EIP-55 checksum.
+func ParseAddress(s string) (Address, error) {
+	var address Address
+
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok || len(digits) != 2*AddressLength {
+		return address, fmt.Errorf("%w: %q", ErrInvalidAddress, s)
+	}
+
+	if _, err := hex.Decode(address[:], []byte(digits)); err != nil {
+		return address, fmt.Errorf("%w: %q", ErrInvalidAddress, s)
+	}
+
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && address.Hex() != s {
+		return address, fmt.Errorf("%w: %q", ErrInvalidChecksum, s)
+	}
+
+	return address, nil
+}
+
+// BytesToAddress returns the address of the last 20 bytes of b (left padded if shorter).
+func BytesToAddress(b []byte) Address {
+	var address Address
+
+	if len(b) > AddressLength {
+		b = b[len(b)-AddressLength:]
+	}
+
+	copy(address[AddressLength-len(b):], b)
+
+	return address
+}
+
+// NormalizeAddress returns the canonical storage key of an address. see Address.Key.
+func NormalizeAddress(s string) (string, error) {
+	address, err := ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+
+	return address.Key(), nil
+}
+
+// ChecksumAddress returns the EIP-55 checksummed form of an address or the
+// input unchanged if it is not a valid address.
+func ChecksumAddress(s string) string {
+	address, err := ParseAddress(s)
+	if err != nil {
+		return s
+	}
+
+	return address.Hex()
+}
+
+// Bytes returns the address as a byte slice.
+func (a Address) Bytes() []byte {
+	return a[:]
+}
+
+// IsZero reports whether the address is the zero address.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// Key returns the canonical lowercase "0x" prefixed hex encoding of the address.
+// it is used to key addresses in storage so different casings of an address match.
+func (a Address) Key() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// Hex returns the EIP-55 checksummed hex encoding of the address.
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	hash := Keccak256([]byte(lower))
+
+	checksummed := []byte(lower)
+	for i, char := range checksummed {
+		if char < 'a' {
+			continue
+		}
+
+		// uppercase the letter if the matching nibble of the hash is >= 8.
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+
+		if nibble&0x0f >= 8 {
+			checksummed[i] = char - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(checksummed)
+}
+
+// String implements fmt.Stringer.
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(text []byte) error {
+	address, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = address
+
+	return nil
+}
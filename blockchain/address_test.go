@@ -0,0 +1,59 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	checksummed := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+
+	for _, want := range checksummed {
+		t.Run(want, func(t *testing.T) {
+			for _, input := range []string{want, strings.ToLower(want), "0x" + strings.ToUpper(want[2:])} {
+				address, err := ParseAddress(input)
+				if err != nil {
+					t.Fatalf("ParseAddress(%s) error = %v, want nil", input, err)
+				}
+
+				if got := address.Hex(); got != want {
+					t.Errorf("Hex() = %v, want %v", got, want)
+				}
+
+				if got := address.Key(); got != strings.ToLower(want) {
+					t.Errorf("Key() = %v, want %v", got, strings.ToLower(want))
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalidAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "bad checksum", input: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", wantErr: ErrInvalidChecksum},
+		{name: "missing prefix", input: "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", wantErr: ErrInvalidAddress},
+		{name: "too short", input: "0xabc", wantErr: ErrInvalidAddress},
+		{name: "not hex", input: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg", wantErr: ErrInvalidAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAddress(tt.input); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseAddress() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if IsValidEthereumAddress(tt.input) {
+				t.Errorf("IsValidEthereumAddress(%s) = true, want false", tt.input)
+			}
+		})
+	}
+}
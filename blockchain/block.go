@@ -1,5 +1,13 @@
 package blockchain
 
+import (
+	"math/big"
+	"time"
+)
+
+// gweiInWei is the number of wei in one gwei.
+const gweiInWei = 1_000_000_000
+
 // Block represents a block in the blockchain.
 type Block struct {
 	Difficulty       string        `json:"difficulty"`
@@ -22,4 +30,87 @@ type Block struct {
 	Transactions     []Transaction `json:"transactions"`
 	TransactionsRoot string        `json:"transactionsRoot"`
 	Uncles           []string      `json:"uncles"`
+
+	// EIP-1559 (london) base fee.
+	BaseFeePerGas string `json:"baseFeePerGas,omitempty"`
+
+	// EIP-4895 (shanghai) beacon chain withdrawals.
+	WithdrawalsRoot string       `json:"withdrawalsRoot,omitempty"`
+	Withdrawals     []Withdrawal `json:"withdrawals,omitempty"`
+
+	// EIP-4844 and EIP-4788 (cancun) blob gas accounting and beacon block root.
+	BlobGasUsed           string `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas         string `json:"excessBlobGas,omitempty"`
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot,omitempty"`
+
+	// EIP-7685 (prague) execution layer requests commitment.
+	RequestsHash string `json:"requestsHash,omitempty"`
+}
+
+// Withdrawal is a validator withdrawal from the beacon chain credited to an execution
+// layer address (EIP-4895). the amount is denominated in gwei.
+type Withdrawal struct {
+	Index          string `json:"index"`
+	ValidatorIndex string `json:"validatorIndex"`
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
+}
+
+// NumberUint64 returns the block number.
+func (b Block) NumberUint64() (uint64, error) {
+	return parseField("number", b.Number)
+}
+
+// GasLimitUint64 returns the gas limit of the block.
+func (b Block) GasLimitUint64() (uint64, error) {
+	return parseField("gasLimit", b.GasLimit)
+}
+
+// GasUsedUint64 returns the gas used by all transactions in the block.
+func (b Block) GasUsedUint64() (uint64, error) {
+	return parseField("gasUsed", b.GasUsed)
+}
+
+// Time returns the time the block was produced.
+func (b Block) Time() (time.Time, error) {
+	timestamp, err := parseField("timestamp", b.Timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(int64(timestamp), 0).UTC(), nil //nolint: gosec // timestamps fit in int64.
+}
+
+// BaseFee returns the base fee per gas of the block or nil for blocks before london.
+func (b Block) BaseFee() (*big.Int, error) {
+	if b.BaseFeePerGas == "" {
+		return nil, nil //nolint: nilnil // pre-london blocks have no base fee.
+	}
+
+	return parseBigField("baseFeePerGas", b.BaseFeePerGas)
+}
+
+// BlobGasUsedUint64 returns the total blob gas used by the block's transactions.
+func (b Block) BlobGasUsedUint64() (uint64, error) {
+	return parseField("blobGasUsed", b.BlobGasUsed)
+}
+
+// ExcessBlobGasUint64 returns the running excess blob gas of the block.
+func (b Block) ExcessBlobGasUint64() (uint64, error) {
+	return parseField("excessBlobGas", b.ExcessBlobGas)
+}
+
+// AmountGwei returns the withdrawn amount in gwei.
+func (w Withdrawal) AmountGwei() (uint64, error) {
+	return parseField("amount", w.Amount)
+}
+
+// AmountWei returns the withdrawn amount in wei.
+func (w Withdrawal) AmountWei() (*big.Int, error) {
+	amount, err := w.AmountGwei()
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Mul(new(big.Int).SetUint64(amount), big.NewInt(gweiInWei)), nil
 }
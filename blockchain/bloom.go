@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidBloom = errors.New("invalid logs bloom")
+
+const (
+	// BloomLength is the length of a logs bloom in bytes (2048 bits).
+	BloomLength = 256
+	// bloomBitMask keeps the 11 low bits of a bloom index, addressing one of 2048 bits.
+	bloomBitMask = BloomLength*8 - 1
+)
+
+// Bloom is the 2048 bit bloom filter of the addresses and topics of the logs of a
+// receipt or a block. a value that was added to the bloom always tests positive, a
+// value that was not may test positive too (a false positive).
+type Bloom [BloomLength]byte
+
+// ParseBloom parses a "0x" prefixed hex encoded logs bloom.
+func ParseBloom(s string) (Bloom, error) {
+	var bloom Bloom
+
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok || len(digits) != 2*BloomLength {
+		return bloom, fmt.Errorf("%w: %q", ErrInvalidBloom, s)
+	}
+
+	if _, err := hex.Decode(bloom[:], []byte(digits)); err != nil {
+		return bloom, fmt.Errorf("%w: %q", ErrInvalidBloom, s)
+	}
+
+	return bloom, nil
+}
+
+// CreateBloom returns the bloom of the addresses and topics of the logs.
+func CreateBloom(logs []Log) (Bloom, error) {
+	var bloom Bloom
+
+	for _, log := range logs {
+		address, err := decodeHexData(log.Address)
+		if err != nil {
+			return bloom, fmt.Errorf("invalid log address: %w", err)
+		}
+
+		bloom.Add(address)
+
+		for _, topic := range log.Topics {
+			value, err := decodeHexData(topic)
+			if err != nil {
+				return bloom, fmt.Errorf("invalid log topic: %w", err)
+			}
+
+			bloom.Add(value)
+		}
+	}
+
+	return bloom, nil
+}
+
+// BloomIndex is the 3 bits of the bloom set by a value. it lets a value be tested against
+// many blooms without hashing it every time.
+type BloomIndex [3]int
+
+// BloomIndexOf returns the bits of the bloom set by the value.
+func BloomIndexOf(value []byte) BloomIndex {
+	return bloomBits(value)
+}
+
+// Add sets the 3 bits of the value in the bloom.
+func (b *Bloom) Add(value []byte) {
+	for _, bit := range bloomBits(value) {
+		b[BloomLength-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether the value may be in the bloom. false means it is certainly not.
+func (b Bloom) Test(value []byte) bool {
+	return b.TestIndex(bloomBits(value))
+}
+
+// TestIndex reports whether the value of the bloom index may be in the bloom.
+func (b *Bloom) TestIndex(index BloomIndex) bool {
+	for _, bit := range index {
+		if b[BloomLength-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TestAddress reports whether the address may be the emitter of a log in the bloom.
+func (b Bloom) TestAddress(address Address) bool {
+	return b.Test(address[:])
+}
+
+// TestTopic reports whether the 32 byte topic may be the topic of a log in the bloom.
+func (b Bloom) TestTopic(topic [32]byte) bool {
+	return b.Test(topic[:])
+}
+
+// String returns the "0x" prefixed hex encoding of the bloom.
+func (b Bloom) String() string {
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// bloomBits returns the 3 bits of the bloom set by the value: the low 11 bits of each
+// of the first 3 byte pairs of its Keccak-256 hash.
+func bloomBits(value []byte) [3]int {
+	hash := Keccak256(value)
+
+	var bits [3]int
+	for i := range bits {
+		bits[i] = (int(hash[2*i])<<8 | int(hash[2*i+1])) & bloomBitMask
+	}
+
+	return bits
+}
+
+// AddressTopic returns the 32 byte topic of an address passed as an indexed event
+// argument (e.g. the from and to of an ERC-20 Transfer), the address left padded with zeros.
+func AddressTopic(address Address) [32]byte {
+	var topic [32]byte
+
+	copy(topic[len(topic)-AddressLength:], address[:])
+
+	return topic
+}
+
+// ParseTopic parses a "0x" prefixed hex encoded 32 byte log topic.
+func ParseTopic(s string) ([32]byte, error) {
+	var topic [32]byte
+
+	value, err := decodeHexData(s)
+	if err != nil {
+		return topic, err
+	}
+
+	if len(value) != len(topic) {
+		return topic, fmt.Errorf("%w: topic %q is not 32 bytes", ErrInvalidHexData, s)
+	}
+
+	copy(topic[:], value)
+
+	return topic, nil
+}
+
+// Bloom returns the parsed logs bloom of the block.
+func (b Block) Bloom() (Bloom, error) {
+	return ParseBloom(b.LogsBloom)
+}
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCreateBloom(t *testing.T) {
+	logs := []Log{{
+		Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			"0x0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		},
+	}}
+
+	bloom, err := CreateBloom(logs)
+	if err != nil {
+		t.Fatalf("CreateBloom() error = %v, want nil", err)
+	}
+
+	want := map[int]byte{2: 0x04, 50: 0x40, 72: 0x08, 75: 0x08, 93: 0x80, 123: 0x10, 148: 0x01, 166: 0x20, 195: 0x02}
+	for i, b := range bloom {
+		if b != want[i] {
+			t.Errorf("bloom[%d] = %#02x, want %#02x", i, b, want[i])
+		}
+	}
+
+	parsed, err := ParseBloom(bloom.String())
+	if err != nil || parsed != bloom {
+		t.Errorf("ParseBloom(String()) = %v, %v, want %v", parsed, err, bloom)
+	}
+
+	usdc, _ := ParseAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	subscriber, _ := ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	other, _ := ParseAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{name: "emitter", got: bloom.TestAddress(usdc), want: true},
+		{name: "indexed address", got: bloom.TestTopic(AddressTopic(subscriber)), want: true},
+		{name: "indexed address as emitter", got: bloom.TestAddress(subscriber), want: false},
+		{name: "unrelated address", got: bloom.TestAddress(other), want: false},
+		{name: "unrelated topic", got: bloom.TestTopic(AddressTopic(other)), want: false},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: Test() = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestBloomExtensively(t *testing.T) {
+	var bloom Bloom
+
+	for i := range 100 {
+		bloom.Add(fmt.Appendf(nil, "xxxxxxxxxx data %d", i))
+	}
+
+	if got := hex.EncodeToString(Keccak256(bloom[:])); got != "a52354dd69dab5938cb2d78aad79b6f64404eb2d49754b0bd65c22d06114949d" {
+		t.Errorf("Keccak256(bloom) = %s", got)
+	}
+
+	for i := range 100 {
+		if !bloom.Test(fmt.Appendf(nil, "xxxxxxxxxx data %d", i)) {
+			t.Errorf("Test(data %d) = false, want true", i)
+		}
+	}
+}
+
+func TestParseInvalidBloom(t *testing.T) {
+	for _, invalid := range []string{"", "0x", strings.Repeat("0", 512), "0x" + strings.Repeat("zz", 256)} {
+		if _, err := ParseBloom(invalid); !errors.Is(err, ErrInvalidBloom) {
+			t.Errorf("ParseBloom(%q) error = %v, want %v", invalid, err, ErrInvalidBloom)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spankie/tw-interview/rlp"
+)
+
+var ErrNotContractCreation = errors.New("transaction is not a contract creation")
+
+// IsContractCreation reports whether the transaction deploys a contract, i.e. it has
+// no recipient.
+func (t Transaction) IsContractCreation() bool {
+	return t.To == "" || t.To == "0x"
+}
+
+// ContractAddress returns the address of the contract deployed by a contract creation
+// transaction, computed from its sender and nonce. see CreateAddress.
+func (t Transaction) ContractAddress() (Address, error) {
+	if !t.IsContractCreation() {
+		return Address{}, fmt.Errorf("%w: %s", ErrNotContractCreation, t.Hash)
+	}
+
+	sender, err := ParseAddress(t.From)
+	if err != nil {
+		return Address{}, fmt.Errorf("field from: %w", err)
+	}
+
+	nonce, err := t.NonceUint64()
+	if err != nil {
+		return Address{}, err
+	}
+
+	return CreateAddress(sender, nonce), nil
+}
+
+// CreateAddress returns the address of a contract deployed with CREATE, the last 20 bytes
+// of the Keccak-256 hash of the rlp encoded [sender, nonce].
+func CreateAddress(sender Address, nonce uint64) Address {
+	// encoding a byte string and an integer cannot fail.
+	encoded, _ := rlp.Encode([]any{sender.Bytes(), nonce})
+
+	return BytesToAddress(Keccak256(encoded))
+}
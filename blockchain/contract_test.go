@@ -0,0 +1,42 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateAddress(t *testing.T) {
+	sender, err := ParseAddress("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0")
+	if err != nil {
+		t.Fatalf("ParseAddress() error = %v", err)
+	}
+
+	tests := []struct {
+		nonce uint64
+		want  string
+	}{
+		{nonce: 0, want: "0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d"},
+		{nonce: 1, want: "0x343c43a37d37dff08ae8c4a11544c718abb4fcf8"},
+		{nonce: 2, want: "0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91"},
+		{nonce: 3, want: "0xfffd933a0bc612844eaf0c6fe3e5b8e9b6c1d19c"},
+	}
+	for _, tt := range tests {
+		if got := CreateAddress(sender, tt.nonce); got.Key() != tt.want {
+			t.Errorf("CreateAddress(%d) = %s, want %s", tt.nonce, got.Key(), tt.want)
+		}
+	}
+}
+
+func TestTransactionContractAddress(t *testing.T) {
+	deployment := Transaction{From: "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", Nonce: "0x1"}
+
+	address, err := deployment.ContractAddress()
+	if err != nil || address.Key() != "0x343c43a37d37dff08ae8c4a11544c718abb4fcf8" {
+		t.Errorf("ContractAddress() = %s, %v, want 0x343c43a37d37dff08ae8c4a11544c718abb4fcf8", address.Key(), err)
+	}
+
+	deployment.To = "0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d"
+	if _, err := deployment.ContractAddress(); !errors.Is(err, ErrNotContractCreation) {
+		t.Errorf("ContractAddress() error = %v, want %v", err, ErrNotContractCreation)
+	}
+}
@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakRate is the sponge rate in bytes for Keccak-256 (1600 - 2*256 bits).
+const keccakRate = 136
+
+// keccakRoundConstants are the iota step constants of Keccak-f[1600].
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations are the rho step offsets indexed by lane (x + 5*y).
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Keccak256 returns the legacy Keccak-256 digest of the concatenated data as used by ethereum.
+// NOTE: this is not the standardized SHA3-256, the two only differ in the padding byte.
+func Keccak256(data ...[]byte) []byte {
+	var (
+		state [25]uint64
+		block [keccakRate]byte
+	)
+
+	var message []byte
+	for _, d := range data {
+		message = append(message, d...)
+	}
+
+	for len(message) >= keccakRate {
+		absorbKeccakBlock(&state, message[:keccakRate])
+		message = message[keccakRate:]
+	}
+
+	// pad the final block using the keccak multi-rate padding (0x01 ... 0x80).
+	copy(block[:], message)
+	block[len(message)] ^= 0x01
+	block[keccakRate-1] ^= 0x80
+	absorbKeccakBlock(&state, block[:])
+
+	digest := make([]byte, 32)
+	for i := range 4 {
+		binary.LittleEndian.PutUint64(digest[i*8:], state[i])
+	}
+
+	return digest
+}
+
+// Keccak256Hash returns the Keccak-256 digest of the data as a fixed size array.
+func Keccak256Hash(data ...[]byte) [32]byte {
+	return [32]byte(Keccak256(data...))
+}
+
+func absorbKeccakBlock(state *[25]uint64, block []byte) {
+	for i := range keccakRate / 8 {
+		state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+
+	keccakF1600(state)
+}
+
+// keccakF1600 applies the Keccak-f[1600] permutation to the state.
+func keccakF1600(state *[25]uint64) {
+	var (
+		c [5]uint64
+		b [25]uint64
+	)
+
+	for round := range 24 {
+		// theta step.
+		for x := range 5 {
+			c[x] = state[x] ^ state[x+5] ^ state[x+10] ^ state[x+15] ^ state[x+20]
+		}
+
+		for x := range 5 {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				state[x+y] ^= d
+			}
+		}
+
+		// rho and pi steps.
+		for x := range 5 {
+			for y := range 5 {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(state[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+
+		// chi step.
+		for y := 0; y < 25; y += 5 {
+			for x := range 5 {
+				state[x+y] = b[x+y] ^ (^b[(x+1)%5+y] & b[(x+2)%5+y])
+			}
+		}
+
+		// iota step.
+		state[0] ^= keccakRoundConstants[round]
+	}
+}
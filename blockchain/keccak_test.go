@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "empty input",
+			input: "",
+			want:  "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		},
+		{
+			name:  "short input",
+			input: "abc",
+			want:  "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		},
+		{
+			name:  "function signature",
+			input: "transfer(address,uint256)",
+			want:  "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b",
+		},
+		{
+			name:  "input longer than the sponge rate",
+			input: strings.Repeat("a", 200),
+			want:  "96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d",
+		},
+		{
+			name:  "input one byte short of the rate",
+			input: strings.Repeat("c", keccakRate-1),
+			want:  "c05b1ba4c8f046ae743faa70cbe7d4f3f75081d584c7f1bf675dd6e7cc1fc7e8",
+		},
+		{
+			name:  "input exactly the rate",
+			input: strings.Repeat("b", keccakRate),
+			want:  "121b76d0b19f3c2c7632310b92c54cddd59d16a6b5aafe84696426f10e5733bf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(Keccak256([]byte(tt.input))); got != tt.want {
+				t.Errorf("Keccak256() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
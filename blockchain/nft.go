@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrNotNFTTransfer = errors.New("log is not an NFT transfer")
+
+var (
+	// TransferSingleEventTopic is the event signature topic of the ERC-1155
+	// TransferSingle(address,address,address,uint256,uint256) event.
+	TransferSingleEventTopic = [32]byte(Keccak256([]byte("TransferSingle(address,address,address,uint256,uint256)")))
+	// TransferBatchEventTopic is the event signature topic of the ERC-1155
+	// TransferBatch(address,address,address,uint256[],uint256[]) event.
+	TransferBatchEventTopic = [32]byte(Keccak256([]byte("TransferBatch(address,address,address,uint256[],uint256[])")))
+)
+
+// NFTStandard is the token standard of an NFT contract.
+type NFTStandard string
+
+const (
+	ERC721  NFTStandard = "erc721"
+	ERC1155 NFTStandard = "erc1155"
+)
+
+// NFTTransfer is the transfer of an amount of a single NFT, decoded from an ERC-721
+// Transfer log or an ERC-1155 TransferSingle or TransferBatch log. a batch log is decoded
+// into one transfer per token, told apart by their batch index.
+type NFTTransfer struct {
+	Standard NFTStandard `json:"standard"`
+	Contract string      `json:"contract"`
+	// Operator is the address that sent an ERC-1155 transfer on behalf of the sender.
+	Operator string `json:"operator,omitempty"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	// TokenID and Amount are in decimal. the amount of ERC-721 transfers is always 1.
+	TokenID         string `json:"tokenId"`
+	Amount          string `json:"amount"`
+	TransactionHash string `json:"transactionHash"`
+	LogIndex        string `json:"logIndex"`
+	BatchIndex      int    `json:"batchIndex,omitempty"`
+}
+
+// IsNFTTransferLog reports whether the event signature of the log is the one of an ERC-721
+// Transfer (shared with ERC-20 transfers) or an ERC-1155 transfer.
+func IsNFTTransferLog(log Log) bool {
+	if len(log.Topics) == 0 {
+		return false
+	}
+
+	signature, err := ParseTopic(log.Topics[0])
+	if err != nil {
+		return false
+	}
+
+	return signature == TransferEventTopic || signature == TransferSingleEventTopic ||
+		signature == TransferBatchEventTopic
+}
+
+// ParseNFTTransfers decodes the NFT transfers of an ERC-721 Transfer(address indexed from,
+// address indexed to, uint256 indexed tokenId) log or an ERC-1155 TransferSingle or
+// TransferBatch log. ERC-20 transfers, which do not index the value, are not NFT transfers.
+func ParseNFTTransfers(log Log) ([]NFTTransfer, error) {
+	if !IsNFTTransferLog(log) {
+		return nil, fmt.Errorf("%w: log %s of %s", ErrNotNFTTransfer, log.LogIndex, log.TransactionHash)
+	}
+
+	signature, _ := ParseTopic(log.Topics[0])
+
+	contract, err := ParseAddress(log.Address)
+	if err != nil {
+		return nil, fmt.Errorf("field address: %w", err)
+	}
+
+	transfer := NFTTransfer{
+		Contract:        contract.Key(),
+		TransactionHash: log.TransactionHash,
+		LogIndex:        log.LogIndex,
+	}
+
+	if signature == TransferEventTopic {
+		return parseERC721Transfer(log, transfer)
+	}
+
+	data, err := decodeHexData(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("field data: %w", err)
+	}
+
+	if len(log.Topics) != 4 {
+		return nil, fmt.Errorf("%w: log %s of %s does not index the operator, sender and recipient",
+			ErrNotNFTTransfer, log.LogIndex, log.TransactionHash)
+	}
+
+	transfer.Standard = ERC1155
+
+	addresses := make([]string, 0, 3)
+
+	for _, topic := range log.Topics[1:] {
+		address, err := topicAddress(topic)
+		if err != nil {
+			return nil, fmt.Errorf("field topics: %w", err)
+		}
+
+		addresses = append(addresses, address.Key())
+	}
+
+	transfer.Operator, transfer.From, transfer.To = addresses[0], addresses[1], addresses[2]
+
+	if signature == TransferSingleEventTopic {
+		if len(data) != 64 {
+			return nil, fmt.Errorf("%w: data of log %s of %s is not an id and a value",
+				ErrNotNFTTransfer, log.LogIndex, log.TransactionHash)
+		}
+
+		transfer.TokenID = new(big.Int).SetBytes(data[:32]).String()
+		transfer.Amount = new(big.Int).SetBytes(data[32:]).String()
+
+		return []NFTTransfer{transfer}, nil
+	}
+
+	return parseERC1155Batch(log, transfer, data)
+}
+
+func parseERC721Transfer(log Log, transfer NFTTransfer) ([]NFTTransfer, error) {
+	if len(log.Topics) != 4 {
+		return nil, fmt.Errorf("%w: log %s of %s does not index the token id",
+			ErrNotNFTTransfer, log.LogIndex, log.TransactionHash)
+	}
+
+	from, err := topicAddress(log.Topics[1])
+	if err != nil {
+		return nil, fmt.Errorf("field from: %w", err)
+	}
+
+	to, err := topicAddress(log.Topics[2])
+	if err != nil {
+		return nil, fmt.Errorf("field to: %w", err)
+	}
+
+	tokenID, err := ParseTopic(log.Topics[3])
+	if err != nil {
+		return nil, fmt.Errorf("field tokenId: %w", err)
+	}
+
+	transfer.Standard = ERC721
+	transfer.From, transfer.To = from.Key(), to.Key()
+	transfer.TokenID = new(big.Int).SetBytes(tokenID[:]).String()
+	transfer.Amount = "1"
+
+	return []NFTTransfer{transfer}, nil
+}
+
+// parseERC1155Batch decodes the ids and values arrays of a TransferBatch log into one
+// transfer per token.
+func parseERC1155Batch(log Log, transfer NFTTransfer, data []byte) ([]NFTTransfer, error) {
+	ids, err := uint256Array(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ids of log %s of %s: %w", ErrNotNFTTransfer, log.LogIndex, log.TransactionHash, err)
+	}
+
+	values, err := uint256Array(data, 1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: values of log %s of %s: %w", ErrNotNFTTransfer, log.LogIndex,
+			log.TransactionHash, err)
+	}
+
+	if len(ids) != len(values) {
+		return nil, fmt.Errorf("%w: log %s of %s has %d ids and %d values", ErrNotNFTTransfer, log.LogIndex,
+			log.TransactionHash, len(ids), len(values))
+	}
+
+	transfers := make([]NFTTransfer, len(ids))
+
+	for i := range ids {
+		transfers[i] = transfer
+		transfers[i].TokenID = ids[i].String()
+		transfers[i].Amount = values[i].String()
+		transfers[i].BatchIndex = i
+	}
+
+	return transfers, nil
+}
+
+// uint256Array decodes the ABI encoded uint256[] argument at the given position of the data.
+func uint256Array(data []byte, position int) ([]*big.Int, error) {
+	offset, err := abiLength(data, position*32)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := abiLength(data, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if length > (len(data)-offset-32)/32 {
+		return nil, fmt.Errorf("array of %d elements at offset %d overflows the data", length, offset)
+	}
+
+	values := make([]*big.Int, length)
+
+	for i := range values {
+		start := offset + 32 + i*32
+		values[i] = new(big.Int).SetBytes(data[start : start+32])
+	}
+
+	return values, nil
+}
+
+// abiLength decodes the offset or length word at the given byte position of the data.
+func abiLength(data []byte, position int) (int, error) {
+	if position < 0 || position+32 > len(data) {
+		return 0, fmt.Errorf("word at %d is out of the %d bytes of data", position, len(data))
+	}
+
+	word := new(big.Int).SetBytes(data[position : position+32])
+	if !word.IsInt64() || word.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("offset or length %s is out of the %d bytes of data", word, len(data))
+	}
+
+	return int(word.Int64()), nil
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNFTTransfers(t *testing.T) {
+	const (
+		operator = "0x000000000000000000000000dbf03b407c01e7cd3cbea99509d93f8dddc8c6fb"
+		from     = "0x000000000000000000000000a7d9ddbe1f17865597fbd27ec712455208b6b76d"
+		to       = "0x000000000000000000000000f02c1c8e6114b1dbe8937a39260b5b0a374432bb"
+		txHash   = "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+	)
+
+	word := func(value string) string {
+		return strings.Repeat("0", 64-len(value)) + value
+	}
+	topic := func(value [32]byte) string {
+		return "0x" + hex.EncodeToString(value[:])
+	}
+
+	erc721 := Log{
+		Address:         "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D",
+		Topics:          []string{topic(TransferEventTopic), from, to, "0x" + word("2a")},
+		Data:            "0x",
+		TransactionHash: txHash,
+		LogIndex:        "0x2",
+	}
+	single := Log{
+		Address:         "0x76be3b62873462d2142405439777e971754e8e77",
+		Topics:          []string{topic(TransferSingleEventTopic), operator, from, to},
+		Data:            "0x" + word("2a") + word("5"),
+		TransactionHash: txHash,
+		LogIndex:        "0x3",
+	}
+	batch := single
+	batch.Topics = []string{topic(TransferBatchEventTopic), operator, from, to}
+	batch.Data = "0x" + word("40") + word("a0") + word("2") + word("1") + word("2") + word("2") + word("a") + word("b")
+
+	shortBatch := batch
+	shortBatch.Data = "0x" + word("40") + word("a0") + word("2") + word("1")
+
+	erc20 := erc721
+	erc20.Topics = erc721.Topics[:3]
+	erc20.Data = "0x" + word("1")
+
+	wantBatch := []NFTTransfer{
+		{
+			Standard: ERC1155, Contract: "0x76be3b62873462d2142405439777e971754e8e77",
+			Operator: "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb", From: "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+			To: "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb", TokenID: "1", Amount: "10", TransactionHash: txHash,
+			LogIndex: "0x3",
+		},
+	}
+	wantBatch = append(wantBatch, wantBatch[0])
+	wantBatch[1].TokenID, wantBatch[1].Amount, wantBatch[1].BatchIndex = "2", "11", 1
+
+	wantSingle := wantBatch[0]
+	wantSingle.TokenID, wantSingle.Amount = "42", "5"
+
+	tests := []struct {
+		name    string
+		log     Log
+		want    []NFTTransfer
+		wantErr error
+	}{
+		{
+			name: "erc-721 transfer",
+			log:  erc721,
+			want: []NFTTransfer{{
+				Standard: ERC721, Contract: "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d",
+				From: "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d", To: "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+				TokenID: "42", Amount: "1", TransactionHash: txHash, LogIndex: "0x2",
+			}},
+		},
+		{name: "erc-1155 single transfer", log: single, want: []NFTTransfer{wantSingle}},
+		{name: "erc-1155 batch transfer", log: batch, want: wantBatch},
+		{name: "truncated batch", log: shortBatch, wantErr: ErrNotNFTTransfer},
+		{name: "erc-20 transfer", log: erc20, wantErr: ErrNotNFTTransfer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNFTTransfers(tt.log)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseNFTTransfers() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNFTTransfers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
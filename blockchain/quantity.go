@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+// maxQuantityDigits is the maximum number of hex digits of a 64 bit quantity.
+const maxQuantityDigits = 16
+
+// Quantity is a json-rpc hex encoded (e.g. "0x1b4") unsigned integer that fits in 64 bits.
+// it is used for block numbers, gas, nonces and timestamps.
+type Quantity uint64
+
+// ParseQuantity parses a json-rpc hex encoded quantity. unlike ConvertHexToInt it returns an
+// error when the value is not hex encoded, is empty or does not fit in 64 bits.
+func ParseQuantity(s string) (Quantity, error) {
+	digits, err := quantityDigits(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(strings.TrimLeft(digits, "0")) > maxQuantityDigits {
+		return 0, fmt.Errorf("%w: %q overflows 64 bits", ErrInvalidQuantity, s)
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, s)
+	}
+
+	return Quantity(value), nil
+}
+
+// Uint64 returns the quantity as an uint64.
+func (q Quantity) Uint64() uint64 {
+	return uint64(q)
+}
+
+// String returns the json-rpc hex encoding of the quantity.
+func (q Quantity) String() string {
+	return "0x" + strconv.FormatUint(uint64(q), 16)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (q Quantity) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *Quantity) UnmarshalText(text []byte) error {
+	value, err := ParseQuantity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*q = value
+
+	return nil
+}
+
+// BigQuantity is a json-rpc hex encoded unsigned integer of arbitrary size.
+// it is used for wei amounts (e.g. value and gas price) that can overflow 64 bits.
+type BigQuantity big.Int
+
+// ParseBigQuantity parses a json-rpc hex encoded quantity of arbitrary size.
+func ParseBigQuantity(s string) (*BigQuantity, error) {
+	digits, err := quantityDigits(s)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidQuantity, s)
+	}
+
+	return (*BigQuantity)(value), nil
+}
+
+// Big returns a copy of the quantity as a *big.Int.
+func (q *BigQuantity) Big() *big.Int {
+	return new(big.Int).Set((*big.Int)(q))
+}
+
+// String returns the json-rpc hex encoding of the quantity.
+func (q *BigQuantity) String() string {
+	return "0x" + (*big.Int)(q).Text(16)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (q *BigQuantity) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *BigQuantity) UnmarshalText(text []byte) error {
+	value, err := ParseBigQuantity(string(text))
+	if err != nil {
+		return err
+	}
+
+	(*big.Int)(q).Set((*big.Int)(value))
+
+	return nil
+}
+
+// quantityDigits validates s is a "0x" prefixed hex number and returns its digits.
+func quantityDigits(s string) (string, error) {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		return "", fmt.Errorf("%w: %q is missing the 0x prefix", ErrInvalidQuantity, s)
+	}
+
+	if digits == "" {
+		return "", fmt.Errorf("%w: %q has no digits", ErrInvalidQuantity, s)
+	}
+
+	for _, char := range digits {
+		if !isHexChar(char) {
+			return "", fmt.Errorf("%w: %q is not hex encoded", ErrInvalidQuantity, s)
+		}
+	}
+
+	return digits, nil
+}
+
+func isHexChar(char rune) bool {
+	return (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{name: "zero", input: "0x0", want: 0},
+		{name: "block number", input: "0x1b4", want: 436},
+		{name: "max uint64", input: "0xffffffffffffffff", want: 1<<64 - 1},
+		{name: "overflow", input: "0x10000000000000000", wantErr: true},
+		{name: "missing prefix", input: "1b4", wantErr: true},
+		{name: "decimal", input: "436", wantErr: true},
+		{name: "no digits", input: "0x", wantErr: true},
+		{name: "invalid hex", input: "0xg", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuantity(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidQuantity) {
+					t.Errorf("ParseQuantity() error = %v, want %v", err, ErrInvalidQuantity)
+				}
+
+				return
+			}
+
+			if err != nil || got.Uint64() != tt.want {
+				t.Errorf("ParseQuantity() = %v, %v, want %v, nil", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBigQuantityJSON(t *testing.T) {
+	var value struct {
+		Value *BigQuantity `json:"value"`
+		Gas   Quantity     `json:"gas"`
+	}
+
+	// 100 ETH in wei overflows int64.
+	input := `{"value":"0x56bc75e2d63100000","gas":"0x5208"}`
+
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, want nil", err)
+	}
+
+	if got := value.Value.Big().String(); got != "100000000000000000000" {
+		t.Errorf("Value = %v, want 100000000000000000000", got)
+	}
+
+	if value.Gas != 21000 {
+		t.Errorf("Gas = %v, want 21000", value.Gas.Uint64())
+	}
+
+	output, err := json.Marshal(value)
+	if err != nil || string(output) != input {
+		t.Errorf("json.Marshal() = %s, %v, want %s, nil", output, err, input)
+	}
+
+	if err := json.Unmarshal([]byte(`{"value":"100"}`), &value); !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("json.Unmarshal() error = %v, want %v", err, ErrInvalidQuantity)
+	}
+}
+
+func TestTransactionAccessors(t *testing.T) {
+	transaction := Transaction{Value: "0x56bc75e2d63100000", Gas: "0x5208", Nonce: "nil"}
+
+	value, err := transaction.ValueWei()
+	if err != nil || value.String() != "100000000000000000000" {
+		t.Errorf("ValueWei() = %v, %v, want 100000000000000000000, nil", value, err)
+	}
+
+	if gas, err := transaction.GasUint64(); err != nil || gas != 21000 {
+		t.Errorf("GasUint64() = %v, %v, want 21000, nil", gas, err)
+	}
+
+	if _, err := transaction.NonceUint64(); !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("NonceUint64() error = %v, want %v", err, ErrInvalidQuantity)
+	}
+
+	block := Block{Timestamp: "0x55ba467c"}
+
+	if got, err := block.Time(); err != nil || !got.Equal(time.Unix(1438271100, 0)) {
+		t.Errorf("Time() = %v, %v, want %v, nil", got, err, time.Unix(1438271100, 0))
+	}
+}
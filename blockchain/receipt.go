@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/spankie/tw-interview/rlp"
+)
+
+// Receipt represents the receipt of a transaction included in a block.
+type Receipt struct {
+	BlockHash         string `json:"blockHash"`
+	BlockNumber       string `json:"blockNumber"`
+	ContractAddress   string `json:"contractAddress,omitempty"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	From              string `json:"from"`
+	GasUsed           string `json:"gasUsed"`
+	Logs              []Log  `json:"logs"`
+	LogsBloom         string `json:"logsBloom"`
+	// Status is 0x1 for successful transactions and 0x0 for failed ones since byzantium.
+	Status string `json:"status,omitempty"`
+	// Root is the post transaction state root of receipts before byzantium.
+	Root             string `json:"root,omitempty"`
+	To               string `json:"to"`
+	TransactionHash  string `json:"transactionHash"`
+	TransactionIndex string `json:"transactionIndex"`
+	Type             string `json:"type,omitempty"`
+}
+
+// Log represents an event emitted by a contract during the execution of a transaction.
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// MarshalBinary returns the consensus encoding of the receipt as committed to by the
+// receipts root of a block: [status or state root, cumulative gas used, logs bloom, logs],
+// prefixed with the transaction type for typed transactions.
+func (r Receipt) MarshalBinary() ([]byte, error) {
+	fields := newFieldEncoder()
+
+	if r.Root != "" {
+		fields.data("root", r.Root)
+	} else {
+		fields.big("status", r.Status)
+	}
+
+	fields.big("cumulativeGasUsed", r.CumulativeGasUsed).data("logsBloom", r.LogsBloom)
+
+	logs := make([]any, 0, len(r.Logs))
+
+	for _, log := range r.Logs {
+		logFields := newFieldEncoder().address("log.address", log.Address).
+			hashes("log.topics", log.Topics).data("log.data", log.Data)
+		if logFields.err != nil {
+			return nil, logFields.err
+		}
+
+		logs = append(logs, logFields.fields)
+	}
+
+	items, err := fields.add(logs).list()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := rlp.Encode(items)
+	if err != nil {
+		return nil, fmt.Errorf("could not rlp encode receipt: %w", err)
+	}
+
+	txType, err := (Transaction{Type: r.Type}).TxType()
+	if err != nil {
+		return nil, err
+	}
+
+	if txType == LegacyTxType {
+		return encoded, nil
+	}
+
+	return append([]byte{byte(txType)}, encoded...), nil
+}
@@ -0,0 +1,155 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// secp256k1 curve parameters (y² = x³ + 7 over the prime field p).
+var (
+	secp256k1P, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secp256k1N, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secp256k1Gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secp256k1Gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+	// secp256k1SqrtExp is (p+1)/4, p ≡ 3 mod 4 so a^((p+1)/4) is a square root of a.
+	secp256k1SqrtExp = new(big.Int).Rsh(new(big.Int).Add(secp256k1P, big.NewInt(1)), 2)
+)
+
+// curvePoint is an affine point on secp256k1. a nil x is the point at infinity.
+type curvePoint struct {
+	x, y *big.Int
+}
+
+func (p curvePoint) isInfinity() bool {
+	return p.x == nil
+}
+
+// RecoverAddress recovers the address of the key that signed hash with the signature
+// (r, s) and recovery id (0 or 1, the parity of the y coordinate of the signature point).
+// signatures with s in the upper half of the curve order are rejected (EIP-2).
+func RecoverAddress(hash []byte, r, s *big.Int, recoveryID uint64) (Address, error) {
+	publicKey, err := recoverPublicKey(hash, r, s, recoveryID)
+	if err != nil {
+		return Address{}, err
+	}
+
+	return publicKeyAddress(publicKey), nil
+}
+
+// recoverPublicKey computes Q = r⁻¹(sR - eG) where R is the curve point with x = r.
+func recoverPublicKey(hash []byte, r, s *big.Int, recoveryID uint64) (curvePoint, error) {
+	if recoveryID > 1 || r.Sign() <= 0 || s.Sign() <= 0 ||
+		r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1HalfN) > 0 {
+		return curvePoint{}, ErrInvalidSignature
+	}
+
+	// y² = x³ + 7.
+	ySquared := new(big.Int).Exp(r, big.NewInt(3), secp256k1P)
+	ySquared.Add(ySquared, big.NewInt(7)).Mod(ySquared, secp256k1P)
+
+	y := new(big.Int).Exp(ySquared, secp256k1SqrtExp, secp256k1P)
+	if new(big.Int).Exp(y, big.NewInt(2), secp256k1P).Cmp(ySquared) != 0 {
+		return curvePoint{}, ErrInvalidSignature
+	}
+
+	if y.Bit(0) != uint(recoveryID) {
+		y.Sub(secp256k1P, y)
+	}
+
+	e := new(big.Int).SetBytes(hash)
+	e.Mod(e, secp256k1N)
+
+	rInverse := new(big.Int).ModInverse(r, secp256k1N)
+
+	// u1 = -e * r⁻¹, u2 = s * r⁻¹.
+	u1 := new(big.Int).Mul(new(big.Int).Neg(e), rInverse)
+	u1.Mod(u1, secp256k1N)
+
+	u2 := new(big.Int).Mul(s, rInverse)
+	u2.Mod(u2, secp256k1N)
+
+	generator := curvePoint{x: secp256k1Gx, y: secp256k1Gy}
+	publicKey := addPoints(scalarMult(generator, u1), scalarMult(curvePoint{x: new(big.Int).Set(r), y: y}, u2))
+
+	if publicKey.isInfinity() {
+		return curvePoint{}, ErrInvalidSignature
+	}
+
+	return publicKey, nil
+}
+
+// publicKeyAddress derives the address of a public key from the last 20 bytes
+// of the Keccak-256 hash of its uncompressed coordinates.
+func publicKeyAddress(publicKey curvePoint) Address {
+	var coordinates [64]byte
+
+	publicKey.x.FillBytes(coordinates[:32])
+	publicKey.y.FillBytes(coordinates[32:])
+
+	return BytesToAddress(Keccak256(coordinates[:])[12:])
+}
+
+// scalarMult computes k*point using double and add.
+func scalarMult(point curvePoint, k *big.Int) curvePoint {
+	result := curvePoint{}
+
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = addPoints(result, result)
+
+		if k.Bit(i) == 1 {
+			result = addPoints(result, point)
+		}
+	}
+
+	return result
+}
+
+// addPoints adds two affine points, handling doubling and the point at infinity.
+func addPoints(a, b curvePoint) curvePoint {
+	if a.isInfinity() {
+		return b
+	}
+
+	if b.isInfinity() {
+		return a
+	}
+
+	lambda := new(big.Int)
+
+	if a.x.Cmp(b.x) == 0 {
+		// a + (-a) is the point at infinity.
+		sum := new(big.Int).Add(a.y, b.y)
+		if sum.Mod(sum, secp256k1P).Sign() == 0 {
+			return curvePoint{}
+		}
+
+		// λ = 3x² / 2y.
+		numerator := new(big.Int).Mul(a.x, a.x)
+		numerator.Mul(numerator, big.NewInt(3))
+
+		denominator := new(big.Int).Lsh(a.y, 1)
+		denominator.Mod(denominator, secp256k1P)
+		lambda.Mul(numerator, denominator.ModInverse(denominator, secp256k1P))
+	} else {
+		// λ = (y2 - y1) / (x2 - x1).
+		numerator := new(big.Int).Sub(b.y, a.y)
+
+		denominator := new(big.Int).Sub(b.x, a.x)
+		denominator.Mod(denominator, secp256k1P)
+		lambda.Mul(numerator, denominator.ModInverse(denominator, secp256k1P))
+	}
+
+	lambda.Mod(lambda, secp256k1P)
+
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, secp256k1P)
+
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, lambda).Sub(y, a.y).Mod(y, secp256k1P)
+
+	return curvePoint{x: x, y: y}
+}
@@ -0,0 +1,337 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spankie/tw-interview/rlp"
+)
+
+var (
+	ErrInvalidHexData = errors.New("invalid hex data")
+	ErrHashMismatch   = errors.New("transaction hash mismatch")
+	ErrSenderMismatch = errors.New("transaction sender mismatch")
+)
+
+// hashLength is the length of a Keccak-256 hash in bytes.
+const hashLength = 32
+
+// MarshalBinary returns the canonical EIP-2718 encoding of the signed transaction: the
+// RLP encoded fields for legacy transactions and the type byte followed by the RLP
+// encoded fields for typed transactions.
+func (t Transaction) MarshalBinary() ([]byte, error) {
+	return t.encode(true)
+}
+
+// ComputeHash recomputes the hash of the transaction from its fields.
+func (t Transaction) ComputeHash() (string, error) {
+	encoded, err := t.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	return "0x" + hex.EncodeToString(Keccak256(encoded)), nil
+}
+
+// SigningHash returns the hash the sender signed to authorize the transaction.
+func (t Transaction) SigningHash() ([]byte, error) {
+	encoded, err := t.encode(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return Keccak256(encoded), nil
+}
+
+// RecoverSender recovers the address that signed the transaction from its V, R and S values.
+func (t Transaction) RecoverSender() (Address, error) {
+	hash, err := t.SigningHash()
+	if err != nil {
+		return Address{}, err
+	}
+
+	recoveryID, err := t.recoveryID()
+	if err != nil {
+		return Address{}, err
+	}
+
+	r, err := parseBigField("r", t.R)
+	if err != nil {
+		return Address{}, err
+	}
+
+	s, err := parseBigField("s", t.S)
+	if err != nil {
+		return Address{}, err
+	}
+
+	return RecoverAddress(hash, r, s, recoveryID)
+}
+
+// VerifyTransaction checks that the hash and sender of a transaction returned by a node
+// match the hash and sender recomputed from its fields and signature.
+func VerifyTransaction(t Transaction) error {
+	hash, err := t.ComputeHash()
+	if err != nil {
+		return fmt.Errorf("could not compute hash of transaction %s: %w", t.Hash, err)
+	}
+
+	if !strings.EqualFold(hash, t.Hash) {
+		return fmt.Errorf("%w: got %s, computed %s", ErrHashMismatch, t.Hash, hash)
+	}
+
+	sender, err := t.RecoverSender()
+	if err != nil {
+		return fmt.Errorf("could not recover sender of transaction %s: %w", t.Hash, err)
+	}
+
+	if !strings.EqualFold(sender.Key(), t.From) {
+		return fmt.Errorf("%w: got %s, recovered %s", ErrSenderMismatch, t.From, sender.Hex())
+	}
+
+	return nil
+}
+
+// recoveryID returns the parity of the y coordinate of the signature point.
+func (t Transaction) recoveryID() (uint64, error) {
+	txType, err := t.TxType()
+	if err != nil {
+		return 0, err
+	}
+
+	if txType != LegacyTxType {
+		// nodes return the parity as both yParity and v for typed transactions.
+		yParity := t.YParity
+		if yParity == "" {
+			yParity = t.V
+		}
+
+		return parseField("yParity", yParity)
+	}
+
+	v, err := parseField("v", t.V)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case v == 27 || v == 28:
+		return v - 27, nil
+	case v >= 35:
+		// EIP-155: v = chainId * 2 + 35 + parity.
+		return (v - 35) % 2, nil
+	default:
+		return 0, fmt.Errorf("%w: invalid v %d", ErrInvalidSignature, v)
+	}
+}
+
+// encode returns the EIP-2718 encoding of the transaction. when signed is false the
+// signature is left out, which is the payload the sender signs.
+func (t Transaction) encode(signed bool) ([]byte, error) {
+	txType, err := t.TxType()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := t.rlpFields(txType, signed)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := rlp.Encode(fields)
+	if err != nil {
+		return nil, fmt.Errorf("could not rlp encode transaction: %w", err)
+	}
+
+	if txType == LegacyTxType {
+		return encoded, nil
+	}
+
+	return append([]byte{byte(txType)}, encoded...), nil
+}
+
+// rlpFields returns the fields of the transaction in the order they are encoded for its type.
+func (t Transaction) rlpFields(txType TxType, signed bool) ([]any, error) {
+	fields := newFieldEncoder()
+
+	if txType == LegacyTxType {
+		fields.big("nonce", t.Nonce).big("gasPrice", t.GasPrice).big("gas", t.Gas).
+			address("to", t.To).big("value", t.Value).data("input", t.Input)
+
+		if signed {
+			fields.big("v", t.V).big("r", t.R).big("s", t.S)
+		} else if chainID := t.legacyChainID(); chainID != nil {
+			// EIP-155 replay protected transactions sign over the chain id.
+			fields.add(chainID, uint64(0), uint64(0))
+		}
+
+		return fields.list()
+	}
+
+	fields.big("chainId", t.ChainID).big("nonce", t.Nonce)
+
+	if txType == AccessListTxType {
+		fields.big("gasPrice", t.GasPrice)
+	} else {
+		fields.big("maxPriorityFeePerGas", t.MaxPriorityFeePerGas).big("maxFeePerGas", t.MaxFeePerGas)
+	}
+
+	fields.big("gas", t.Gas).address("to", t.To).big("value", t.Value).data("input", t.Input).
+		accessList(t.AccessList)
+
+	if txType == BlobTxType {
+		fields.big("maxFeePerBlobGas", t.MaxFeePerBlobGas).hashes("blobVersionedHashes", t.BlobVersionedHashes)
+	}
+
+	if txType == SetCodeTxType {
+		fields.authorizationList(t.AuthorizationList)
+	}
+
+	if signed {
+		recoveryID, err := t.recoveryID()
+		if err != nil {
+			return nil, err
+		}
+
+		fields.add(recoveryID).big("r", t.R).big("s", t.S)
+	}
+
+	return fields.list()
+}
+
+// legacyChainID returns the chain id of an EIP-155 replay protected legacy
+// transaction or nil for unprotected transactions.
+func (t Transaction) legacyChainID() *big.Int {
+	v, err := parseBigField("v", t.V)
+	if err != nil || v.Cmp(big.NewInt(35)) < 0 {
+		return nil
+	}
+
+	return v.Sub(v, big.NewInt(35)).Rsh(v, 1)
+}
+
+// fieldEncoder collects rlp fields parsed from hex strings, keeping the first parse error.
+type fieldEncoder struct {
+	fields []any
+	err    error
+}
+
+func newFieldEncoder() *fieldEncoder {
+	return &fieldEncoder{fields: make([]any, 0)}
+}
+
+func (e *fieldEncoder) add(values ...any) *fieldEncoder {
+	e.fields = append(e.fields, values...)
+
+	return e
+}
+
+func (e *fieldEncoder) setErr(err error) *fieldEncoder {
+	if e.err == nil {
+		e.err = err
+	}
+
+	return e
+}
+
+func (e *fieldEncoder) big(name, value string) *fieldEncoder {
+	quantity, err := parseBigField(name, value)
+	if err != nil {
+		return e.setErr(err)
+	}
+
+	return e.add(quantity)
+}
+
+// address adds an address field, empty for contract creations.
+func (e *fieldEncoder) address(name, value string) *fieldEncoder {
+	if value == "" {
+		return e.add([]byte{})
+	}
+
+	data, err := decodeHexData(value)
+	if err != nil || len(data) != AddressLength {
+		return e.setErr(fmt.Errorf("field %s: %w: %q", name, ErrInvalidAddress, value))
+	}
+
+	return e.add(data)
+}
+
+func (e *fieldEncoder) data(name, value string) *fieldEncoder {
+	data, err := decodeHexData(value)
+	if err != nil {
+		return e.setErr(fmt.Errorf("field %s: %w", name, err))
+	}
+
+	return e.add(data)
+}
+
+func (e *fieldEncoder) hashes(name string, values []string) *fieldEncoder {
+	hashes := make([]any, 0, len(values))
+
+	for _, value := range values {
+		hash, err := decodeHexData(value)
+		if err != nil || len(hash) != hashLength {
+			return e.setErr(fmt.Errorf("field %s: %w: %q", name, ErrInvalidHexData, value))
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	return e.add(hashes)
+}
+
+func (e *fieldEncoder) accessList(accessList []AccessTuple) *fieldEncoder {
+	tuples := make([]any, 0, len(accessList))
+
+	for _, tuple := range accessList {
+		tupleFields := newFieldEncoder().address("accessList.address", tuple.Address).
+			hashes("accessList.storageKeys", tuple.StorageKeys)
+		if tupleFields.err != nil {
+			return e.setErr(tupleFields.err)
+		}
+
+		tuples = append(tuples, tupleFields.fields)
+	}
+
+	return e.add(tuples)
+}
+
+func (e *fieldEncoder) authorizationList(authorizations []SetCodeAuthorization) *fieldEncoder {
+	tuples := make([]any, 0, len(authorizations))
+
+	for _, auth := range authorizations {
+		authFields := newFieldEncoder().big("authorization.chainId", auth.ChainID).
+			address("authorization.address", auth.Address).big("authorization.nonce", auth.Nonce).
+			big("authorization.yParity", auth.YParity).big("authorization.r", auth.R).big("authorization.s", auth.S)
+		if authFields.err != nil {
+			return e.setErr(authFields.err)
+		}
+
+		tuples = append(tuples, authFields.fields)
+	}
+
+	return e.add(tuples)
+}
+
+func (e *fieldEncoder) list() ([]any, error) {
+	return e.fields, e.err
+}
+
+// decodeHexData decodes "0x" prefixed hex data. "0x" decodes to an empty slice.
+func decodeHexData(value string) ([]byte, error) {
+	digits, ok := strings.CutPrefix(value, "0x")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is missing the 0x prefix", ErrInvalidHexData, value)
+	}
+
+	data, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHexData, value)
+	}
+
+	return data, nil
+}
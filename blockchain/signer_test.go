@@ -0,0 +1,167 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// signTransaction signs the transaction with the private key and sets its signature,
+// hash and sender. the nonce k is derived from the key and hash, which is good enough for tests.
+func signTransaction(t *testing.T, transaction Transaction, privateKey *big.Int) Transaction {
+	t.Helper()
+
+	hash, err := transaction.SigningHash()
+	if err != nil {
+		t.Fatalf("SigningHash() error = %v", err)
+	}
+
+	k := new(big.Int).SetBytes(Keccak256(privateKey.Bytes(), hash))
+	k.Mod(k, secp256k1N)
+
+	point := scalarMult(curvePoint{x: secp256k1Gx, y: secp256k1Gy}, k)
+	r := new(big.Int).Mod(point.x, secp256k1N)
+	recoveryID := point.y.Bit(0)
+
+	// s = k⁻¹(e + r*d) mod n.
+	s := new(big.Int).Mul(r, privateKey)
+	s.Add(s, new(big.Int).SetBytes(hash)).Mul(s, new(big.Int).ModInverse(k, secp256k1N)).Mod(s, secp256k1N)
+
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s.Sub(secp256k1N, s)
+		recoveryID ^= 1
+	}
+
+	transaction.R = "0x" + r.Text(16)
+	transaction.S = "0x" + s.Text(16)
+	if transaction.Type == "" {
+		// unprotected legacy transactions carry 27 + parity as v.
+		transaction.V = Quantity(27 + recoveryID).String()
+	} else {
+		transaction.V = Quantity(recoveryID).String()
+		transaction.YParity = transaction.V
+	}
+
+	publicKey := scalarMult(curvePoint{x: secp256k1Gx, y: secp256k1Gy}, privateKey)
+	transaction.From = publicKeyAddress(publicKey).Key()
+
+	transaction.Hash, err = transaction.ComputeHash()
+	if err != nil {
+		t.Fatalf("ComputeHash() error = %v", err)
+	}
+
+	return transaction
+}
+
+func TestLegacyTransactionEIP155(t *testing.T) {
+	// the example transaction of EIP-155, signed with the private key 0x4646...46.
+	transaction := Transaction{
+		Nonce:    "0x9",
+		GasPrice: "0x4a817c800",
+		Gas:      "0x5208",
+		To:       "0x3535353535353535353535353535353535353535",
+		Value:    "0xde0b6b3a7640000",
+		Input:    "0x",
+		V:        "0x25",
+		R:        "0x28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276",
+		S:        "0x67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+	}
+
+	wantRaw := "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a0" +
+		"28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a0" +
+		"67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+
+	raw, err := transaction.MarshalBinary()
+	if err != nil || hex.EncodeToString(raw) != wantRaw {
+		t.Errorf("MarshalBinary() = %x, %v, want %s, nil", raw, err, wantRaw)
+	}
+
+	signingHash, err := transaction.SigningHash()
+	wantSigningHash := "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53"
+
+	if err != nil || hex.EncodeToString(signingHash) != wantSigningHash {
+		t.Errorf("SigningHash() = %x, %v, want %s, nil", signingHash, err, wantSigningHash)
+	}
+
+	sender, err := transaction.RecoverSender()
+	if err != nil || sender.Hex() != "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F" {
+		t.Errorf("RecoverSender() = %v, %v, want 0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F, nil", sender, err)
+	}
+
+	transaction.Hash = "0x" + hex.EncodeToString(Keccak256(raw))
+	transaction.From = "0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"
+
+	if err := VerifyTransaction(transaction); err != nil {
+		t.Errorf("VerifyTransaction() = %v, want nil", err)
+	}
+}
+
+func TestVerifyTypedTransactions(t *testing.T) {
+	privateKey := big.NewInt(0x4646)
+	accessList := []AccessTuple{{
+		Address:     "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae",
+		StorageKeys: []string{"0x0000000000000000000000000000000000000000000000000000000000000003"},
+	}}
+
+	base := Transaction{
+		ChainID: "0x1", Nonce: "0x1", Gas: "0x5208", To: "0x3535353535353535353535353535353535353535",
+		Value: "0xde0b6b3a7640000", Input: "0xa9059cbb", AccessList: accessList,
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Transaction)
+	}{
+		{name: "unprotected legacy", mutate: func(tx *Transaction) { tx.ChainID, tx.AccessList, tx.GasPrice = "", nil, "0x1" }},
+		{name: "access list", mutate: func(tx *Transaction) { tx.Type, tx.GasPrice = "0x1", "0x1" }},
+		{name: "dynamic fee", mutate: func(tx *Transaction) {
+			tx.Type, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = "0x2", "0x77359400", "0x3b9aca00"
+		}},
+		{name: "blob", mutate: func(tx *Transaction) {
+			tx.Type, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas, tx.MaxFeePerBlobGas = "0x3", "0x2", "0x1", "0x1"
+			tx.BlobVersionedHashes = []string{"0x01" + hex.EncodeToString(make([]byte, 31))}
+		}},
+		{name: "set code", mutate: func(tx *Transaction) {
+			tx.Type, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = "0x4", "0x2", "0x1"
+			tx.AuthorizationList = []SetCodeAuthorization{{
+				ChainID: "0x1", Address: "0x3535353535353535353535353535353535353535",
+				Nonce: "0x0", YParity: "0x1", R: "0x1", S: "0x2",
+			}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction := base
+			tt.mutate(&transaction)
+
+			signed := signTransaction(t, transaction, privateKey)
+
+			if err := VerifyTransaction(signed); err != nil {
+				t.Fatalf("VerifyTransaction() = %v, want nil", err)
+			}
+
+			tamperedHash := signed
+			tamperedHash.Value = "0x1"
+
+			if err := VerifyTransaction(tamperedHash); !errors.Is(err, ErrHashMismatch) {
+				t.Errorf("VerifyTransaction() of tampered value = %v, want %v", err, ErrHashMismatch)
+			}
+
+			tamperedSender := signed
+			tamperedSender.From = "0x3535353535353535353535353535353535353535"
+
+			if err := VerifyTransaction(tamperedSender); !errors.Is(err, ErrSenderMismatch) {
+				t.Errorf("VerifyTransaction() of tampered sender = %v, want %v", err, ErrSenderMismatch)
+			}
+		})
+	}
+}
+
+func TestRecoverAddressOfKnownKey(t *testing.T) {
+	// the address of private key 1 is derived from the generator point itself.
+	address := publicKeyAddress(curvePoint{x: secp256k1Gx, y: secp256k1Gy})
+	if address.Hex() != "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf" {
+		t.Errorf("publicKeyAddress(G) = %v, want 0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf", address)
+	}
+}
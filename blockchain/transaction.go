@@ -1,12 +1,29 @@
 package blockchain
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 	"strconv"
-	"strings"
 )
 
-// Transaction represents a transaction in a block.
+var ErrInvalidAddress = errors.New("invalid ethereum address")
+
+// TxType is the EIP-2718 type of a transaction.
+type TxType uint8
+
+const (
+	LegacyTxType     TxType = 0x00
+	AccessListTxType TxType = 0x01 // EIP-2930
+	DynamicFeeTxType TxType = 0x02 // EIP-1559
+	BlobTxType       TxType = 0x03 // EIP-4844
+	SetCodeTxType    TxType = 0x04 // EIP-7702
+)
+
+var ErrUnsupportedTxType = errors.New("unsupported transaction type")
+
+// Transaction represents a transaction in a block. fields that only exist for
+// some transaction types are omitted when empty.
 type Transaction struct {
 	BlockHash        string `json:"blockHash"`
 	BlockNumber      string `json:"blockNumber"`
@@ -22,12 +39,153 @@ type Transaction struct {
 	V                string `json:"v"`
 	R                string `json:"r"`
 	S                string `json:"s"`
+
+	// EIP-2718 typed transaction fields.
+	Type       string        `json:"type,omitempty"`
+	ChainID    string        `json:"chainId,omitempty"`
+	AccessList []AccessTuple `json:"accessList,omitempty"`
+	YParity    string        `json:"yParity,omitempty"`
+
+	// EIP-1559 fee market fields.
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+
+	// EIP-4844 blob fields.
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes,omitempty"`
+
+	// EIP-7702 set code fields.
+	AuthorizationList []SetCodeAuthorization `json:"authorizationList,omitempty"`
+}
+
+// AccessTuple is an address and the storage keys a transaction plans to access (EIP-2930).
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// SetCodeAuthorization authorizes an account to delegate its code to Address (EIP-7702).
+type SetCodeAuthorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"`
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
 }
 
 func (t Transaction) String() string {
-	return strconv.FormatInt(ConvertHexToInt(t.Nonce), 10)
+	nonce, err := t.NonceUint64()
+	if err != nil {
+		return t.Nonce
+	}
+
+	return strconv.FormatUint(nonce, 10)
 }
 
+// TxType returns the EIP-2718 type of the transaction. transactions without a type are legacy.
+func (t Transaction) TxType() (TxType, error) {
+	if t.Type == "" {
+		return LegacyTxType, nil
+	}
+
+	txType, err := parseField("type", t.Type)
+	if err != nil {
+		return 0, err
+	}
+
+	if txType > uint64(SetCodeTxType) {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedTxType, t.Type)
+	}
+
+	return TxType(txType), nil
+}
+
+// EffectiveGasPrice returns the price per gas paid by the transaction in a block with the
+// given base fee. for fee market transactions it is min(maxFeePerGas, baseFee + maxPriorityFeePerGas),
+// other transactions pay their gas price.
+func (t Transaction) EffectiveGasPrice(baseFee *big.Int) (*big.Int, error) {
+	txType, err := t.TxType()
+	if err != nil {
+		return nil, err
+	}
+
+	if txType < DynamicFeeTxType || baseFee == nil {
+		return t.GasPriceWei()
+	}
+
+	maxFee, err := parseBigField("maxFeePerGas", t.MaxFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPriorityFee, err := parseBigField("maxPriorityFeePerGas", t.MaxPriorityFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+
+	price := new(big.Int).Add(baseFee, maxPriorityFee)
+	if price.Cmp(maxFee) > 0 {
+		return maxFee, nil
+	}
+
+	return price, nil
+}
+
+// ValueWei returns the value transferred in wei.
+func (t Transaction) ValueWei() (*big.Int, error) {
+	return parseBigField("value", t.Value)
+}
+
+// GasPriceWei returns the gas price in wei.
+func (t Transaction) GasPriceWei() (*big.Int, error) {
+	return parseBigField("gasPrice", t.GasPrice)
+}
+
+// GasUint64 returns the gas limit of the transaction.
+func (t Transaction) GasUint64() (uint64, error) {
+	return parseField("gas", t.Gas)
+}
+
+// NonceUint64 returns the nonce of the transaction.
+func (t Transaction) NonceUint64() (uint64, error) {
+	return parseField("nonce", t.Nonce)
+}
+
+// BlockNumberUint64 returns the number of the block the transaction was included in.
+func (t Transaction) BlockNumberUint64() (uint64, error) {
+	return parseField("blockNumber", t.BlockNumber)
+}
+
+// IndexUint64 returns the index of the transaction in its block.
+func (t Transaction) IndexUint64() (uint64, error) {
+	return parseField("transactionIndex", t.TransactionIndex)
+}
+
+// parseField parses a hex encoded quantity field, naming the field in the error.
+func parseField(name, value string) (uint64, error) {
+	quantity, err := ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("field %s: %w", name, err)
+	}
+
+	return quantity.Uint64(), nil
+}
+
+// parseBigField parses a hex encoded big quantity field, naming the field in the error.
+func parseBigField(name, value string) (*big.Int, error) {
+	quantity, err := ParseBigQuantity(value)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", name, err)
+	}
+
+	return quantity.Big(), nil
+}
+
+// ConvertHexToInt converts a hex string to an int64.
+//
+// Deprecated: values that overflow int64 are truncated and malformed values
+// silently become 0. Use ParseQuantity or ParseBigQuantity instead.
 func ConvertHexToInt(hex string) int64 {
 	bigNumber := new(big.Int)
 
@@ -39,27 +197,10 @@ func ConvertHexToInt(hex string) int64 {
 	return bigNumber.Int64()
 }
 
-// IsValidEthereumAddress validates an ethereum address.
+// IsValidEthereumAddress validates an ethereum address. mixed case addresses
+// must have a valid EIP-55 checksum.
 func IsValidEthereumAddress(address string) bool {
-	// Check if the address starts with "0x".
-	if !strings.HasPrefix(address, "0x") {
-		return false
-	}
-
-	// Remove the "0x" prefix.
-	address = strings.TrimPrefix(address, "0x")
-
-	// Check if the address has exactly 40 characters.
-	if len(address) != 40 {
-		return false
-	}
-
-	// Check if the address contains only hexadecimal characters.
-	for _, char := range address {
-		if !((char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')) {
-			return false
-		}
-	}
+	_, err := ParseAddress(address)
 
-	return true
+	return err == nil
 }
@@ -1,6 +1,11 @@
 package blockchain
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
 
 func TestConvertHexToString(t *testing.T) {
 	tests := []struct {
@@ -37,3 +42,52 @@ func TestConvertHexToString(t *testing.T) {
 		})
 	}
 }
+
+func TestTypedTransaction(t *testing.T) {
+	input := `{
+		"type": "0x2",
+		"chainId": "0x1",
+		"nonce": "0x1",
+		"gas": "0x5208",
+		"gasPrice": "0x3b9aca00",
+		"maxFeePerGas": "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"accessList": [{"address": "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae", "storageKeys": []}],
+		"yParity": "0x1"
+	}`
+
+	var transaction Transaction
+	if err := json.Unmarshal([]byte(input), &transaction); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, want nil", err)
+	}
+
+	if txType, err := transaction.TxType(); err != nil || txType != DynamicFeeTxType {
+		t.Errorf("TxType() = %v, %v, want %v, nil", txType, err, DynamicFeeTxType)
+	}
+
+	if len(transaction.AccessList) != 1 {
+		t.Errorf("len(AccessList) = %d, want 1", len(transaction.AccessList))
+	}
+
+	tests := []struct {
+		name    string
+		baseFee *big.Int
+		want    int64
+	}{
+		{name: "tip on top of base fee", baseFee: big.NewInt(500_000_000), want: 1_500_000_000},
+		{name: "capped by max fee", baseFee: big.NewInt(1_800_000_000), want: 2_000_000_000},
+		{name: "pre-london block", baseFee: nil, want: 1_000_000_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transaction.EffectiveGasPrice(tt.baseFee)
+			if err != nil || got.Int64() != tt.want {
+				t.Errorf("EffectiveGasPrice() = %v, %v, want %v, nil", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Transaction{Type: "0x7f"}).TxType(); !errors.Is(err, ErrUnsupportedTxType) {
+		t.Errorf("TxType() error = %v, want %v", err, ErrUnsupportedTxType)
+	}
+}
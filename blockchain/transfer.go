@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrNotTokenTransfer = errors.New("log is not an ERC-20 token transfer")
+
+// TransferEventTopic is the event signature topic of Transfer(address,address,uint256),
+// emitted by ERC-20 tokens, and ERC-721 tokens with an indexed token id.
+var TransferEventTopic = [32]byte(Keccak256([]byte("Transfer(address,address,uint256)")))
+
+// TokenTransfer is an ERC-20 token transfer decoded from its Transfer log.
+type TokenTransfer struct {
+	// Token is the address of the token contract that emitted the transfer.
+	Token string `json:"token"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	// Amount is the raw amount transferred in the smallest unit of the token, in decimal.
+	Amount          string `json:"amount"`
+	TransactionHash string `json:"transactionHash"`
+	LogIndex        string `json:"logIndex"`
+}
+
+// IsTransferLog reports whether the log is a Transfer event, of an ERC-20 or ERC-721 token.
+func IsTransferLog(log Log) bool {
+	if len(log.Topics) == 0 {
+		return false
+	}
+
+	signature, err := ParseTopic(log.Topics[0])
+
+	return err == nil && signature == TransferEventTopic
+}
+
+// ParseTokenTransfer decodes an ERC-20 Transfer(address indexed from, address indexed to,
+// uint256 value) log. ERC-721 transfers, which index the token id as a fourth topic, are
+// not token transfers.
+func ParseTokenTransfer(log Log) (TokenTransfer, error) {
+	if !IsTransferLog(log) || len(log.Topics) != 3 {
+		return TokenTransfer{}, fmt.Errorf("%w: log %s of %s", ErrNotTokenTransfer, log.LogIndex, log.TransactionHash)
+	}
+
+	token, err := ParseAddress(log.Address)
+	if err != nil {
+		return TokenTransfer{}, fmt.Errorf("field address: %w", err)
+	}
+
+	from, err := topicAddress(log.Topics[1])
+	if err != nil {
+		return TokenTransfer{}, fmt.Errorf("field from: %w", err)
+	}
+
+	to, err := topicAddress(log.Topics[2])
+	if err != nil {
+		return TokenTransfer{}, fmt.Errorf("field to: %w", err)
+	}
+
+	data, err := decodeHexData(log.Data)
+	if err != nil {
+		return TokenTransfer{}, fmt.Errorf("field data: %w", err)
+	}
+
+	if len(data) != 32 {
+		return TokenTransfer{}, fmt.Errorf("%w: data of log %s of %s is not a uint256",
+			ErrNotTokenTransfer, log.LogIndex, log.TransactionHash)
+	}
+
+	return TokenTransfer{
+		Token:           token.Key(),
+		From:            from.Key(),
+		To:              to.Key(),
+		Amount:          new(big.Int).SetBytes(data).String(),
+		TransactionHash: log.TransactionHash,
+		LogIndex:        log.LogIndex,
+	}, nil
+}
+
+// topicAddress decodes an address passed as an indexed event argument. see AddressTopic.
+func topicAddress(value string) (Address, error) {
+	topic, err := ParseTopic(value)
+	if err != nil {
+		return Address{}, err
+	}
+
+	for _, b := range topic[:len(topic)-AddressLength] {
+		if b != 0 {
+			return Address{}, fmt.Errorf("%w: topic %s is not an address", ErrInvalidHexData, value)
+		}
+	}
+
+	return BytesToAddress(topic[len(topic)-AddressLength:]), nil
+}
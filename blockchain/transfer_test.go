@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestParseTokenTransfer(t *testing.T) {
+	transfer := Log{
+		Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			"0x000000000000000000000000a7d9ddbe1f17865597fbd27ec712455208b6b76d",
+			"0x000000000000000000000000f02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+		},
+		Data:            "0x0000000000000000000000000000000000000000000000000000000077359400",
+		TransactionHash: "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+		LogIndex:        "0x1",
+	}
+
+	nft := transfer
+	nft.Topics = append(append([]string{}, transfer.Topics...),
+		"0x0000000000000000000000000000000000000000000000000000000000000001")
+	nft.Data = "0x"
+
+	approval := transfer
+	approval.Topics = append([]string{"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"},
+		transfer.Topics[1:]...)
+
+	paddedAddress := transfer
+	paddedAddress.Topics = []string{transfer.Topics[0],
+		"0x010000000000000000000000a7d9ddbe1f17865597fbd27ec712455208b6b76d", transfer.Topics[2]}
+
+	tests := []struct {
+		name    string
+		log     Log
+		want    TokenTransfer
+		wantErr error
+	}{
+		{
+			name: "erc-20 transfer",
+			log:  transfer,
+			want: TokenTransfer{
+				Token:           "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+				From:            "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+				To:              "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+				Amount:          "2000000000",
+				TransactionHash: transfer.TransactionHash,
+				LogIndex:        "0x1",
+			},
+		},
+		{name: "erc-721 transfer", log: nft, wantErr: ErrNotTokenTransfer},
+		{name: "approval", log: approval, wantErr: ErrNotTokenTransfer},
+		{name: "not an address", log: paddedAddress, wantErr: ErrInvalidHexData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTokenTransfer(tt.log)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseTokenTransfer() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseTokenTransfer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferEventTopic(t *testing.T) {
+	want := "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	if got := hex.EncodeToString(TransferEventTopic[:]); got != want {
+		t.Errorf("TransferEventTopic = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package blockchain
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/spankie/tw-interview/rlp"
+)
+
+// EmptyRootHash is the root hash of an empty Merkle Patricia trie, keccak256(rlp("")).
+const EmptyRootHash = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+
+// Trie builds a Merkle Patricia trie in memory to compute its root hash. it only
+// supports inserting key value pairs and hashing, which is all that is needed to
+// recompute the transactions, receipts and withdrawals roots of a block.
+type Trie struct {
+	entries map[string][]byte
+}
+
+// trieEntry is a key of the trie split in nibbles and its value.
+type trieEntry struct {
+	nibbles []byte
+	value   []byte
+}
+
+// NewTrie creates an empty trie.
+func NewTrie() *Trie {
+	return &Trie{entries: make(map[string][]byte)}
+}
+
+// Put inserts or replaces the value of key. an empty value removes the key.
+func (t *Trie) Put(key, value []byte) {
+	if len(value) == 0 {
+		delete(t.entries, string(key))
+		return
+	}
+
+	t.entries[string(key)] = value
+}
+
+// Hash returns the root hash of the trie.
+func (t *Trie) Hash() [32]byte {
+	entries := make([]trieEntry, 0, len(t.entries))
+
+	for key, value := range t.entries {
+		entries = append(entries, trieEntry{nibbles: keyNibbles([]byte(key)), value: value})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].nibbles, entries[j].nibbles) < 0
+	})
+
+	return Keccak256Hash(encodeTrieNode(entries, 0))
+}
+
+// encodeTrieNode returns the RLP encoding of the node holding the sorted entries
+// from the nibble at depth onwards.
+func encodeTrieNode(entries []trieEntry, depth int) []byte {
+	if len(entries) == 0 {
+		return rlp.EmptyString
+	}
+
+	if len(entries) == 1 {
+		// leaf node: [hex prefix encoded remaining key, value].
+		return mustEncode([]any{hexPrefix(entries[0].nibbles[depth:], true), entries[0].value})
+	}
+
+	// extension node: [hex prefix encoded shared key, child].
+	if shared := sharedPrefixLength(entries, depth); shared > 0 {
+		sharedNibbles := entries[0].nibbles[depth : depth+shared]
+
+		return mustEncode([]any{hexPrefix(sharedNibbles, false), trieNodeRef(encodeTrieNode(entries, depth+shared))})
+	}
+
+	// branch node: [child for each of the 16 nibbles, value of the key ending here].
+	branch := make([]any, 17)
+	branch[16] = []byte{}
+
+	for len(entries) > 0 && len(entries[0].nibbles) == depth {
+		branch[16] = entries[0].value
+		entries = entries[1:]
+	}
+
+	for nibble := range byte(16) {
+		end := 0
+		for end < len(entries) && entries[end].nibbles[depth] == nibble {
+			end++
+		}
+
+		if end == 0 {
+			branch[nibble] = []byte{}
+			continue
+		}
+
+		branch[nibble] = trieNodeRef(encodeTrieNode(entries[:end], depth+1))
+		entries = entries[end:]
+	}
+
+	return mustEncode(branch)
+}
+
+// trieNodeRef returns how a node is referenced from its parent: nodes shorter than
+// 32 bytes are embedded, other nodes are referenced by their hash.
+func trieNodeRef(encoded []byte) any {
+	if len(encoded) < hashLength {
+		return rlp.RawValue(encoded)
+	}
+
+	return Keccak256(encoded)
+}
+
+// sharedPrefixLength returns the number of nibbles all the sorted entries share after depth.
+func sharedPrefixLength(entries []trieEntry, depth int) int {
+	first, last := entries[0].nibbles[depth:], entries[len(entries)-1].nibbles[depth:]
+
+	shared := 0
+	for shared < len(first) && shared < len(last) && first[shared] == last[shared] {
+		shared++
+	}
+
+	return shared
+}
+
+// hexPrefix encodes nibbles into bytes with a flag nibble marking the node type
+// and whether the number of nibbles is odd.
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	var flag byte
+	if leaf {
+		flag = 2
+	}
+
+	encoded := make([]byte, 0, len(nibbles)/2+1)
+
+	if len(nibbles)%2 == 1 {
+		encoded = append(encoded, (flag+1)<<4|nibbles[0])
+		nibbles = nibbles[1:]
+	} else {
+		encoded = append(encoded, flag<<4)
+	}
+
+	for i := 0; i < len(nibbles); i += 2 {
+		encoded = append(encoded, nibbles[i]<<4|nibbles[i+1])
+	}
+
+	return encoded
+}
+
+func keyNibbles(key []byte) []byte {
+	nibbles := make([]byte, 0, 2*len(key))
+
+	for _, b := range key {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+
+	return nibbles
+}
+
+// mustEncode encodes items that only contain byte slices, raw values and lists,
+// which cannot fail.
+func mustEncode(item []any) []byte {
+	encoded, err := rlp.Encode(item)
+	if err != nil {
+		panic(err)
+	}
+
+	return encoded
+}
@@ -0,0 +1,43 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestTrieHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string]string
+		want    string
+	}{
+		{
+			name:    "empty trie",
+			entries: map[string]string{},
+			want:    EmptyRootHash,
+		},
+		{
+			name:    "dogs",
+			entries: map[string]string{"doe": "reindeer", "dog": "puppy", "dogglesworth": "cat"},
+			want:    "0x8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3",
+		},
+		{
+			name:    "puppy",
+			entries: map[string]string{"do": "verb", "horse": "stallion", "doge": "coin", "dog": "puppy"},
+			want:    "0x5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := NewTrie()
+			for key, value := range tt.entries {
+				trie.Put([]byte(key), []byte(value))
+			}
+
+			root := trie.Hash()
+			if got := "0x" + hex.EncodeToString(root[:]); got != tt.want {
+				t.Errorf("Hash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
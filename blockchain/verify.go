@@ -0,0 +1,194 @@
+package blockchain
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spankie/tw-interview/rlp"
+)
+
+var (
+	ErrTransactionsRootMismatch = errors.New("transactions root mismatch")
+	ErrReceiptsRootMismatch     = errors.New("receipts root mismatch")
+	ErrWithdrawalsRootMismatch  = errors.New("withdrawals root mismatch")
+	ErrBlockHashMismatch        = errors.New("block hash mismatch")
+)
+
+// DeriveTransactionsRoot computes the root of the trie of the block's transactions
+// keyed by the RLP encoding of their index.
+func DeriveTransactionsRoot(transactions []Transaction) (string, error) {
+	items := make([]encoding.BinaryMarshaler, 0, len(transactions))
+	for _, transaction := range transactions {
+		items = append(items, transaction)
+	}
+
+	return deriveRoot(items)
+}
+
+// DeriveReceiptsRoot computes the root of the trie of the block's receipts
+// keyed by the RLP encoding of their index.
+func DeriveReceiptsRoot(receipts []Receipt) (string, error) {
+	items := make([]encoding.BinaryMarshaler, 0, len(receipts))
+	for _, receipt := range receipts {
+		items = append(items, receipt)
+	}
+
+	return deriveRoot(items)
+}
+
+// DeriveWithdrawalsRoot computes the root of the trie of the block's withdrawals
+// keyed by the RLP encoding of their index.
+func DeriveWithdrawalsRoot(withdrawals []Withdrawal) (string, error) {
+	items := make([]encoding.BinaryMarshaler, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		items = append(items, withdrawal)
+	}
+
+	return deriveRoot(items)
+}
+
+func deriveRoot(items []encoding.BinaryMarshaler) (string, error) {
+	trie := NewTrie()
+
+	for i, item := range items {
+		key, err := rlp.Encode(uint64(i))
+		if err != nil {
+			return "", fmt.Errorf("could not encode trie key %d: %w", i, err)
+		}
+
+		value, err := item.MarshalBinary()
+		if err != nil {
+			return "", fmt.Errorf("could not encode trie item %d: %w", i, err)
+		}
+
+		trie.Put(key, value)
+	}
+
+	root := trie.Hash()
+
+	return "0x" + hex.EncodeToString(root[:]), nil
+}
+
+// MarshalBinary returns the RLP encoding of the withdrawal:
+// [index, validator index, address, amount in gwei].
+func (w Withdrawal) MarshalBinary() ([]byte, error) {
+	fields, err := newFieldEncoder().big("index", w.Index).big("validatorIndex", w.ValidatorIndex).
+		address("address", w.Address).big("amount", w.Amount).list()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := rlp.Encode(fields)
+	if err != nil {
+		return nil, fmt.Errorf("could not rlp encode withdrawal: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// ComputeHash recomputes the block hash from the RLP encoded header fields. fields
+// introduced by later forks are only part of the header when the block has them.
+func (b Block) ComputeHash() (string, error) {
+	fields := newFieldEncoder().data("parentHash", b.ParentHash).data("sha3Uncles", b.Sha3Uncles).
+		address("miner", b.Miner).data("stateRoot", b.StateRoot).data("transactionsRoot", b.TransactionsRoot).
+		data("receiptsRoot", b.ReceiptsRoot).data("logsBloom", b.LogsBloom).big("difficulty", b.Difficulty).
+		big("number", b.Number).big("gasLimit", b.GasLimit).big("gasUsed", b.GasUsed).
+		big("timestamp", b.Timestamp).data("extraData", b.ExtraData).data("mixHash", b.MixHash).
+		data("nonce", b.Nonce)
+
+	optional := []struct {
+		name, value string
+		quantity    bool
+	}{
+		{name: "baseFeePerGas", value: b.BaseFeePerGas, quantity: true},
+		{name: "withdrawalsRoot", value: b.WithdrawalsRoot},
+		{name: "blobGasUsed", value: b.BlobGasUsed, quantity: true},
+		{name: "excessBlobGas", value: b.ExcessBlobGas, quantity: true},
+		{name: "parentBeaconBlockRoot", value: b.ParentBeaconBlockRoot},
+		{name: "requestsHash", value: b.RequestsHash},
+	}
+
+	for _, field := range optional {
+		if field.value == "" {
+			break
+		}
+
+		if field.quantity {
+			fields.big(field.name, field.value)
+		} else {
+			fields.data(field.name, field.value)
+		}
+	}
+
+	items, err := fields.list()
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := rlp.Encode(items)
+	if err != nil {
+		return "", fmt.Errorf("could not rlp encode block header: %w", err)
+	}
+
+	return "0x" + hex.EncodeToString(Keccak256(encoded)), nil
+}
+
+// VerifyBlock checks that the transactions and withdrawals of a block returned by a node
+// match the roots committed to in its header and that the header matches the block hash.
+// the transactions root of a block with transactions of an unsupported type, such as the
+// deposit transactions of L2 chains, cannot be derived and is not checked.
+func VerifyBlock(block Block) error {
+	transactionsRoot, err := DeriveTransactionsRoot(block.Transactions)
+
+	switch {
+	case errors.Is(err, ErrUnsupportedTxType):
+	case err != nil:
+		return err
+	case !strings.EqualFold(transactionsRoot, block.TransactionsRoot):
+		return fmt.Errorf("%w: got %s, computed %s", ErrTransactionsRootMismatch, block.TransactionsRoot, transactionsRoot)
+	}
+
+	if block.WithdrawalsRoot != "" {
+		withdrawalsRoot, err := DeriveWithdrawalsRoot(block.Withdrawals)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(withdrawalsRoot, block.WithdrawalsRoot) {
+			return fmt.Errorf("%w: got %s, computed %s", ErrWithdrawalsRootMismatch, block.WithdrawalsRoot, withdrawalsRoot)
+		}
+	}
+
+	hash, err := block.ComputeHash()
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(hash, block.Hash) {
+		return fmt.Errorf("%w: got %s, computed %s", ErrBlockHashMismatch, block.Hash, hash)
+	}
+
+	return nil
+}
+
+// VerifyReceipts checks that the receipts of a block match the receipts root of its header.
+// like transactions, receipts of an unsupported type leave the receipts root unchecked.
+func VerifyReceipts(block Block, receipts []Receipt) error {
+	receiptsRoot, err := DeriveReceiptsRoot(receipts)
+	if errors.Is(err, ErrUnsupportedTxType) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(receiptsRoot, block.ReceiptsRoot) {
+		return fmt.Errorf("%w: got %s, computed %s", ErrReceiptsRootMismatch, block.ReceiptsRoot, receiptsRoot)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mainnetGenesis is the header of the ethereum mainnet genesis block.
+var mainnetGenesis = Block{
+	Difficulty:       "0x400000000",
+	ExtraData:        "0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa",
+	GasLimit:         "0x1388",
+	GasUsed:          "0x0",
+	Hash:             "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+	LogsBloom:        "0x" + strings.Repeat("00", 256),
+	Miner:            "0x0000000000000000000000000000000000000000",
+	MixHash:          "0x" + strings.Repeat("00", 32),
+	Nonce:            "0x0000000000000042",
+	Number:           "0x0",
+	ParentHash:       "0x" + strings.Repeat("00", 32),
+	ReceiptsRoot:     EmptyRootHash,
+	Sha3Uncles:       "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+	StateRoot:        "0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544",
+	Timestamp:        "0x0",
+	TransactionsRoot: EmptyRootHash,
+}
+
+func TestBlockComputeHash(t *testing.T) {
+	hash, err := mainnetGenesis.ComputeHash()
+	if err != nil || hash != mainnetGenesis.Hash {
+		t.Errorf("ComputeHash() = %v, %v, want %v, nil", hash, err, mainnetGenesis.Hash)
+	}
+
+	if err := VerifyBlock(mainnetGenesis); err != nil {
+		t.Errorf("VerifyBlock() = %v, want nil", err)
+	}
+
+	if err := VerifyReceipts(mainnetGenesis, nil); err != nil {
+		t.Errorf("VerifyReceipts() = %v, want nil", err)
+	}
+
+	receipts := []Receipt{{Type: "0x7e", Status: "0x1", CumulativeGasUsed: "0x5208", LogsBloom: mainnetGenesis.LogsBloom}}
+	if err := VerifyReceipts(mainnetGenesis, receipts); err != nil {
+		t.Errorf("VerifyReceipts() of an unsupported receipt type = %v, want nil", err)
+	}
+}
+
+func TestVerifyBlock(t *testing.T) {
+	block := mainnetGenesis
+	block.Number = "0x1"
+	block.BaseFeePerGas = "0x7"
+	block.WithdrawalsRoot = EmptyRootHash
+	block.Transactions = []Transaction{{
+		Nonce: "0x9", GasPrice: "0x4a817c800", Gas: "0x5208", To: "0x3535353535353535353535353535353535353535",
+		Value: "0xde0b6b3a7640000", Input: "0x", V: "0x25",
+		R: "0x28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276",
+		S: "0x67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+	}}
+	block.Withdrawals = []Withdrawal{{
+		Index: "0x1", ValidatorIndex: "0x2", Address: "0x3535353535353535353535353535353535353535", Amount: "0x3",
+	}}
+
+	var err error
+
+	block.TransactionsRoot, err = DeriveTransactionsRoot(block.Transactions)
+	if err != nil {
+		t.Fatalf("DeriveTransactionsRoot() error = %v", err)
+	}
+
+	block.WithdrawalsRoot, err = DeriveWithdrawalsRoot(block.Withdrawals)
+	if err != nil {
+		t.Fatalf("DeriveWithdrawalsRoot() error = %v", err)
+	}
+
+	block.Hash, err = block.ComputeHash()
+	if err != nil {
+		t.Fatalf("ComputeHash() error = %v", err)
+	}
+
+	if err := VerifyBlock(block); err != nil {
+		t.Fatalf("VerifyBlock() = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Block)
+		wantErr error
+	}{
+		{
+			name: "tampered transaction",
+			mutate: func(b *Block) {
+				b.Transactions = append([]Transaction{}, b.Transactions...)
+				b.Transactions[0].Value = "0x1"
+			},
+			wantErr: ErrTransactionsRootMismatch,
+		},
+		{
+			name:    "missing withdrawal",
+			mutate:  func(b *Block) { b.Withdrawals = nil },
+			wantErr: ErrWithdrawalsRootMismatch,
+		},
+		{
+			name:    "tampered header",
+			mutate:  func(b *Block) { b.GasUsed = "0x1" },
+			wantErr: ErrBlockHashMismatch,
+		},
+		{
+			name: "unsupported transaction type",
+			mutate: func(b *Block) {
+				b.Transactions = append([]Transaction{{Type: "0x7e", Hash: "0x01"}}, b.Transactions...)
+			},
+		},
+		{
+			name: "unsupported transaction type with tampered header",
+			mutate: func(b *Block) {
+				b.Transactions = append([]Transaction{{Type: "0x7e", Hash: "0x01"}}, b.Transactions...)
+				b.GasUsed = "0x1"
+			},
+			wantErr: ErrBlockHashMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := block
+			tt.mutate(&tampered)
+
+			if err := VerifyBlock(tampered); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyBlock() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// ActivityKind is the kind of an activity entry in the history of an address.
+type ActivityKind string
+
+const (
+	// ActivityKindTransaction is a transaction sent from or to the address.
+	ActivityKindTransaction ActivityKind = "transaction"
+	// ActivityKindWithdrawal is a beacon chain withdrawal credited to the address.
+	ActivityKindWithdrawal ActivityKind = "withdrawal"
+	// ActivityKindLog is an event log emitted by the address or with the address as
+	// an indexed topic.
+	ActivityKindLog ActivityKind = "log"
+	// ActivityKindContractCreated is a contract deployed by the address.
+	ActivityKindContractCreated ActivityKind = "contract_created"
+	// ActivityKindTokenTransfer is an ERC-20 token transfer sent or received by the address.
+	ActivityKindTokenTransfer ActivityKind = "token_transfer"
+	// ActivityKindNFTTransfer is an ERC-721 or ERC-1155 token sent or received by the address.
+	ActivityKindNFTTransfer ActivityKind = "nft_transfer"
+)
+
+// Direction is whether activity was sent or received by the address.
+type Direction string
+
+const (
+	// DirectionIn is activity received by the address.
+	DirectionIn Direction = "in"
+	// DirectionOut is activity sent by the address.
+	DirectionOut Direction = "out"
+	// DirectionSelf is a transaction sent by the address to itself.
+	DirectionSelf Direction = "self"
+)
+
+// Activity is an entry in the history of a subscribed address. only the field
+// matching the kind of the activity is set.
+type Activity struct {
+	Kind        ActivityKind            `json:"kind"`
+	BlockNumber uint64                  `json:"blockNumber"`
+	BlockHash   string                  `json:"blockHash"`
+	Transaction *blockchain.Transaction `json:"transaction,omitempty"`
+	Withdrawal  *blockchain.Withdrawal  `json:"withdrawal,omitempty"`
+	Log         *blockchain.Log         `json:"log,omitempty"`
+	// TokenTransfer is the ERC-20 transfer decoded from the Transfer log of token transfer activity.
+	TokenTransfer *blockchain.TokenTransfer `json:"tokenTransfer,omitempty"`
+	// NFTTransfer is the transfer of a single NFT of NFT transfer activity.
+	NFTTransfer *blockchain.NFTTransfer `json:"nftTransfer,omitempty"`
+	// ContractAddress is the address of the contract deployed by the transaction of
+	// contract creation activity.
+	ContractAddress string `json:"contractAddress,omitempty"`
+	// Direction and Counterparty tell whether transactions, token and NFT transfers were sent
+	// or received by the address and the other address involved. withdrawals are received.
+	Direction    Direction `json:"direction,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+
+	// Confirmations and Status tell how settled the block of the activity is. they are
+	// computed from the chain head when the activity is read.
+	Confirmations uint64             `json:"confirmations"`
+	Status        ConfirmationStatus `json:"status"`
+
+	// Verified and VerificationError flag whether the hash and sender of the transaction
+	// match the ones recomputed from its fields. they are only set when the parser
+	// verifies transactions (see WithTransactionVerification). transactions of an
+	// unsupported type, such as L2 deposits, are neither verified nor flagged.
+	Verified          bool   `json:"verified,omitempty"`
+	VerificationError string `json:"verificationError,omitempty"`
+}
+
+// id identifies the activity entry within the history of an address.
+func (a Activity) id() string {
+	switch {
+	case a.NFTTransfer != nil:
+		return fmt.Sprintf("%s:%s:%s:%s:%d", a.Kind, a.BlockHash, a.NFTTransfer.TransactionHash,
+			a.NFTTransfer.LogIndex, a.NFTTransfer.BatchIndex)
+	case a.TokenTransfer != nil:
+		return fmt.Sprintf("%s:%s:%s:%s", a.Kind, a.BlockHash, a.TokenTransfer.TransactionHash,
+			a.TokenTransfer.LogIndex)
+	case a.Log != nil:
+		return fmt.Sprintf("%s:%s:%s:%s", a.Kind, a.BlockHash, a.Log.TransactionHash, a.Log.LogIndex)
+	case a.Withdrawal != nil:
+		return fmt.Sprintf("%s:%s:%s", a.Kind, a.BlockHash, a.Withdrawal.Index)
+	case a.Transaction != nil:
+		return fmt.Sprintf("%s:%s:%s", a.Kind, a.BlockHash, a.Transaction.Hash)
+	default:
+		return fmt.Sprintf("%s:%s", a.Kind, a.BlockHash)
+	}
+}
+
+// GetActivity returns the history of all activity kinds for an address in the order
+// it happened on chain, with the confirmations of each entry. the history of unsubscribed
+// addresses is returned as long as it is kept.
+func (p *Parser) GetActivity(address string, opts ...QueryOption) []Activity {
+	address, ok := p.resolveAddress(address)
+	if !ok {
+		return nil
+	}
+
+	activity, ok := p.datastore.Get(address)
+	if !ok {
+		return nil
+	}
+
+	return p.withConfirmations(activity, newActivityQuery(opts))
+}
+
+// list of inbound or outbound transactions for an address.
+func (p *Parser) GetTransactions(address string, opts ...QueryOption) []blockchain.Transaction {
+	activity := p.GetActivity(address, opts...)
+	if activity == nil {
+		return nil
+	}
+
+	transactions := make([]blockchain.Transaction, 0, len(activity))
+
+	for _, entry := range activity {
+		if entry.Kind == ActivityKindTransaction {
+			transactions = append(transactions, *entry.Transaction)
+		}
+	}
+
+	return transactions
+}
+
+// GetWithdrawals returns the beacon chain withdrawals credited to an address.
+func (p *Parser) GetWithdrawals(address string, opts ...QueryOption) []blockchain.Withdrawal {
+	activity := p.GetActivity(address, opts...)
+	if activity == nil {
+		return nil
+	}
+
+	withdrawals := make([]blockchain.Withdrawal, 0)
+
+	for _, entry := range activity {
+		if entry.Kind == ActivityKindWithdrawal {
+			withdrawals = append(withdrawals, *entry.Withdrawal)
+		}
+	}
+
+	return withdrawals
+}
+
+// blockActivity returns the activity entries of a block keyed by the canonical storage
+// key of the addresses involved. addresses that are not watched are left out.
+func (p *Parser) blockActivity(block *blockchain.Block, watched *watchSet) map[string][]Activity {
+	blockNumber, err := block.NumberUint64()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("invalid block %s: %v", block.Hash, err))
+		return nil
+	}
+
+	activity := make(map[string][]Activity)
+
+	for i := range block.Transactions {
+		transaction := &block.Transactions[i]
+
+		var entry *Activity
+
+		// a transaction is stored once per address, also when it is sent to itself.
+		stored := make(map[string]bool, 2)
+
+		for _, address := range []string{transaction.From, transaction.To} {
+			key, ok := watched.match(address)
+			if !ok || stored[key] {
+				continue
+			}
+
+			if entry == nil {
+				entry = p.transactionActivity(*transaction, blockNumber, block.Hash)
+			}
+
+			stored[key] = true
+			activity[key] = append(activity[key], withDirection(*entry, key))
+		}
+	}
+
+	for i := range block.Withdrawals {
+		if key, ok := watched.match(block.Withdrawals[i].Address); ok {
+			// the withdrawal is copied so the stored activity does not keep the block alive.
+			withdrawal := block.Withdrawals[i]
+
+			activity[key] = append(activity[key], Activity{
+				Kind:        ActivityKindWithdrawal,
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash,
+				Withdrawal:  &withdrawal,
+				Direction:   DirectionIn,
+			})
+		}
+	}
+
+	return activity
+}
+
+// withDirection sets the direction and counterparty of the transaction activity from the
+// point of view of the address of the key.
+func withDirection(entry Activity, key string) Activity {
+	from, _ := blockchain.NormalizeAddress(entry.Transaction.From)
+	to, _ := blockchain.NormalizeAddress(entry.Transaction.To)
+
+	entry.Direction, entry.Counterparty = direction(from, to, key)
+
+	return entry
+}
+
+// direction returns the direction and counterparty of a transfer between the normalized
+// from and to addresses from the point of view of the address of the key.
+func direction(from, to, key string) (Direction, string) {
+	switch {
+	case from == key && to == key:
+		return DirectionSelf, key
+	case from == key:
+		return DirectionOut, to
+	default:
+		return DirectionIn, from
+	}
+}
+
+// transactionActivity creates the activity entry of a transaction, verifying it
+// first when transaction verification is enabled. the entry holds a copy of the
+// transaction so it does not keep the block alive.
+func (p *Parser) transactionActivity(transaction blockchain.Transaction, blockNumber uint64,
+	blockHash string,
+) *Activity {
+	entry := &Activity{
+		Kind:        ActivityKindTransaction,
+		BlockNumber: blockNumber,
+		BlockHash:   blockHash,
+		Transaction: &transaction,
+	}
+
+	if !p.verifyTransactions {
+		return entry
+	}
+
+	err := blockchain.VerifyTransaction(transaction)
+	if errors.Is(err, blockchain.ErrUnsupportedTxType) {
+		p.logger.Debug(fmt.Sprintf("transaction %s not verified: %v", transaction.Hash, err))
+		return entry
+	}
+
+	if err != nil {
+		p.logger.Warn(fmt.Sprintf("transaction %s failed verification: %v", transaction.Hash, err))
+		entry.VerificationError = err.Error()
+
+		return entry
+	}
+
+	entry.Verified = true
+
+	return entry
+}
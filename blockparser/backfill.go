@@ -0,0 +1,343 @@
+package blockparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotSubscribed     = errors.New("address is not subscribed")
+	ErrBackfillRunning   = errors.New("a backfill is already running for the address")
+	ErrInvalidBackfill   = errors.New("invalid backfill start")
+	ErrBackfillNotFound  = errors.New("no backfill for the address")
+	errBackfillCancelled = errors.New("backfill cancelled")
+)
+
+// BackfillStatus is the state of a backfill job.
+type BackfillStatus string
+
+const (
+	BackfillRunning   BackfillStatus = "running"
+	BackfillCompleted BackfillStatus = "completed"
+	BackfillCancelled BackfillStatus = "cancelled"
+	BackfillFailed    BackfillStatus = "failed"
+)
+
+// BackfillProgress is a snapshot of the progress of a backfill job. the job scans the
+// blocks FromBlock..ToBlock, ToBlock being the last block scanned when the job started.
+type BackfillProgress struct {
+	Address       string         `json:"address"`
+	Status        BackfillStatus `json:"status"`
+	FromBlock     int64          `json:"fromBlock"`
+	ToBlock       int64          `json:"toBlock"`
+	BlocksScanned int64          `json:"blocksScanned"`
+	Matches       int            `json:"matches"`
+	// FailedBlocks are the blocks that could not be fetched, their activity is missing.
+	FailedBlocks []int64    `json:"failedBlocks,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+// BackfillStart resolves the first block of a backfill.
+type BackfillStart func(p *Parser) (int64, error)
+
+// BackfillFromBlock starts a backfill at the given block.
+func BackfillFromBlock(blockNumber int64) BackfillStart {
+	return func(_ *Parser) (int64, error) {
+		if blockNumber < 0 {
+			return 0, fmt.Errorf("%w: block %d", ErrInvalidBackfill, blockNumber)
+		}
+
+		return blockNumber, nil
+	}
+}
+
+// BackfillBlocks backfills the given number of blocks before the last scanned block.
+func BackfillBlocks(blocks int64) BackfillStart {
+	return func(p *Parser) (int64, error) {
+		if blocks <= 0 {
+			return 0, fmt.Errorf("%w: %d blocks", ErrInvalidBackfill, blocks)
+		}
+
+		return max(p.lastScannedBlock.Load()-blocks+1, 0), nil
+	}
+}
+
+// BackfillSince starts a backfill at the first block mined at or after the given time.
+// the block is found with a binary search over the block timestamps.
+func BackfillSince(since time.Time) BackfillStart {
+	return func(p *Parser) (int64, error) {
+		low, high := int64(0), p.lastScannedBlock.Load()+1
+
+		for low < high {
+			middle := low + (high-low)/2
+
+			block, err := p.getBlockByNumber(middle)
+			if err != nil {
+				return 0, fmt.Errorf("could not find the first block since %s: %w", since, err)
+			}
+
+			minedAt, err := block.Time()
+			if err != nil {
+				return 0, fmt.Errorf("could not find the first block since %s: %w", since, err)
+			}
+
+			if minedAt.Before(since) {
+				low = middle + 1
+			} else {
+				high = middle
+			}
+		}
+
+		return low, nil
+	}
+}
+
+type backfillJob struct {
+	mu       sync.Mutex
+	progress BackfillProgress
+	cancel   context.CancelFunc
+}
+
+func (j *backfillJob) snapshot() BackfillProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	progress := j.progress
+	progress.FailedBlocks = append([]int64(nil), j.progress.FailedBlocks...)
+
+	return progress
+}
+
+func (j *backfillJob) update(update func(*BackfillProgress)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	update(&j.progress)
+}
+
+// backfills are the backfill jobs of the parser by address, the last job of each address
+// is kept once finished so its outcome can be queried.
+type backfills struct {
+	mu   sync.Mutex
+	jobs map[string]*backfillJob
+}
+
+// Backfill scans past blocks for the activity of a subscribed address in a background
+// job, separate from following the chain head. the job scans from the block resolved by
+// start up to the last block scanned when it starts, and merges the activity found with
+// the activity already stored without duplicates.
+func (p *Parser) Backfill(address string, start BackfillStart) (BackfillProgress, error) {
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return BackfillProgress{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	if _, ok := p.subscriptions.get(key); !ok {
+		return BackfillProgress{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	from, err := start(p)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &backfillJob{
+		progress: BackfillProgress{
+			Address:   key,
+			Status:    BackfillRunning,
+			FromBlock: from,
+			ToBlock:   p.lastScannedBlock.Load(),
+			StartedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+
+	p.backfills.mu.Lock()
+	if running, ok := p.backfills.jobs[key]; ok && running.snapshot().Status == BackfillRunning {
+		p.backfills.mu.Unlock()
+		cancel()
+
+		return BackfillProgress{}, fmt.Errorf("%w: %s", ErrBackfillRunning, key)
+	}
+
+	p.backfills.jobs[key] = job
+	p.backfills.mu.Unlock()
+
+	p.logger.Info(fmt.Sprintf("backfilling address %s from block %d to %d",
+		key, job.progress.FromBlock, job.progress.ToBlock))
+
+	go p.runBackfill(ctx, job)
+
+	return job.snapshot(), nil
+}
+
+// BackfillProgress returns the progress of the last backfill of an address.
+func (p *Parser) BackfillProgress(address string) (BackfillProgress, error) {
+	job, err := p.backfillJob(address)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+
+	return job.snapshot(), nil
+}
+
+// CancelBackfill stops the running backfill of an address. the activity found so far is kept.
+func (p *Parser) CancelBackfill(address string) (BackfillProgress, error) {
+	job, err := p.backfillJob(address)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+
+	job.cancel()
+
+	return job.snapshot(), nil
+}
+
+func (p *Parser) backfillJob(address string) (*backfillJob, error) {
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBackfillNotFound, address)
+	}
+
+	p.backfills.mu.Lock()
+	defer p.backfills.mu.Unlock()
+
+	job, ok := p.backfills.jobs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBackfillNotFound, address)
+	}
+
+	return job, nil
+}
+
+func (p *Parser) runBackfill(ctx context.Context, job *backfillJob) {
+	defer job.cancel()
+
+	progress := job.snapshot()
+	err := p.backfillBlocks(ctx, job, progress.Address, progress.FromBlock, progress.ToBlock)
+
+	job.update(func(progress *BackfillProgress) {
+		finishedAt := time.Now().UTC()
+		progress.FinishedAt = &finishedAt
+
+		switch {
+		case errors.Is(err, errBackfillCancelled):
+			progress.Status = BackfillCancelled
+		case err != nil:
+			progress.Status = BackfillFailed
+			progress.Error = err.Error()
+		default:
+			progress.Status = BackfillCompleted
+		}
+	})
+
+	progress = job.snapshot()
+	p.logger.Info(fmt.Sprintf("backfill of address %s %s after %d blocks with %d matches",
+		progress.Address, progress.Status, progress.BlocksScanned, progress.Matches))
+}
+
+func (p *Parser) backfillBlocks(ctx context.Context, job *backfillJob, key string, from, to int64) error {
+	// only the backfilled address is matched, the activity of other addresses and the
+	// contracts deployed in past blocks are left alone.
+	watched := addressWatchSet(key)
+
+	for result := range p.fetchBlocks(ctx, from, to) {
+		var found map[string][]Activity
+
+		blockErr := result.err
+		if blockErr == nil {
+			found, blockErr = p.collectActivity(result.block, watched, false)
+		}
+
+		if blockErr != nil {
+			p.logger.Error(fmt.Sprintf("backfill of address %s could not scan block %d: %v",
+				key, result.blockNumber, blockErr))
+		}
+
+		added, err := p.mergeStoredActivity(key, found[key])
+		if err != nil {
+			return err
+		}
+
+		job.update(func(progress *BackfillProgress) {
+			progress.BlocksScanned++
+			progress.Matches += len(added)
+
+			if blockErr != nil {
+				progress.FailedBlocks = append(progress.FailedBlocks, result.blockNumber)
+			}
+		})
+	}
+
+	if ctx.Err() != nil {
+		return errBackfillCancelled
+	}
+
+	return nil
+}
+
+// mergeStoredActivity adds the activity entries that are not stored yet to the activity
+// of an address, keeping the activity in block order. entries are only compared with the
+// stored entries of their block, so blocks scanned in order are appended without looking at
+// the rest of the history. it returns the entries added.
+func (p *Parser) mergeStoredActivity(key string, entries []Activity) ([]Activity, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	added := make([]Activity, 0, len(entries))
+
+	err := p.datastore.Update(key, func(activity []Activity) []Activity {
+		var (
+			blockNumber uint64
+			stored      map[string]bool
+		)
+
+		for _, entry := range entries {
+			// the stored entries of the block of the entry are activity[from:to].
+			from := sort.Search(len(activity), func(i int) bool {
+				return activity[i].BlockNumber >= entry.BlockNumber
+			})
+			to := from + sort.Search(len(activity)-from, func(i int) bool {
+				return activity[from+i].BlockNumber > entry.BlockNumber
+			})
+
+			if stored == nil || entry.BlockNumber != blockNumber {
+				blockNumber = entry.BlockNumber
+				stored = make(map[string]bool, to-from)
+
+				for _, storedEntry := range activity[from:to] {
+					stored[storedEntry.id()] = true
+				}
+			}
+
+			if stored[entry.id()] {
+				continue
+			}
+
+			stored[entry.id()] = true
+			activity = slices.Insert(activity, to, entry)
+			added = append(added, entry)
+		}
+
+		return activity
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not store activity of address %s: %w", key, err)
+	}
+
+	if len(added) > 0 {
+		p.subscriptions.touch(key)
+		p.holdings.apply(key, added, 1)
+	}
+
+	return added, nil
+}
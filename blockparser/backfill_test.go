@@ -0,0 +1,264 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// waitForBackfill polls the progress of the backfill of the address until it finishes.
+func waitForBackfill(t *testing.T, parser *Parser, address string) BackfillProgress {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		progress, err := parser.BackfillProgress(address)
+		if err != nil {
+			t.Fatalf("BackfillProgress() error = %v, want nil", err)
+		}
+
+		if progress.Status != BackfillRunning {
+			return progress
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("backfill of %s did not finish", address)
+
+	return BackfillProgress{}
+}
+
+func newBackfillParser(t *testing.T, blockchainQuerier BlockchainQuerier, address string) *Parser {
+	t.Helper()
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	if _, err := parser.Backfill(address, BackfillFromBlock(0)); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("Backfill() error = %v, want %v", err, ErrNotSubscribed)
+	}
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	return parser
+}
+
+func TestParserBackfill(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	first, second := sampleBlock.Transactions[0], sampleBlock.Transactions[1]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 20, map[int64][]blockchain.Transaction{5: {first}, 15: {second}})
+
+	for number, block := range chain {
+		block.Timestamp = blockchain.Quantity(1000 + 12*number).String()
+	}
+
+	tests := []struct {
+		name      string
+		start     BackfillStart
+		wantFrom  int64
+		wantTxs   []string
+		wantMatch int
+	}{
+		{name: "from block", start: BackfillFromBlock(1), wantFrom: 1, wantTxs: []string{first.Hash, second.Hash}, wantMatch: 2},
+		{name: "last blocks", start: BackfillBlocks(10), wantFrom: 11, wantTxs: []string{second.Hash}, wantMatch: 1},
+		{name: "since", start: BackfillSince(time.Unix(1000+12*15-1, 0)), wantFrom: 15, wantTxs: []string{second.Hash}, wantMatch: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newBackfillParser(t, &MockChainQuerier{LatestBlock: 20, Blocks: chain}, address)
+
+			progress, err := parser.Backfill(address, tt.start)
+			if err != nil {
+				t.Fatalf("Backfill() error = %v, want nil", err)
+			}
+
+			if progress.FromBlock != tt.wantFrom || progress.ToBlock != 20 {
+				t.Errorf("Backfill() = blocks %d..%d, want %d..20", progress.FromBlock, progress.ToBlock, tt.wantFrom)
+			}
+
+			progress = waitForBackfill(t, parser, address)
+			if progress.Status != BackfillCompleted || progress.Matches != tt.wantMatch ||
+				progress.BlocksScanned != 20-tt.wantFrom+1 {
+				t.Errorf("BackfillProgress() = %+v, want completed with %d matches", progress, tt.wantMatch)
+			}
+
+			// backfilling again does not duplicate activity.
+			if _, err := parser.Backfill(address, tt.start); err != nil {
+				t.Fatalf("Backfill() error = %v, want nil", err)
+			}
+
+			if progress := waitForBackfill(t, parser, address); progress.Matches != 0 {
+				t.Errorf("BackfillProgress() matches = %d, want 0", progress.Matches)
+			}
+
+			transactions := parser.GetTransactions(address)
+			if len(transactions) != len(tt.wantTxs) {
+				t.Fatalf("GetTransactions() = %v, want %v", transactions, tt.wantTxs)
+			}
+
+			for i, hash := range tt.wantTxs {
+				if transactions[i].Hash != hash {
+					t.Errorf("GetTransactions()[%d] = %s, want %s", i, transactions[i].Hash, hash)
+				}
+			}
+		})
+	}
+}
+
+func TestParserBackfillMatchesOnlyTheAddress(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	deployer := "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"
+	deployment := blockchain.Transaction{
+		Hash:  "0x5a1f0b9e3c6d0d1b33c4e88e0bd2b66d81a7b1b2c1e8f3a7e76c1d0bb2f7a6c1",
+		From:  deployer,
+		Nonce: "0x1",
+		Input: "0x6080604052",
+	}
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 10, map[int64][]blockchain.Transaction{5: {sampleBlock.Transactions[0], deployment}})
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockChainQuerier{LatestBlock: 10, Blocks: chain}), WithContractAutoSubscribe())
+
+	for _, subscriber := range []string{address, deployer} {
+		if subscribed := parser.Subscribe(subscriber); !subscribed {
+			t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+		}
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	if _, err := parser.Backfill(address, BackfillFromBlock(1)); err != nil {
+		t.Fatalf("Backfill() error = %v, want nil", err)
+	}
+
+	if progress := waitForBackfill(t, parser, address); progress.Matches != 1 {
+		t.Errorf("BackfillProgress() matches = %d, want 1", progress.Matches)
+	}
+
+	// the contract deployed by the other subscribed address is not subscribed.
+	if subscriptions := parser.Subscriptions(); len(subscriptions) != 2 {
+		t.Errorf("Subscriptions() = %+v, want the 2 subscribed addresses", subscriptions)
+	}
+
+	if activity := parser.GetActivity(deployer); len(activity) != 0 {
+		t.Errorf("GetActivity(%s) = %+v, want no activity", deployer, activity)
+	}
+}
+
+func TestParserCancelBackfill(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	blockchainQuerier := &slowChainQuerier{
+		MockChainQuerier: MockChainQuerier{LatestBlock: 1000, Blocks: newSlowChain(1000, sampleBlock.Transactions[0])},
+		latency:          func(int64) time.Duration { return 10 * time.Millisecond },
+	}
+
+	parser := newBackfillParser(t, blockchainQuerier, address)
+
+	if _, err := parser.Backfill(address, BackfillFromBlock(1)); err != nil {
+		t.Fatalf("Backfill() error = %v, want nil", err)
+	}
+
+	if _, err := parser.Backfill(address, BackfillFromBlock(1)); !errors.Is(err, ErrBackfillRunning) {
+		t.Errorf("Backfill() error = %v, want %v", err, ErrBackfillRunning)
+	}
+
+	if _, err := parser.CancelBackfill(address); err != nil {
+		t.Fatalf("CancelBackfill() error = %v, want nil", err)
+	}
+
+	progress := waitForBackfill(t, parser, address)
+	if progress.Status != BackfillCancelled || progress.BlocksScanned >= 1000 || progress.FinishedAt == nil {
+		t.Errorf("BackfillProgress() = %+v, want a cancelled backfill", progress)
+	}
+}
+
+func TestParserMergeStoredActivity(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	entry := func(blockNumber uint64, logIndex string) Activity {
+		return Activity{
+			Kind:        ActivityKindLog,
+			BlockNumber: blockNumber,
+			BlockHash:   fmt.Sprintf("0x%d", blockNumber),
+			Log:         &blockchain.Log{LogIndex: logIndex},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		entries   []Activity
+		wantAdded int
+		wantOrder []uint64
+	}{
+		{
+			name:      "in order",
+			entries:   []Activity{entry(104, "0x0")},
+			wantAdded: 1,
+			wantOrder: []uint64{101, 103, 103, 104},
+		},
+		{
+			name:      "out of order",
+			entries:   []Activity{entry(102, "0x0")},
+			wantAdded: 1,
+			wantOrder: []uint64{101, 102, 103, 103},
+		},
+		{name: "stored", entries: []Activity{entry(103, "0x1")}, wantOrder: []uint64{101, 103, 103}},
+		{
+			name:      "new entry of a stored block",
+			entries:   []Activity{entry(101, "0x0"), entry(101, "0x1")},
+			wantAdded: 1,
+			wantOrder: []uint64{101, 101, 103, 103},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()))
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			key, _ := parser.resolveAddress(address)
+			if _, err := parser.mergeStoredActivity(key,
+				[]Activity{entry(101, "0x0"), entry(103, "0x0"), entry(103, "0x1")}); err != nil {
+				t.Fatalf("mergeStoredActivity() error = %v, want nil", err)
+			}
+
+			added, err := parser.mergeStoredActivity(key, tt.entries)
+			if err != nil {
+				t.Fatalf("mergeStoredActivity() error = %v, want nil", err)
+			}
+
+			if len(added) != tt.wantAdded {
+				t.Errorf("mergeStoredActivity() added %v, want %d entries", added, tt.wantAdded)
+			}
+
+			activity, _ := parser.datastore.Get(key)
+
+			order := make([]uint64, 0, len(activity))
+			for _, stored := range activity {
+				order = append(order, stored.BlockNumber)
+			}
+
+			if !slices.Equal(order, tt.wantOrder) {
+				t.Errorf("stored blocks = %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package blockparser
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,10 +19,14 @@ type Logger interface {
 }
 
 // DataStore is an interface for storing data and querying data.
+// the activity of each subscribed address is stored under the address.
 type DataStore interface {
-	Add(key string, value []blockchain.Transaction) error
-	Get(key string) ([]blockchain.Transaction, bool)
+	Add(key string, value []Activity) error
+	Get(key string) ([]Activity, bool)
 	GetKeys() []string
+	// Update replaces the value of an existing key with the result of update, atomically.
+	Update(key string, update func([]Activity) []Activity) error
+	Delete(key string) error
 }
 
 // BlockchainQuerier is an interface for querying the blockchain.
@@ -28,24 +35,132 @@ type BlockchainQuerier interface {
 	GetBlock(blockNumber string) (*blockchain.Block, error)
 }
 
+// NameResolver is an interface for resolving human readable names (e.g. ENS names) to addresses.
+type NameResolver interface {
+	ResolveName(name string) (string, error)
+}
+
 type BlockParser interface {
 	// last parsed block
 	GetCurrentBlock() int
 
 	// add address to observer
-	Subscribe(address string) bool
+	Subscribe(address string, opts ...SubscriptionOption) bool
+
+	// remove address from observer
+	Unsubscribe(address string) bool
+
+	// pause, resume or change the expiry of a subscription
+	UpdateSubscription(address string, opts ...SubscriptionOption) (Subscription, error)
+
+	// subscription of an address
+	GetSubscription(address string) (Subscription, error)
+
+	// all subscriptions
+	Subscriptions() []Subscription
 
 	// list of inbound or outbound transactions for an address
-	GetTransactions(address string) []blockchain.Transaction
+	GetTransactions(address string, opts ...QueryOption) []blockchain.Transaction
+
+	// list of beacon chain withdrawals credited to an address
+	GetWithdrawals(address string, opts ...QueryOption) []blockchain.Withdrawal
+
+	// list of ERC-20 token transfers sent or received by an address
+	GetTokenTransfers(address string, opts ...QueryOption) []blockchain.TokenTransfer
+
+	// list of NFT transfers sent or received by an address
+	GetNFTTransfers(address string, opts ...QueryOption) []blockchain.NFTTransfer
+
+	// NFTs held by an address
+	GetNFTHoldings(address string) []NFTHolding
+
+	// history of all activity kinds for an address
+	GetActivity(address string, opts ...QueryOption) []Activity
+
+	// logs bloom pre-filtering counters
+	BloomStats() BloomStats
+
+	// blocks that could not be fetched yet
+	Gaps() []Gap
+
+	// scan past blocks for the activity of a subscribed address
+	Backfill(address string, start BackfillStart) (BackfillProgress, error)
+
+	// progress of the last backfill of an address
+	BackfillProgress(address string) (BackfillProgress, error)
+
+	// stop the running backfill of an address
+	CancelBackfill(address string) (BackfillProgress, error)
+
+	// scan a range of blocks for the activity of subscribed addresses
+	ScanRange(ctx context.Context, from, to int64) (ScanSummary, error)
+
+	// receive the events published while scanning
+	Observe(opts ...ObserverOption) *Observer
+
+	// call a handler with the events published while scanning
+	OnEvent(handler func(Event), opts ...ObserverOption) func()
 }
 
 type Parser struct {
 	// NOTE: using atomic int64 for thread safety
-	lastScannedBlock  atomic.Int64
+	lastScannedBlock atomic.Int64
+	// headBlock and finalizedBlock are the latest and latest finalized blocks of the
+	// chain, used to compute the confirmations of stored activity.
+	headBlock         atomic.Int64
+	finalizedBlock    atomic.Int64
+	confirmationDepth uint64
+	followFinalized   bool
+	// gaps are the scanned blocks that could not be fetched, retried until filled.
+	gaps              *gapQueue
+	failedBlockPolicy FailedBlockPolicy
+	// fetchWorkers is the number of blocks fetched concurrently.
+	fetchWorkers int
+	// checkpointStore persists the last scanned block, resumed from on start up to
+	// maxCatchUp blocks behind the chain head.
+	checkpointStore CheckpointStore
+	maxCatchUp      int64
+	catchUpPolicy   CatchUpPolicy
+	// subscriptions are the addresses observed, their history is kept or purged on
+	// unsubscribe depending on historyPolicy.
+	subscriptions *subscriptions
+	historyPolicy HistoryPolicy
+	observers     *observers
+	// startBlock and endBlock bound the blocks scanned when following the chain, nil when unbounded.
+	startBlock        *int64
+	endBlock          *int64
+	backfills         backfills
 	datastore         DataStore
 	blockchainQuerier BlockchainQuerier
 	scanningInterval  time.Duration
 	logger            Logger
+	nameResolver      NameResolver
+	// verifyTransactions recomputes the hash and sender of matched transactions before storing them.
+	verifyTransactions bool
+	// trackLogs fetches the receipts of blocks whose logs bloom may contain logs
+	// involving subscribed addresses, and stores these logs.
+	trackLogs     bool
+	watchedTopics map[[32]byte]bool
+	bloomStats    bloomCounters
+	// trackTokenTransfers decodes the ERC-20 transfers sent or received by subscribed addresses.
+	trackTokenTransfers bool
+	// trackNFTs decodes the NFT transfers of subscribed addresses, projected into their holdings.
+	trackNFTs bool
+	holdings  *holdings
+	// autoSubscribeContracts subscribes contracts deployed by subscribed addresses.
+	autoSubscribeContracts bool
+	// recentBlocks are the hashes of the last scanned blocks, used to detect reorganizations.
+	recentBlocks *blockWindow
+	// chainID is the id of the chain followed, 0 when not set.
+	chainID uint64
+	// checkpointMu orders the checkpoints saved by scanning and by subscription changes,
+	// which are only saved once the scanning position was initialized from the checkpoint.
+	checkpointMu sync.Mutex
+	initialized  atomic.Bool
+	// checkpointPending is set when the scanning position or the subscriptions changed since
+	// the checkpoint was saved, uncheckpointedBlocks counts the blocks committed since then.
+	checkpointPending    atomic.Bool
+	uncheckpointedBlocks atomic.Int64
 }
 
 // NewBlockParser creates a new parser and starts the block transactions scanning.
@@ -64,10 +179,34 @@ func NewBlockParser(cfgOpts ...ConfigOptionResolver) *Parser {
 
 func newBlockParserWithConfig(cfg Config) *Parser {
 	parser := &Parser{
-		datastore:         cfg.datastore,
-		scanningInterval:  cfg.scanningInterval,
-		blockchainQuerier: cfg.blockchainQuerier,
-		logger:            cfg.logger,
+		datastore:              cfg.datastore,
+		scanningInterval:       cfg.scanningInterval,
+		blockchainQuerier:      cfg.blockchainQuerier,
+		logger:                 cfg.logger,
+		nameResolver:           cfg.nameResolver,
+		verifyTransactions:     cfg.verifyTransactions,
+		trackLogs:              cfg.trackLogs,
+		watchedTopics:          parseTopics(cfg.watchedTopics, cfg.logger),
+		trackTokenTransfers:    cfg.trackTokenTransfers,
+		trackNFTs:              cfg.trackNFTs,
+		holdings:               newHoldings(),
+		autoSubscribeContracts: cfg.autoSubscribeContracts,
+		recentBlocks:           newBlockWindow(cfg.reorgWindow),
+		confirmationDepth:      *cfg.confirmationDepth,
+		followFinalized:        cfg.followFinalized,
+		gaps:                   newGapQueue(cfg.gapRetryBackoff, cfg.maxGapRetryBackoff),
+		failedBlockPolicy:      cfg.failedBlockPolicy,
+		fetchWorkers:           cfg.fetchWorkers,
+		backfills:              backfills{jobs: make(map[string]*backfillJob)},
+		checkpointStore:        cfg.checkpointStore,
+		maxCatchUp:             cfg.maxCatchUp,
+		catchUpPolicy:          cfg.catchUpPolicy,
+		subscriptions:          newSubscriptions(cfg.watchPrefilter),
+		historyPolicy:          cfg.historyPolicy,
+		observers:              &observers{},
+		startBlock:             cfg.startBlock,
+		endBlock:               cfg.endBlock,
+		chainID:                cfg.chainID,
 	}
 
 	return parser
@@ -78,28 +217,57 @@ func (p *Parser) GetCurrentBlock() int {
 	return int(p.lastScannedBlock.Load())
 }
 
-// add address to observer.
-func (p *Parser) Subscribe(address string) bool {
-	if !blockchain.IsValidEthereumAddress(address) {
+// add address to observer. the address can also be a name (e.g. vitalik.eth) when
+// the parser has a name resolver. it returns false when the address is already subscribed
+// or the options are invalid.
+func (p *Parser) Subscribe(address string, opts ...SubscriptionOption) bool {
+	if err := validateSubscriptionOptions(opts); err != nil {
+		p.logger.Debug(fmt.Sprintf("could not subscribe address %s: %v", address, err))
+		return false
+	}
+
+	address, ok := p.resolveAddress(address)
+	if !ok {
 		return false
 	}
 
-	// check if the address is already subscribed (already in the db).
-	if _, ok := p.datastore.Get(address); ok {
+	if _, ok := p.subscriptions.add(address, opts); !ok {
 		return false
 	}
 
-	// if the address is not in the db, add it so it can be observed when
-	// scanning the blockchain.
-	if err := p.datastore.Add(address, []blockchain.Transaction{}); err != nil {
+	// add the address to the db so its activity can be stored, the history kept from a
+	// previous subscription is extended.
+	if err := p.datastore.Add(address, []Activity{}); err != nil {
+		p.subscriptions.remove(address)
 		return false
 	}
 
+	p.markCheckpoint()
+
 	return true
 }
 
-// list of inbound or outbound transactions for an address.
-func (p *Parser) GetTransactions(address string) []blockchain.Transaction {
-	transactions, _ := p.datastore.Get(address)
-	return transactions
+// resolveAddress returns the canonical storage key of the address when it is a valid
+// ethereum address, otherwise it tries to resolve it as a name using the parser's name resolver.
+func (p *Parser) resolveAddress(address string) (string, bool) {
+	if key, err := blockchain.NormalizeAddress(address); err == nil {
+		return key, true
+	}
+
+	if p.nameResolver == nil {
+		return "", false
+	}
+
+	resolved, err := p.nameResolver.ResolveName(address)
+	if err != nil {
+		p.logger.Debug(fmt.Sprintf("could not resolve name %s: %v", address, err))
+		return "", false
+	}
+
+	key, err := blockchain.NormalizeAddress(resolved)
+	if err != nil {
+		return "", false
+	}
+
+	return key, true
 }
@@ -2,7 +2,9 @@ package blockparser
 
 import (
 	"context"
-	"strconv"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +26,7 @@ func (m *MockBlockchainQuerier) GetLatestBlock() (string, error) {
 	m.LatestBlock++
 	latestBlock := m.LatestBlock
 
-	return strconv.Itoa(latestBlock), nil
+	return blockchain.Quantity(latestBlock).String(), nil
 }
 
 func (m *MockBlockchainQuerier) GetBlock(_ string) (*blockchain.Block, error) {
@@ -114,7 +116,7 @@ var sampleBlock = blockchain.Block{
 }
 
 func TestParserSubscription(t *testing.T) {
-	datastore := newMemoryDataStore[blockchain.Transaction]()
+	datastore := newMemoryDataStore[Activity]()
 	block := sampleBlock
 	blockchainQuerier := &MockBlockchainQuerier{
 		LatestBlock: 0x7b,
@@ -147,7 +149,7 @@ func TestParserSubscription(t *testing.T) {
 
 func TestParserGetTransactions(t *testing.T) {
 	t.Run("test parser get transactions", func(t *testing.T) {
-		datastore := newMemoryDataStore[blockchain.Transaction]()
+		datastore := newMemoryDataStore[Activity]()
 		block := sampleBlock
 
 		blockchainQuerier := &MockBlockchainQuerier{
@@ -192,9 +194,88 @@ func TestParserGetTransactions(t *testing.T) {
 	})
 }
 
+func TestParserTransactionDirection(t *testing.T) {
+	sender, recipient := sampleBlock.Transactions[0].From, sampleBlock.Transactions[0].To
+	other := sampleBlock.Transactions[2].From
+
+	toRecipient := sampleBlock.Transactions[0]
+	toSelf := sampleBlock.Transactions[1]
+	toSelf.To = sender
+	fromOther := sampleBlock.Transactions[2]
+	fromOther.To = sender
+
+	block := sampleBlock
+	block.Transactions = []blockchain.Transaction{toRecipient, toSelf, fromOther}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockBlockchainQuerier{}))
+
+	for _, address := range []string{sender, recipient} {
+		if subscribed := parser.Subscribe(address); !subscribed {
+			t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+		}
+	}
+
+	parser.saveSubscribedAddressActivity(&block)
+
+	tests := []struct {
+		name      string
+		address   string
+		opts      []QueryOption
+		wantTxs   []string
+		wantDirs  []Direction
+		wantPeers []string
+	}{
+		{
+			name: "all", address: sender,
+			wantTxs:   []string{toRecipient.Hash, toSelf.Hash, fromOther.Hash},
+			wantDirs:  []Direction{DirectionOut, DirectionSelf, DirectionIn},
+			wantPeers: []string{recipient, sender, other},
+		},
+		{
+			name: "in", address: sender, opts: []QueryOption{InDirection(DirectionIn)},
+			wantTxs:   []string{toSelf.Hash, fromOther.Hash},
+			wantDirs:  []Direction{DirectionSelf, DirectionIn},
+			wantPeers: []string{sender, other},
+		},
+		{
+			name: "out", address: sender, opts: []QueryOption{InDirection(DirectionOut)},
+			wantTxs:   []string{toRecipient.Hash, toSelf.Hash},
+			wantDirs:  []Direction{DirectionOut, DirectionSelf},
+			wantPeers: []string{recipient, sender},
+		},
+		{
+			name: "recipient", address: recipient,
+			wantTxs:   []string{toRecipient.Hash},
+			wantDirs:  []Direction{DirectionIn},
+			wantPeers: []string{sender},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if transactions := parser.GetTransactions(tt.address, tt.opts...); len(transactions) != len(tt.wantTxs) {
+				t.Errorf("GetTransactions() = %d transactions, want %d", len(transactions), len(tt.wantTxs))
+			}
+
+			activity := parser.GetActivity(tt.address, tt.opts...)
+			if len(activity) != len(tt.wantTxs) {
+				t.Fatalf("GetActivity() = %d entries, want %d", len(activity), len(tt.wantTxs))
+			}
+
+			for i, entry := range activity {
+				if entry.Transaction.Hash != tt.wantTxs[i] || entry.Direction != tt.wantDirs[i] ||
+					entry.Counterparty != tt.wantPeers[i] {
+					t.Errorf("GetActivity()[%d] = %s %s %s, want %s %s %s", i, entry.Transaction.Hash,
+						entry.Direction, entry.Counterparty, tt.wantTxs[i], tt.wantDirs[i], tt.wantPeers[i])
+				}
+			}
+		})
+	}
+}
+
 func TestStartBlockScanning(t *testing.T) {
 	t.Run("test start block scanning", func(t *testing.T) {
-		datastore := newMemoryDataStore[blockchain.Transaction]()
+		datastore := newMemoryDataStore[Activity]()
 		block := sampleBlock
 
 		blockchainQuerier := &MockBlockchainQuerier{
@@ -219,11 +300,332 @@ func TestStartBlockScanning(t *testing.T) {
 
 		time.Sleep(1100 * time.Millisecond)
 
-		// the transactions should be 4.
-		expectedNumTransactions := 4
+		// the querier returns the same block for every block number, its 2 transactions
+		// are stored once.
+		expectedNumTransactions := 2
 
 		if transactions := parser.GetTransactions(address); len(transactions) != expectedNumTransactions {
 			t.Errorf("should get %d transactions but got %d", expectedNumTransactions, len(transactions))
 		}
 	})
 }
+
+type mockNameResolver map[string]string
+
+func (m mockNameResolver) ResolveName(name string) (string, error) {
+	address, ok := m[name]
+	if !ok {
+		return "", errors.New("name not found")
+	}
+
+	return address, nil
+}
+
+func TestParserSubscribeName(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	block := sampleBlock
+
+	parser := NewBlockParser(
+		WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}),
+		WithNameResolver(mockNameResolver{"sender.eth": address}),
+	)
+
+	if ok := parser.Subscribe("unknown.eth"); ok {
+		t.Errorf("should not subscribe a name that cannot be resolved. got %v, want false", ok)
+	}
+
+	if ok := parser.Subscribe("sender.eth"); !ok {
+		t.Errorf("should subscribe a resolvable name. got %v, want true", ok)
+	}
+
+	if ok := parser.Subscribe(address); ok {
+		t.Errorf("should not subscribe the address a subscribed name resolves to. got %v, want false", ok)
+	}
+
+	if transactions := parser.GetTransactions("sender.eth"); transactions == nil {
+		t.Errorf("GetTransactions() by name = nil, want the subscribed address transactions")
+	}
+}
+
+func TestParserMatchesAddressCaseInsensitively(t *testing.T) {
+	block := sampleBlock
+	blockchainQuerier := &MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	// the sample block transactions use lowercase addresses.
+	address := blockchain.ChecksumAddress(sampleBlock.Transactions[0].From)
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe checksummed address %s; got %v, want true", address, subscribed)
+	}
+
+	if subscribed := parser.Subscribe(sampleBlock.Transactions[0].From); subscribed {
+		t.Errorf("should not subscribe the lowercase form of a subscribed address; got %v, want false", subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if transactions := parser.GetTransactions(address); len(transactions) != 2 {
+		t.Errorf("should get 2 transactions but got %d", len(transactions))
+	}
+}
+
+func TestParserGetWithdrawals(t *testing.T) {
+	feeRecipient := "0x388c818ca8b9251b393131c08a736a67ccb19297"
+
+	block := sampleBlock
+	block.Withdrawals = []blockchain.Withdrawal{
+		{Index: "0x1", ValidatorIndex: "0x10", Address: feeRecipient, Amount: "0x1bc16d674"},
+		{Index: "0x2", ValidatorIndex: "0x11", Address: "0xb9d7934878b5fb9610b3fe8a5e441e8fad7e293f", Amount: "0x1"},
+	}
+
+	blockchainQuerier := &MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	if subscribed := parser.Subscribe(feeRecipient); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", feeRecipient, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	withdrawals := parser.GetWithdrawals(feeRecipient)
+	if len(withdrawals) != 1 || withdrawals[0].Index != "0x1" {
+		t.Fatalf("GetWithdrawals() = %v, want the withdrawal with index 0x1", withdrawals)
+	}
+
+	if transactions := parser.GetTransactions(feeRecipient); len(transactions) != 0 {
+		t.Errorf("withdrawals should not be returned as transactions; got %d transactions", len(transactions))
+	}
+
+	activity := parser.GetActivity(feeRecipient)
+	if len(activity) != 1 || activity[0].Kind != ActivityKindWithdrawal || activity[0].BlockNumber != 0x1b4 {
+		t.Errorf("GetActivity() = %v, want one withdrawal activity in block 0x1b4", activity)
+	}
+}
+
+func TestParserStoredActivityDoesNotReferenceTheBlock(t *testing.T) {
+	subscriber := sampleBlock.Transactions[0].From
+	log := blockchain.Log{
+		Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+		Topics:  []string{transferTopic, "0x000000000000000000000000" + subscriber[2:]},
+	}
+
+	bloom, err := blockchain.CreateBloom([]blockchain.Log{log})
+	if err != nil {
+		t.Fatalf("CreateBloom() error = %v", err)
+	}
+
+	block := sampleBlock
+	block.Transactions = []blockchain.Transaction{sampleBlock.Transactions[0]}
+	block.Withdrawals = []blockchain.Withdrawal{{Index: "0x1", Address: subscriber, Amount: "0x1"}}
+	block.LogsBloom = bloom.String()
+
+	receipts := []blockchain.Receipt{{Logs: []blockchain.Log{log}}}
+	blockchainQuerier := &MockReceiptsQuerier{Receipts: receipts}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithLogTracking())
+
+	if subscribed := parser.Subscribe(subscriber); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+	}
+
+	parser.saveSubscribedAddressActivity(&block)
+
+	activity := parser.GetActivity(subscriber)
+	if len(activity) != 3 {
+		t.Fatalf("GetActivity() = %d entries, want a transaction, a withdrawal and a log", len(activity))
+	}
+
+	for _, entry := range activity {
+		if entry.Transaction == &block.Transactions[0] || entry.Withdrawal == &block.Withdrawals[0] ||
+			entry.Log == &receipts[0].Logs[0] {
+			t.Errorf("GetActivity() %s entry references the block or its receipts", entry.Kind)
+		}
+	}
+}
+
+func TestParserFlagsUnverifiedTransactions(t *testing.T) {
+	deposit := sampleBlock.Transactions[0]
+	deposit.Type = "0x7e"
+	deposit.Hash = "0x" + strings.Repeat("7e", 32)
+
+	block := sampleBlock
+	block.Transactions = append(slices.Clone(sampleBlock.Transactions), deposit)
+	blockchainQuerier := &MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithTransactionVerification())
+
+	address := sampleBlock.Transactions[0].From
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	// the first sample transaction is a real mainnet transaction, the second one
+	// is a copy with made up values and signature and the third one has a type that
+	// cannot be verified.
+	activity := parser.GetActivity(address)
+	if len(activity) != 3 {
+		t.Fatalf("should get 3 activity entries but got %d", len(activity))
+	}
+
+	if !activity[0].Verified || activity[0].VerificationError != "" {
+		t.Errorf("transaction %s should be verified; got error %q",
+			activity[0].Transaction.Hash, activity[0].VerificationError)
+	}
+
+	if activity[1].Verified || activity[1].VerificationError == "" {
+		t.Errorf("transaction %s should be flagged as failing verification", activity[1].Transaction.Hash)
+	}
+
+	if activity[2].Verified || activity[2].VerificationError != "" {
+		t.Errorf("transaction %s should be left unverified; got error %q",
+			activity[2].Transaction.Hash, activity[2].VerificationError)
+	}
+}
+
+func TestVerifyingQuerierRejectsInvalidBlocks(t *testing.T) {
+	block := sampleBlock
+	blockchainQuerier := &MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithBlockVerification())
+
+	// the sample block claims the empty transactions root while it has transactions.
+	if _, err := parser.blockchainQuerier.GetBlock("0x1b4"); !errors.Is(err, ErrBlockVerificationFailed) {
+		t.Fatalf("GetBlock() error = %v, want %v", err, ErrBlockVerificationFailed)
+	}
+
+	address := sampleBlock.Transactions[0].From
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if transactions := parser.GetTransactions(address); len(transactions) != 0 {
+		t.Errorf("should not store transactions of unverified blocks; got %d", len(transactions))
+	}
+}
+
+// blockCountingQuerier counts the blocks fetched from the querier it wraps.
+type blockCountingQuerier struct {
+	*MockReceiptsQuerier
+	blockCalls int
+}
+
+func (q *blockCountingQuerier) GetBlock(blockNumber string) (*blockchain.Block, error) {
+	q.blockCalls++
+
+	return q.MockReceiptsQuerier.GetBlock(blockNumber)
+}
+
+func TestVerifyingQuerierDoesNotRefetchBlocksForReceipts(t *testing.T) {
+	subscriber := sampleBlock.Transactions[0].From
+
+	// the bloom of the block may contain a log of the subscriber, so its receipts are fetched.
+	bloom, err := blockchain.CreateBloom([]blockchain.Log{{Address: subscriber}})
+	if err != nil {
+		t.Fatalf("CreateBloom() error = %v", err)
+	}
+
+	block := sampleBlock
+	block.Transactions = nil
+	block.LogsBloom = bloom.String()
+	block.TransactionsRoot, _ = blockchain.DeriveTransactionsRoot(nil)
+	block.ReceiptsRoot, _ = blockchain.DeriveReceiptsRoot(nil)
+
+	if block.Hash, err = block.ComputeHash(); err != nil {
+		t.Fatalf("ComputeHash() error = %v", err)
+	}
+
+	blockchainQuerier := &blockCountingQuerier{MockReceiptsQuerier: &MockReceiptsQuerier{
+		MockBlockchainQuerier: MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block},
+	}}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithBlockVerification(), WithLogTracking())
+
+	if subscribed := parser.Subscribe(subscriber); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if blockchainQuerier.ReceiptsCalls == 0 || blockchainQuerier.blockCalls != blockchainQuerier.ReceiptsCalls {
+		t.Errorf("fetched %d blocks and %d receipts, want each block fetched once with its receipts",
+			blockchainQuerier.blockCalls, blockchainQuerier.ReceiptsCalls)
+	}
+
+	if gaps := parser.Gaps(); len(gaps) != 0 {
+		t.Errorf("Gaps() = %+v, want the verified blocks scanned", gaps)
+	}
+}
+
+func TestVerifyingQuerierWithoutReceipts(t *testing.T) {
+	subscriber := sampleBlock.Transactions[0].From
+
+	bloom, err := blockchain.CreateBloom([]blockchain.Log{{Address: subscriber}})
+	if err != nil {
+		t.Fatalf("CreateBloom() error = %v", err)
+	}
+
+	block := sampleBlock
+	block.Transactions = nil
+	block.LogsBloom = bloom.String()
+	block.TransactionsRoot, _ = blockchain.DeriveTransactionsRoot(nil)
+
+	if block.Hash, err = block.ComputeHash(); err != nil {
+		t.Fatalf("ComputeHash() error = %v", err)
+	}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}),
+		WithBlockVerification(), WithLogTracking())
+
+	if _, ok := parser.receiptsQuerier(); ok {
+		t.Errorf("receiptsQuerier() ok = true, want false when the verified querier cannot fetch receipts")
+	}
+
+	if subscribed := parser.Subscribe(subscriber); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if gaps := parser.Gaps(); len(gaps) != 0 {
+		t.Errorf("Gaps() = %+v, want the blocks scanned without receipts", gaps)
+	}
+}
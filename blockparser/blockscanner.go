@@ -2,12 +2,15 @@ package blockparser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/spankie/tw-interview/blockchain"
 )
 
+var ErrBlockNotFound = errors.New("block not found")
+
 // StartBlockScanning runs a task every minute to find inbound/outbound
 // transactions for subscribed address.
 func (p *Parser) StartBlockScanning(ctx context.Context) { //nolint: contextcheck
@@ -30,25 +33,44 @@ func (p *Parser) StartBlockScanning(ctx context.Context) { //nolint: contextchec
 			select {
 			case <-ctx.Done():
 				p.logger.Info("block scanning stopped")
+				p.saveCheckpoint()
+
 				return
 			default:
 				time.Sleep(p.scanningInterval)
 				p.querySubscribedAddressTransactions(ctx)
+
+				if p.reachedEndBlock() {
+					p.logger.Info(fmt.Sprintf("block scanning reached end block %d", *p.endBlock))
+					return
+				}
 			}
 		}
 	}()
 }
 
-// initScannedBlockNumber initializes the last scanned block with the current block number.
-// this is done when the parser is started to ensure that the parser starts scanning
-// from the latest block.
+// initScannedBlockNumber initializes the last scanned block with the block before the start
+// block when there is one, with the checkpointed block when the parser has a checkpoint
+// store, so scanning resumes where it left off, and with the current block number otherwise.
 func (p *Parser) initScannedBlockNumber() error {
 	blockNumber, err := p.getLatestBlockNumber()
 	if err != nil {
 		return err
 	}
 
-	p.lastScannedBlock.Store(blockNumber)
+	resume := blockNumber
+	if p.startBlock != nil {
+		resume = *p.startBlock - 1
+	} else if resume, err = p.resumeBlock(blockNumber); err != nil {
+		return err
+	}
+
+	p.lastScannedBlock.Store(resume)
+	p.headBlock.Store(blockNumber)
+	p.initialized.Store(true)
+	p.markCheckpoint()
+	p.saveCheckpoint()
+	p.updateFinalizedBlock()
 
 	return nil
 }
@@ -60,56 +82,205 @@ func (p *Parser) getLatestBlockNumber() (int64, error) {
 		return 0, fmt.Errorf("error fetching latest block: %w", err)
 	}
 
-	return blockchain.ConvertHexToInt(blockNumberStr), nil
+	blockNumber, err := blockchain.ParseQuantity(blockNumberStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid latest block number: %w", err)
+	}
+
+	return int64(blockNumber), nil //nolint: gosec // block numbers fit in int64.
 }
 
 // querySubscribedAddressTransactions scans the blockchain for transactions
 // it starts from the last scanned blocked to the current block
 // for each block, it filters out transactions done by subscribed addresses
-// and saves them in the datastore.
+// and saves them in the datastore. the checkpoint is saved once the scan is over.
 func (p *Parser) querySubscribedAddressTransactions(ctx context.Context) {
+	defer p.saveCheckpoint()
+
 	latestBlockNumber, err := p.getLatestBlockNumber()
 	if err != nil {
 		p.logger.Error(fmt.Sprintf("error getting latest block: %v", err))
+		p.publish(Event{Kind: EventError, Error: err.Error()})
+
 		return
 	}
 
-	// start scanning from the last scanned block to the latest block on the blockchain.
-	for blockNumber := p.lastScannedBlock.Load() + 1; blockNumber <= latestBlockNumber; blockNumber++ {
+	p.headBlock.Store(latestBlockNumber)
+	p.updateFinalizedBlock()
+	p.expireSubscriptions()
+	p.retryGaps()
+
+	// start scanning from the last scanned block to the latest block on the blockchain,
+	// or the end block when it comes first. scanning restarts after the common ancestor
+	// of reorganized blocks.
+	to := latestBlockNumber
+	if p.endBlock != nil {
+		to = min(to, *p.endBlock)
+	}
+
+	for from := p.lastScannedBlock.Load() + 1; from <= to; {
+		next, ok := p.scanBlocks(ctx, from, to)
+		if !ok {
+			return
+		}
+
+		from = next
+	}
+}
+
+// scanBlocks fetches the blocks from..to concurrently and commits them in order. it returns
+// the next block to scan, which is before to when a reorganization was detected, and false
+// when scanning stopped.
+func (p *Parser) scanBlocks(ctx context.Context, from, to int64) (int64, bool) {
+	if p.subscriptions.watched().len() == 0 {
+		// nothing can be rolled back without subscriptions.
+		p.recentBlocks.reset()
+		p.lastScannedBlock.Store(to)
+		p.markCheckpoint()
+
+		return to + 1, true
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for result := range p.fetchBlocks(fetchCtx, from, to) {
 		select {
 		case <-ctx.Done():
-			p.logger.Info(fmt.Sprintf("scanning block %d stopped", blockNumber))
-			return
+			p.logger.Info(fmt.Sprintf("scanning block %d stopped", result.blockNumber))
+			return 0, false
 		default:
-			if len(p.datastore.GetKeys()) > 0 {
-				p.saveSubscribedAddressTransactions(p.getTransactionsInBlock(blockNumber))
-			}
+		}
+
+		scanned, err := p.commitBlock(result)
+		if err != nil {
+			p.logger.Error(fmt.Sprintf("scanning halted at block %d: %v", result.blockNumber, err))
+			return 0, false
+		}
 
-			p.lastScannedBlock.Store(blockNumber)
+		if scanned != result.blockNumber {
+			return scanned + 1, true
 		}
 	}
+
+	if ctx.Err() != nil {
+		p.logger.Info("block scanning stopped")
+		return 0, false
+	}
+
+	return to + 1, true
 }
 
-// saveSubscribedAddressTransactions finds and stores all transaction done by subscribed address.
-func (p *Parser) saveSubscribedAddressTransactions(blockTransactions []blockchain.Transaction) {
-	for _, transaction := range blockTransactions {
-		if _, ok := p.datastore.Get(transaction.From); ok {
-			err := p.datastore.Add(transaction.From, []blockchain.Transaction{transaction})
-			if err != nil {
-				p.logger.Error(fmt.Sprintf(
-					"error storing transaction %s for address %s %v",
-					transaction.String(), transaction.From, err))
-			}
+// commitBlock stores the activity of subscribed addresses in the fetched block and marks
+// it as scanned. when the block reveals a chain reorganization, the activity from abandoned
+// blocks is removed instead and the number of the last block still on the canonical
+// chain is returned, so scanning resumes after it. a block that could not be fetched is
+// recorded as a gap to retry, or returns an error when the parser halts on failed blocks. an
+// error is also returned when the common ancestor of a reorganization cannot be fetched, the
+// block is committed again on the next scan. the checkpoint is saved every checkpointBlocks
+// committed blocks, so it does not fall far behind while catching up.
+func (p *Parser) commitBlock(result fetchResult) (int64, error) {
+	scanned, err := p.commitFetchedBlock(result)
+	if err == nil {
+		p.markCheckpoint()
+
+		if p.uncheckpointedBlocks.Add(1) >= checkpointBlocks {
+			p.saveCheckpoint()
 		}
+	}
 
-		if _, ok := p.datastore.Get(transaction.To); ok {
-			err := p.datastore.Add(transaction.To, []blockchain.Transaction{transaction})
-			if err != nil {
-				p.logger.Error(fmt.Sprintf(
-					"error storing transaction %s for address %s %v",
-					transaction.String(), transaction.To, err))
-			}
+	return scanned, err
+}
+
+func (p *Parser) commitFetchedBlock(result fetchResult) (int64, error) {
+	blockNumber, block := result.blockNumber, result.block
+
+	if result.err != nil {
+		return p.failBlock(blockNumber, result.err)
+	}
+
+	ancestor, reorged, err := p.detectReorg(blockNumber, block)
+	if err != nil {
+		p.publish(Event{Kind: EventError, BlockNumber: blockNumber, Error: err.Error()})
+		return 0, err
+	}
+
+	if reorged {
+		p.lastScannedBlock.Store(ancestor)
+
+		return ancestor, nil
+	}
+
+	if err := p.saveSubscribedAddressActivity(block); err != nil {
+		return p.failBlock(blockNumber, err)
+	}
+
+	p.recentBlocks.add(blockNumber, block.Hash)
+	p.lastScannedBlock.Store(blockNumber)
+	p.publish(Event{Kind: EventBlockScanned, BlockNumber: blockNumber, BlockHash: block.Hash})
+
+	return blockNumber, nil
+}
+
+// failBlock records a block that could not be fetched, or whose receipts could not be, as
+// a gap to retry and marks it as scanned. the error is returned instead when the parser
+// halts on failed blocks.
+func (p *Parser) failBlock(blockNumber int64, err error) (int64, error) {
+	p.publish(Event{Kind: EventError, BlockNumber: blockNumber, Error: err.Error()})
+
+	if p.failedBlockPolicy == HaltOnFailedBlock {
+		return 0, err
+	}
+
+	p.gaps.fail(blockNumber, err)
+	p.logger.Error(fmt.Sprintf("block %d recorded as a gap to retry: %v", blockNumber, err))
+	p.lastScannedBlock.Store(blockNumber)
+
+	return blockNumber, nil
+}
+
+// saveSubscribedAddressActivity finds and stores all transactions done by, withdrawals
+// credited to, contracts deployed by and logs involving subscribed addresses in the block,
+// and publishes them to observers. nothing is stored when the activity could not be collected.
+func (p *Parser) saveSubscribedAddressActivity(block *blockchain.Block) error {
+	found, err := p.collectActivity(block, p.subscriptions.watched(), p.autoSubscribeContracts)
+	if err != nil {
+		return err
+	}
+
+	p.storeActivity(block, found)
+
+	return nil
+}
+
+// storeActivity merges the activity found in the block into the stored activity of the
+// addresses in block order and publishes the entries that were not stored yet, so a block
+// processed again is not stored twice. the activity of an address unsubscribed while the
+// block was scanned is not stored when its history was purged.
+func (p *Parser) storeActivity(block *blockchain.Block, found map[string][]Activity) {
+	for address, activity := range found {
+		added, err := p.mergeStoredActivity(address, activity)
+		if err != nil {
+			p.logger.Error(fmt.Sprintf("error storing activity of block %s: %v", block.Number, err))
+			continue
 		}
+
+		p.publishActivity(block, address, added)
+	}
+}
+
+// publishActivity publishes the activity of the address matched in the block to observers,
+// with its confirmations and status.
+func (p *Parser) publishActivity(block *blockchain.Block, address string, activity []Activity) {
+	activity = p.withConfirmations(activity, activityQuery{})
+	for i := range activity {
+		p.publish(Event{
+			Kind:        EventActivityMatched,
+			BlockNumber: int64(activity[i].BlockNumber), //nolint: gosec // block numbers fit in int64.
+			BlockHash:   block.Hash,
+			Address:     address,
+			Activity:    &activity[i],
+		})
 	}
 }
 
@@ -124,12 +295,59 @@ func (p *Parser) getBlock(blockNumber string) (*blockchain.Block, error) {
 	return block, nil
 }
 
-// getTransactionsInBlock requires the address and block number.
-func (p *Parser) getTransactionsInBlock(blockNumber int64) []blockchain.Transaction {
-	block, err := p.getBlock(fmt.Sprintf("0x%x", blockNumber))
+// getBlockByNumber fetches the block identified by the block number.
+func (p *Parser) getBlockByNumber(blockNumber int64) (*blockchain.Block, error) {
+	block, err := p.getBlock(blockchain.Quantity(blockNumber).String())
 	if err != nil {
-		return []blockchain.Transaction{}
+		return nil, fmt.Errorf("error fetching block %d: %w", blockNumber, err)
+	}
+
+	if block == nil || block.Hash == "" {
+		return nil, fmt.Errorf("%w: block %d", ErrBlockNotFound, blockNumber)
+	}
+
+	return block, nil
+}
+
+// collectActivity returns the activity of the watched addresses in the block keyed by the
+// canonical storage key of the addresses. contracts deployed by watched addresses are
+// subscribed when autoSubscribe is true. an error is returned when the receipts or logs of
+// the block could not be fetched, as the activity found without them is incomplete.
+func (p *Parser) collectActivity(block *blockchain.Block, watched *watchSet,
+	autoSubscribe bool,
+) (map[string][]Activity, error) {
+	receipts := p.newBlockReceipts(block)
+
+	activity := mergeActivity(p.blockActivity(block, watched),
+		p.contractActivity(block, receipts, watched, autoSubscribe))
+
+	if autoSubscribe {
+		// the logs of contracts subscribed by deployments in the block are matched.
+		watched = p.subscriptions.watched()
+	}
+
+	activity = mergeActivity(activity, p.logActivity(block, receipts, watched))
+
+	transferLogs := p.newBlockTransferLogs(block, receipts)
+	activity = mergeActivity(activity, p.tokenTransferActivity(block, transferLogs, watched))
+	activity = mergeActivity(activity, p.nftActivity(block, transferLogs, watched))
+
+	if err := errors.Join(receipts.err, transferLogs.err); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+// mergeActivity appends the activity of each address in src to its activity in dst.
+func mergeActivity(dst, src map[string][]Activity) map[string][]Activity {
+	if dst == nil {
+		dst = make(map[string][]Activity, len(src))
+	}
+
+	for address, activity := range src {
+		dst[address] = append(dst[address], activity...)
 	}
 
-	return block.Transactions
+	return dst
 }
@@ -0,0 +1,179 @@
+package blockparser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// slowChainQuerier serves the blocks of a chain with a per block latency and tracks
+// the number of concurrent requests.
+type slowChainQuerier struct {
+	MockChainQuerier
+	latency     func(blockNumber int64) time.Duration
+	inFlight    atomic.Int64
+	maxInFlight atomic.Int64
+}
+
+func (m *slowChainQuerier) GetBlock(blockNumber string) (*blockchain.Block, error) {
+	inFlight := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+
+	for {
+		maxInFlight := m.maxInFlight.Load()
+		if inFlight <= maxInFlight || m.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+
+	number, err := blockchain.ParseQuantity(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(m.latency(int64(number)))
+
+	return m.MockChainQuerier.GetBlock(blockNumber)
+}
+
+// orderRecordingStore records the block numbers of the activity in the order it is stored.
+type orderRecordingStore struct {
+	DataStore
+
+	mu     sync.Mutex
+	blocks []uint64
+}
+
+func (s *orderRecordingStore) Update(key string, update func([]Activity) []Activity) error {
+	return s.DataStore.Update(key, func(stored []Activity) []Activity {
+		updated := update(stored)
+
+		s.mu.Lock()
+		for _, entry := range updated[len(stored):] {
+			s.blocks = append(s.blocks, entry.BlockNumber)
+		}
+		s.mu.Unlock()
+
+		return updated
+	})
+}
+
+func newSlowChain(blocks int64, transaction blockchain.Transaction) map[int64]*blockchain.Block {
+	transactions := make(map[int64][]blockchain.Transaction, blocks)
+	for number := int64(1); number <= blocks; number++ {
+		transactions[number] = []blockchain.Transaction{transaction}
+	}
+
+	chain := make(map[int64]*blockchain.Block, blocks+1)
+	extendChain(chain, "a", 0, blocks, transactions)
+
+	return chain
+}
+
+func TestParserCommitsConcurrentlyFetchedBlocksInOrder(t *testing.T) {
+	const blocks, workers = 20, 4
+
+	address := sampleBlock.Transactions[0].From
+	blockchainQuerier := &slowChainQuerier{
+		MockChainQuerier: MockChainQuerier{Blocks: newSlowChain(blocks, sampleBlock.Transactions[0])},
+		// earlier blocks are slower so later blocks are fetched first.
+		latency: func(blockNumber int64) time.Duration {
+			return time.Duration(blocks-blockNumber) * time.Millisecond
+		},
+	}
+	store := &orderRecordingStore{DataStore: newMemoryDataStore[Activity]()}
+
+	parser := NewBlockParser(WithDataStore(store), WithBlockchainQuerier(blockchainQuerier),
+		WithFetchWorkers(workers))
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	blockchainQuerier.LatestBlock = blocks
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if len(store.blocks) != blocks {
+		t.Fatalf("stored activity of %d blocks, want %d", len(store.blocks), blocks)
+	}
+
+	for i, blockNumber := range store.blocks {
+		if blockNumber != uint64(i+1) {
+			t.Fatalf("activity stored in block order %v, want ascending blocks", store.blocks)
+		}
+	}
+
+	if maxInFlight := blockchainQuerier.maxInFlight.Load(); maxInFlight > workers || maxInFlight < 2 {
+		t.Errorf("max concurrent requests = %d, want between 2 and %d", maxInFlight, workers)
+	}
+
+	if block := parser.GetCurrentBlock(); block != blocks {
+		t.Errorf("GetCurrentBlock() = %d, want %d", block, blocks)
+	}
+}
+
+func TestParserStoresReprocessedBlockOnce(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockBlockchainQuerier{}))
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	observer := parser.Observe()
+	defer observer.Close()
+
+	parser.saveSubscribedAddressActivity(&sampleBlock)
+	stored := len(parser.GetActivity(address))
+	published := len(receive(observer))
+
+	if stored == 0 || published != stored {
+		t.Fatalf("stored %d entries and published %d events, want the activity of the block", stored, published)
+	}
+
+	parser.saveSubscribedAddressActivity(&sampleBlock)
+
+	if activity := parser.GetActivity(address); len(activity) != stored {
+		t.Errorf("GetActivity() = %d entries after processing the block again, want %d", len(activity), stored)
+	}
+
+	if events := receive(observer); len(events) != 0 {
+		t.Errorf("published %d events after processing the block again, want none", len(events))
+	}
+}
+
+func BenchmarkScanBlocks(b *testing.B) {
+	const blocks = 50
+
+	address := sampleBlock.Transactions[0].From
+	chain := newSlowChain(blocks, sampleBlock.Transactions[0])
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for range b.N {
+				blockchainQuerier := &slowChainQuerier{
+					MockChainQuerier: MockChainQuerier{Blocks: chain},
+					latency:          func(int64) time.Duration { return time.Millisecond },
+				}
+
+				parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+					WithBlockchainQuerier(blockchainQuerier), WithFetchWorkers(workers))
+				parser.Subscribe(address)
+
+				blockchainQuerier.LatestBlock = blocks
+				parser.querySubscribedAddressTransactions(context.Background())
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+var (
+	ErrUnknownChain    = errors.New("unknown chain")
+	ErrChainExists     = errors.New("a parser is already registered for the chain")
+	ErrMissingChainID  = errors.New("parser has no chain id")
+	ErrChainIDMismatch = errors.New("chain id of the node does not match the parser")
+)
+
+// ChainIDQuerier is an interface for querying the id of the chain of the node. blockchain
+// queriers can optionally implement it to let the parser check it follows the right chain.
+type ChainIDQuerier interface {
+	GetChainID() (string, error)
+}
+
+// ChainID returns the id of the chain the parser follows, 0 when it was not set (see WithChainID).
+func (p *Parser) ChainID() uint64 {
+	return p.chainID
+}
+
+// VerifyChainID checks that the node the parser queries serves the chain of the parser. it
+// returns an error wrapping ErrChainIDMismatch when it does not, and errors.ErrUnsupported
+// when the blockchain querier cannot query the chain id.
+func (p *Parser) VerifyChainID() error {
+	if p.chainID == 0 {
+		return ErrMissingChainID
+	}
+
+	querier, ok := p.blockchainQuerier.(ChainIDQuerier)
+	if !ok {
+		return fmt.Errorf("%w: %T cannot fetch the chain id", errors.ErrUnsupported, p.blockchainQuerier)
+	}
+
+	chainIDStr, err := querier.GetChainID()
+	if err != nil {
+		return fmt.Errorf("error fetching chain id: %w", err)
+	}
+
+	chainID, err := blockchain.ParseQuantity(chainIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid chain id: %w", err)
+	}
+
+	if uint64(chainID) != p.chainID {
+		return fmt.Errorf("%w: node serves chain %d, parser follows chain %d", ErrChainIDMismatch,
+			uint64(chainID), p.chainID)
+	}
+
+	return nil
+}
+
+// Chains are the parsers of the EVM chains followed by one service, by chain id. each parser
+// has its own querier, scanning interval, checkpoint and subscriptions.
+type Chains struct {
+	mu      sync.RWMutex
+	parsers map[uint64]*Parser
+}
+
+// NewChains creates an empty set of chains.
+func NewChains() *Chains {
+	return &Chains{parsers: make(map[uint64]*Parser)}
+}
+
+// Add registers the parser under its chain id once its node is verified to serve that chain.
+// parsers whose blockchain querier cannot query the chain id are registered unverified.
+func (c *Chains) Add(parser *Parser) error {
+	err := parser.VerifyChainID()
+
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		parser.logger.Warn(fmt.Sprintf("chain %d is not verified: %v", parser.chainID, err))
+	case err != nil:
+		return fmt.Errorf("could not add chain %d: %w", parser.chainID, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.parsers[parser.chainID]; ok {
+		return fmt.Errorf("%w: %d", ErrChainExists, parser.chainID)
+	}
+
+	c.parsers[parser.chainID] = parser
+
+	return nil
+}
+
+// Parser returns the parser of the chain.
+func (c *Chains) Parser(chainID uint64) (*Parser, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	parser, ok := c.parsers[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownChain, chainID)
+	}
+
+	return parser, nil
+}
+
+// IDs returns the ids of the chains in ascending order.
+func (c *Chains) IDs() []uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]uint64, 0, len(c.parsers))
+	for id := range c.parsers {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
@@ -0,0 +1,132 @@
+package blockparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// MockChainIDQuerier is a blockchain querier of a node serving the chain with the given id.
+type MockChainIDQuerier struct {
+	MockBlockchainQuerier
+	ChainID    string
+	ChainIDErr error
+}
+
+func (m *MockChainIDQuerier) GetChainID() (string, error) {
+	return m.ChainID, m.ChainIDErr
+}
+
+func TestChainsAdd(t *testing.T) {
+	errNode := errors.New("node unavailable")
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		querier BlockchainQuerier
+		wantErr error
+	}{
+		{
+			name:    "node serves the chain",
+			chainID: 137,
+			querier: &MockChainIDQuerier{ChainID: "0x89"},
+		},
+		{
+			name:    "node serves another chain",
+			chainID: 137,
+			querier: &MockChainIDQuerier{ChainID: "0x1"},
+			wantErr: ErrChainIDMismatch,
+		},
+		{
+			name:    "node fails",
+			chainID: 137,
+			querier: &MockChainIDQuerier{ChainIDErr: errNode},
+			wantErr: errNode,
+		},
+		{
+			name:    "verified querier",
+			chainID: 137,
+			querier: NewVerifyingQuerier(&MockChainIDQuerier{ChainID: "0x89"}),
+		},
+		{
+			name:    "querier cannot fetch the chain id",
+			chainID: 137,
+			querier: &MockBlockchainQuerier{},
+		},
+		{
+			name:    "parser without chain id",
+			querier: &MockChainIDQuerier{ChainID: "0x89"},
+			wantErr: ErrMissingChainID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chains := NewChains()
+			parser := NewBlockParser(WithChainID(tt.chainID), WithBlockchainQuerier(tt.querier))
+
+			err := chains.Add(parser)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := chains.Parser(tt.chainID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, ErrUnknownChain) {
+					t.Errorf("Parser() error = %v, want %v", err, ErrUnknownChain)
+				}
+
+				return
+			}
+
+			if err != nil || got != parser {
+				t.Errorf("Parser() = %p, %v, want the added parser", got, err)
+			}
+		})
+	}
+}
+
+func TestChainsAreKeyedByChainID(t *testing.T) {
+	chains := NewChains()
+
+	for _, chainID := range []uint64{8453, 1, 137} {
+		querier := &MockChainIDQuerier{ChainID: blockchain.Quantity(chainID).String()}
+		if err := chains.Add(NewBlockParser(WithChainID(chainID), WithBlockchainQuerier(querier))); err != nil {
+			t.Fatalf("Add(%d) error = %v, want nil", chainID, err)
+		}
+	}
+
+	duplicate := NewBlockParser(WithChainID(1), WithBlockchainQuerier(&MockChainIDQuerier{ChainID: "0x1"}))
+	if err := chains.Add(duplicate); !errors.Is(err, ErrChainExists) {
+		t.Errorf("Add() error = %v, want %v", err, ErrChainExists)
+	}
+
+	if got, want := chains.IDs(), []uint64{1, 137, 8453}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IDs() = %v, want %v", got, want)
+	}
+
+	if _, err := chains.Parser(10); !errors.Is(err, ErrUnknownChain) {
+		t.Errorf("Parser(10) error = %v, want %v", err, ErrUnknownChain)
+	}
+
+	// subscriptions and published events belong to the chain of the parser.
+	ethereum, _ := chains.Parser(1)
+	polygon, _ := chains.Parser(137)
+
+	address := sampleBlock.Transactions[0].From
+	ethereum.Subscribe(address)
+
+	if subscriptions := polygon.Subscriptions(); len(subscriptions) != 0 {
+		t.Errorf("Subscriptions() of chain 137 = %v, want none", subscriptions)
+	}
+
+	observer := polygon.Observe()
+	defer observer.Close()
+
+	polygon.publish(Event{Kind: EventBlockScanned, BlockNumber: 1})
+
+	if events := receive(observer); len(events) != 1 || events[0].ChainID != 137 {
+		t.Errorf("events = %+v, want one event of chain 137", events)
+	}
+}
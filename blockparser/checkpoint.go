@@ -0,0 +1,233 @@
+package blockparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// checkpointBlocks is the number of committed blocks after which the checkpoint is saved
+// while a scan is still catching up.
+const checkpointBlocks = 100
+
+// errRestoredGap is the error of the gaps restored from a checkpoint, they are retried right away.
+var errRestoredGap = errors.New("not fetched before the parser restarted")
+
+// Checkpoint is the scanning position of the parser: the last block whose activity was
+// fully processed, the blocks before it that could not be fetched yet and the subscriptions
+// whose activity was being recorded.
+type Checkpoint struct {
+	BlockNumber int64  `json:"blockNumber"`
+	BlockHash   string `json:"blockHash,omitempty"`
+	// Gaps are the blocks up to BlockNumber that are still to be retried.
+	Gaps []int64 `json:"gaps,omitempty"`
+	// RecentBlocks are the hashes of the scanned blocks of the reorg window by block number,
+	// so reorganizations deeper than one block that happened while the parser was down are
+	// rolled back on resume.
+	RecentBlocks map[int64]string `json:"recentBlocks,omitempty"`
+	// Subscriptions are restored on resume, so the blocks mined while the parser was down
+	// are matched against them instead of being skipped as blocks without subscriptions.
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+}
+
+// CheckpointStore is an interface for persisting the scanning position of the parser, so
+// scanning resumes where it left off after a restart.
+type CheckpointStore interface {
+	// LoadCheckpoint returns ErrCheckpointNotFound when no checkpoint was saved yet.
+	LoadCheckpoint() (Checkpoint, error)
+	SaveCheckpoint(checkpoint Checkpoint) error
+}
+
+// CatchUpPolicy is where the parser resumes scanning when its checkpoint is further behind
+// the chain head than the catch-up limit.
+type CatchUpPolicy int
+
+const (
+	// CatchUpToLimit resumes scanning the catch-up limit before the chain head, the blocks
+	// before it are skipped.
+	CatchUpToLimit CatchUpPolicy = iota
+	// SkipToHead resumes scanning at the chain head, as if there was no checkpoint.
+	SkipToHead
+)
+
+// FileCheckpointStore is a CheckpointStore that keeps the checkpoint in a json file. the
+// file is replaced atomically so a crash while saving never leaves a corrupt checkpoint.
+type FileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) LoadCheckpoint() (Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Checkpoint{}, ErrCheckpointNotFound
+	}
+
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("could not read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint %s: %w", s.path, err)
+	}
+
+	return checkpoint, nil
+}
+
+// SaveCheckpoint writes the checkpoint to a temporary file next to the checkpoint file and
+// renames it over the checkpoint file once it is synced to disk.
+func (s *FileCheckpointStore) SaveCheckpoint(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("could not encode checkpoint: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create checkpoint: %w", err)
+	}
+
+	// the temporary file is left over only when saving fails.
+	defer os.Remove(file.Name()) //nolint: errcheck
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		return fmt.Errorf("could not replace checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// resumeBlock returns the block after which scanning resumes: the checkpointed block, or
+// the latest block when there is no checkpoint. the checkpointed subscriptions are restored,
+// gaps in the checkpoint are queued for retry and the checkpointed block hashes are kept to
+// detect reorganizations that happened while the parser was down.
+func (p *Parser) resumeBlock(latestBlockNumber int64) (int64, error) {
+	if p.checkpointStore == nil {
+		return latestBlockNumber, nil
+	}
+
+	checkpoint, err := p.checkpointStore.LoadCheckpoint()
+	if errors.Is(err, ErrCheckpointNotFound) {
+		p.logger.Info(fmt.Sprintf("no checkpoint, scanning from block %d", latestBlockNumber))
+		return latestBlockNumber, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("could not load checkpoint: %w", err)
+	}
+
+	p.subscriptions.restore(checkpoint.Subscriptions)
+
+	for _, subscription := range checkpoint.Subscriptions {
+		if err := p.datastore.Add(subscription.Address, []Activity{}); err != nil {
+			return 0, fmt.Errorf("could not restore subscription of %s: %w", subscription.Address, err)
+		}
+	}
+
+	for _, blockNumber := range checkpoint.Gaps {
+		p.gaps.restore(blockNumber, errRestoredGap)
+	}
+
+	behind := latestBlockNumber - checkpoint.BlockNumber
+	if p.maxCatchUp > 0 && behind > p.maxCatchUp {
+		resume := latestBlockNumber - p.maxCatchUp
+		if p.catchUpPolicy == SkipToHead {
+			resume = latestBlockNumber
+		}
+
+		p.logger.Warn(fmt.Sprintf("checkpoint at block %d is %d blocks behind block %d, blocks %d to %d are skipped",
+			checkpoint.BlockNumber, behind, latestBlockNumber, checkpoint.BlockNumber+1, resume))
+
+		return resume, nil
+	}
+
+	for number, hash := range checkpoint.RecentBlocks {
+		p.recentBlocks.add(number, hash)
+	}
+
+	if checkpoint.BlockHash != "" {
+		p.recentBlocks.add(checkpoint.BlockNumber, checkpoint.BlockHash)
+	}
+
+	p.logger.Info(fmt.Sprintf("resuming scanning after checkpoint at block %d, %d blocks behind",
+		checkpoint.BlockNumber, max(behind, 0)))
+
+	return checkpoint.BlockNumber, nil
+}
+
+// markCheckpoint records that the scanning position, the gaps or the subscriptions changed.
+// the changes are saved in batches by saveCheckpoint instead of after every change.
+func (p *Parser) markCheckpoint() {
+	p.checkpointPending.Store(true)
+}
+
+// SaveCheckpoint saves the changes to the scanning position and to the subscriptions that were
+// not checkpointed yet. the checkpoint is saved at the end of every scan and when scanning
+// stops, SaveCheckpoint saves the changes made since then, e.g. on shutdown.
+func (p *Parser) SaveCheckpoint() {
+	p.saveCheckpoint()
+}
+
+// saveCheckpoint persists the last scanned blocks, the outstanding gaps and the subscriptions
+// when they changed since the checkpoint was last saved.
+func (p *Parser) saveCheckpoint() {
+	if p.checkpointStore == nil || !p.initialized.Load() {
+		return
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	if !p.checkpointPending.Swap(false) {
+		return
+	}
+
+	p.uncheckpointedBlocks.Store(0)
+
+	blockNumber := p.lastScannedBlock.Load()
+	checkpoint := Checkpoint{BlockNumber: blockNumber, Subscriptions: p.subscriptions.list()}
+	checkpoint.BlockHash, _ = p.recentBlocks.hash(blockNumber)
+	checkpoint.RecentBlocks = p.recentBlocks.snapshot()
+
+	for _, gap := range p.gaps.list() {
+		checkpoint.Gaps = append(checkpoint.Gaps, gap.BlockNumber)
+	}
+
+	if err := p.checkpointStore.SaveCheckpoint(checkpoint); err != nil {
+		// the changes are saved with the next checkpoint.
+		p.checkpointPending.Store(true)
+		p.logger.Error(fmt.Sprintf("could not save checkpoint at block %d: %v", blockNumber, err))
+	}
+}
@@ -0,0 +1,289 @@
+package blockparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+
+	if _, err := store.LoadCheckpoint(); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Fatalf("LoadCheckpoint() error = %v, want %v", err, ErrCheckpointNotFound)
+	}
+
+	for _, checkpoint := range []Checkpoint{
+		{BlockNumber: 10, BlockHash: "0xa10", Gaps: []int64{4, 7}},
+		{BlockNumber: 11, BlockHash: "0xa11"},
+	} {
+		if err := store.SaveCheckpoint(checkpoint); err != nil {
+			t.Fatalf("SaveCheckpoint() error = %v, want nil", err)
+		}
+
+		loaded, err := store.LoadCheckpoint()
+		if err != nil {
+			t.Fatalf("LoadCheckpoint() error = %v, want nil", err)
+		}
+
+		if !reflect.DeepEqual(loaded, checkpoint) {
+			t.Errorf("LoadCheckpoint() = %+v, want %+v", loaded, checkpoint)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v, want nil", err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("checkpoint directory has %d files, want only the checkpoint", len(entries))
+	}
+
+	if err := os.WriteFile(store.path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v, want nil", err)
+	}
+
+	if _, err := store.LoadCheckpoint(); err == nil || errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("LoadCheckpoint() error = %v, want an invalid checkpoint error", err)
+	}
+}
+
+func TestParserResumesFromCheckpoint(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	transaction := sampleBlock.Transactions[0]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 20, map[int64][]blockchain.Transaction{
+		5: {transaction}, 12: {transaction}, 18: {transaction},
+	})
+
+	tests := []struct {
+		name       string
+		checkpoint *Checkpoint
+		opts       []ConfigOptionResolver
+		wantBlocks []uint64
+		// wantHash is the hash of the checkpointed block 20, unknown when it was not scanned.
+		wantHash string
+	}{
+		{name: "no checkpoint", wantBlocks: []uint64{}},
+		{
+			name:       "resume",
+			checkpoint: &Checkpoint{BlockNumber: 10, BlockHash: "0xa10"},
+			wantBlocks: []uint64{12, 18},
+			wantHash:   "0xa20",
+		},
+		{
+			name:       "retry gaps",
+			checkpoint: &Checkpoint{BlockNumber: 10, BlockHash: "0xa10", Gaps: []int64{5}},
+			wantBlocks: []uint64{5, 12, 18},
+			wantHash:   "0xa20",
+		},
+		{
+			name:       "reorganized while stopped",
+			checkpoint: &Checkpoint{BlockNumber: 13, BlockHash: "0xb13"},
+			wantBlocks: []uint64{18},
+			wantHash:   "0xa20",
+		},
+		{
+			name: "reorganized deeper than one block while stopped",
+			checkpoint: &Checkpoint{BlockNumber: 13, BlockHash: "0xb13", RecentBlocks: map[int64]string{
+				10: "0xa10", 11: "0xb11", 12: "0xb12", 13: "0xb13",
+			}},
+			wantBlocks: []uint64{12, 18},
+			wantHash:   "0xa20",
+		},
+		{
+			name:       "catch up to limit",
+			checkpoint: &Checkpoint{BlockNumber: 10, BlockHash: "0xa10"},
+			opts:       []ConfigOptionResolver{WithCatchUpLimit(5, CatchUpToLimit)},
+			wantBlocks: []uint64{18},
+			wantHash:   "0xa20",
+		},
+		{
+			name:       "skip to head",
+			checkpoint: &Checkpoint{BlockNumber: 10, BlockHash: "0xa10"},
+			opts:       []ConfigOptionResolver{WithCatchUpLimit(5, SkipToHead)},
+			wantBlocks: []uint64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+			if tt.checkpoint != nil {
+				if err := store.SaveCheckpoint(*tt.checkpoint); err != nil {
+					t.Fatalf("SaveCheckpoint() error = %v, want nil", err)
+				}
+			}
+
+			opts := append([]ConfigOptionResolver{
+				WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(&MockChainQuerier{LatestBlock: 20, Blocks: chain}),
+				WithCheckpointStore(store),
+			}, tt.opts...)
+			parser := NewBlockParser(opts...)
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			blocks := make([]uint64, 0)
+			for _, entry := range parser.GetActivity(address) {
+				blocks = append(blocks, entry.BlockNumber)
+			}
+
+			if !reflect.DeepEqual(blocks, tt.wantBlocks) {
+				t.Errorf("GetActivity() blocks = %v, want %v", blocks, tt.wantBlocks)
+			}
+
+			checkpoint, err := store.LoadCheckpoint()
+			if err != nil {
+				t.Fatalf("LoadCheckpoint() error = %v, want nil", err)
+			}
+
+			if len(checkpoint.Subscriptions) != 1 || checkpoint.Subscriptions[0].Address != address {
+				t.Errorf("LoadCheckpoint() subscriptions = %+v, want the subscription of %s", checkpoint.Subscriptions, address)
+			}
+
+			if hash := checkpoint.RecentBlocks[20]; hash != tt.wantHash {
+				t.Errorf("LoadCheckpoint() recent block 20 = %q, want %q", hash, tt.wantHash)
+			}
+
+			checkpoint.Subscriptions, checkpoint.RecentBlocks = nil, nil
+
+			want := Checkpoint{BlockNumber: 20, BlockHash: tt.wantHash}
+			if !reflect.DeepEqual(checkpoint, want) {
+				t.Errorf("LoadCheckpoint() = %+v, want %+v", checkpoint, want)
+			}
+		})
+	}
+}
+
+func TestParserRestoresSubscriptionsFromCheckpoint(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	transaction := sampleBlock.Transactions[0]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 20, map[int64][]blockchain.Transaction{5: {transaction}, 15: {transaction}})
+
+	querier := &MockChainQuerier{LatestBlock: 10, Blocks: chain}
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	parser := NewBlockParser(WithBlockchainQuerier(querier), WithCheckpointStore(store))
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.Subscribe(address, Paused(true))
+	parser.Subscribe(sampleBlock.Transactions[0].To)
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	// the parser restarts once 10 more blocks were mined, before anyone subscribes again.
+	querier.LatestBlock = 20
+
+	restarted := NewBlockParser(WithBlockchainQuerier(querier), WithCheckpointStore(store))
+	if err := restarted.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	if got, want := restarted.Subscriptions(), parser.Subscriptions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Subscriptions() = %+v, want the checkpointed %+v", got, want)
+	}
+
+	restarted.querySubscribedAddressTransactions(context.Background())
+
+	blocks := make([]uint64, 0)
+	for _, entry := range restarted.GetActivity(sampleBlock.Transactions[0].To) {
+		blocks = append(blocks, entry.BlockNumber)
+	}
+
+	if !reflect.DeepEqual(blocks, []uint64{15}) {
+		t.Errorf("GetActivity() blocks = %v, want the block mined while the parser was down", blocks)
+	}
+
+	if activity := restarted.GetActivity(address); len(activity) != 0 {
+		t.Errorf("GetActivity() of the paused address = %+v, want none", activity)
+	}
+}
+
+// countingCheckpointStore keeps the last checkpoint saved in memory and counts the saves.
+type countingCheckpointStore struct {
+	saves      int
+	checkpoint *Checkpoint
+}
+
+func (s *countingCheckpointStore) LoadCheckpoint() (Checkpoint, error) {
+	if s.checkpoint == nil {
+		return Checkpoint{}, ErrCheckpointNotFound
+	}
+
+	return *s.checkpoint, nil
+}
+
+func (s *countingCheckpointStore) SaveCheckpoint(checkpoint Checkpoint) error {
+	s.saves++
+	s.checkpoint = &checkpoint
+
+	return nil
+}
+
+func TestParserBatchesCheckpoints(t *testing.T) {
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 250, nil)
+
+	querier := &MockChainQuerier{LatestBlock: 0, Blocks: chain}
+	store := &countingCheckpointStore{}
+
+	parser := NewBlockParser(WithBlockchainQuerier(querier), WithCheckpointStore(store))
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	for i := range 10 {
+		parser.Subscribe(fmt.Sprintf("0x%040x", i+1))
+	}
+
+	if store.saves != 1 {
+		t.Fatalf("checkpoint saved %d times, want once on start", store.saves)
+	}
+
+	querier.LatestBlock = 250
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	// every checkpointBlocks committed blocks and once the scan is over.
+	if store.saves != 4 {
+		t.Errorf("checkpoint saved %d times scanning 250 blocks, want 4", store.saves)
+	}
+
+	if store.checkpoint.BlockNumber != 250 || len(store.checkpoint.Subscriptions) != 10 {
+		t.Errorf("checkpoint = block %d with %d subscriptions, want block 250 with 10",
+			store.checkpoint.BlockNumber, len(store.checkpoint.Subscriptions))
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if store.saves != 4 {
+		t.Errorf("checkpoint saved %d times, want no save without changes", store.saves)
+	}
+
+	parser.Unsubscribe(fmt.Sprintf("0x%040x", 1))
+	parser.SaveCheckpoint()
+
+	if store.saves != 5 || len(store.checkpoint.Subscriptions) != 9 {
+		t.Errorf("checkpoint saved %d times with %d subscriptions, want 5 saves with 9 subscriptions",
+			store.saves, len(store.checkpoint.Subscriptions))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/spankie/tw-interview/blockchain"
 	"github.com/spankie/tw-interview/cloudflareeth"
 )
 
@@ -19,6 +18,48 @@ type Config struct {
 	blockchainQuerier BlockchainQuerier
 	scanningInterval  time.Duration
 	logger            Logger
+	nameResolver      NameResolver
+	// verifyTransactions enables recomputing the hash and sender of matched transactions.
+	verifyTransactions bool
+	// verifyBlocks wraps the blockchain querier in a VerifyingQuerier.
+	verifyBlocks bool
+	// trackLogs enables matching the logs of scanned blocks, restricted to events
+	// with one of the watched topics when there are any.
+	trackLogs     bool
+	watchedTopics []string
+	// trackTokenTransfers enables decoding the ERC-20 transfers of scanned blocks.
+	trackTokenTransfers bool
+	// trackNFTs enables decoding the ERC-721 and ERC-1155 transfers of scanned blocks.
+	trackNFTs bool
+	// autoSubscribeContracts subscribes contracts deployed by subscribed addresses.
+	autoSubscribeContracts bool
+	// reorgWindow is the number of recently scanned blocks checked for reorganizations.
+	reorgWindow int
+	// confirmationDepth is the number of confirmations after which activity is confirmed,
+	// nil for the default.
+	confirmationDepth *uint64
+	// followFinalized marks activity in finalized blocks as finalized.
+	followFinalized bool
+	// failedBlockPolicy is what the scanner does with blocks that cannot be fetched.
+	failedBlockPolicy  FailedBlockPolicy
+	gapRetryBackoff    time.Duration
+	maxGapRetryBackoff time.Duration
+	// fetchWorkers is the number of blocks fetched concurrently.
+	fetchWorkers int
+	// checkpointStore persists the scanning position across restarts.
+	checkpointStore CheckpointStore
+	// maxCatchUp is how far behind the chain head scanning resumes at most, 0 for no limit.
+	maxCatchUp    int64
+	catchUpPolicy CatchUpPolicy
+	// watchPrefilter enables the address pre-filter of the watch set.
+	watchPrefilter bool
+	// historyPolicy is whether the activity of unsubscribed addresses is kept.
+	historyPolicy HistoryPolicy
+	// startBlock and endBlock bound the blocks scanned, nil when unbounded.
+	startBlock *int64
+	endBlock   *int64
+	// chainID is the id of the chain followed, checked against the node by Chains.
+	chainID uint64
 }
 
 func LoadDefaultConfig(config *Config) {
@@ -27,7 +68,7 @@ func LoadDefaultConfig(config *Config) {
 	}
 
 	if config.datastore == nil {
-		config.datastore = newMemoryDataStore[blockchain.Transaction]()
+		config.datastore = newMemoryDataStore[Activity]()
 	}
 
 	if config.blockchainQuerier == nil {
@@ -38,9 +79,43 @@ func LoadDefaultConfig(config *Config) {
 		config.scanningInterval = defaultScanningInterval
 	}
 
+	if config.reorgWindow <= 0 {
+		config.reorgWindow = defaultReorgWindow
+	}
+
+	if config.confirmationDepth == nil {
+		depth := uint64(defaultConfirmationDepth)
+		config.confirmationDepth = &depth
+	}
+
+	if config.gapRetryBackoff == 0 {
+		config.gapRetryBackoff = defaultGapRetryBackoff
+	}
+
+	if config.maxGapRetryBackoff < config.gapRetryBackoff {
+		config.maxGapRetryBackoff = max(defaultMaxGapRetryBackoff, config.gapRetryBackoff)
+	}
+
+	if config.fetchWorkers <= 0 {
+		config.fetchWorkers = defaultFetchWorkers
+	}
+
 	if config.logger == nil {
 		config.logger = slog.Default()
 	}
+
+	// use the blockchain querier to resolve names if it supports it (e.g. the cloudflare client).
+	if config.nameResolver == nil {
+		if resolver, ok := config.blockchainQuerier.(NameResolver); ok {
+			config.nameResolver = resolver
+		}
+	}
+
+	if config.verifyBlocks {
+		if _, ok := config.blockchainQuerier.(*VerifyingQuerier); !ok {
+			config.blockchainQuerier = NewVerifyingQuerier(config.blockchainQuerier)
+		}
+	}
 }
 
 func WithLogger(logger Logger) ConfigOptionResolver {
@@ -61,8 +136,179 @@ func WithBlockchainQuerier(blockchainQuerier BlockchainQuerier) ConfigOptionReso
 	}
 }
 
+// WithChainID sets the id of the chain the parser follows. it is included in the published
+// events and checked against the node when the parser is added to Chains.
+func WithChainID(chainID uint64) ConfigOptionResolver {
+	return func(c *Config) {
+		c.chainID = chainID
+	}
+}
+
 func WithScanningInterval(scanningInterval time.Duration) ConfigOptionResolver {
 	return func(c *Config) {
 		c.scanningInterval = scanningInterval
 	}
 }
+
+func WithNameResolver(nameResolver NameResolver) ConfigOptionResolver {
+	return func(c *Config) {
+		c.nameResolver = nameResolver
+	}
+}
+
+// WithTransactionVerification makes the parser recompute the hash and recover the sender of
+// every matched transaction, flagging transactions returned by the node that do not match.
+func WithTransactionVerification() ConfigOptionResolver {
+	return func(c *Config) {
+		c.verifyTransactions = true
+	}
+}
+
+// WithBlockVerification makes the parser reject fetched blocks whose transactions, withdrawals,
+// receipts or header do not match the roots and hash returned with them. see VerifyingQuerier.
+func WithBlockVerification() ConfigOptionResolver {
+	return func(c *Config) {
+		c.verifyBlocks = true
+	}
+}
+
+// WithLogTracking makes the parser store the event logs emitted by subscribed addresses or
+// with a subscribed address as an indexed topic. when topics are given, only logs whose
+// event signature is one of them are stored. receipts are fetched through the blockchain
+// querier, which must implement ReceiptsQuerier, and only for blocks whose logs bloom may
+// contain a matching log.
+func WithLogTracking(topics ...string) ConfigOptionResolver {
+	return func(c *Config) {
+		c.trackLogs = true
+		c.watchedTopics = append(c.watchedTopics, topics...)
+	}
+}
+
+// WithTokenTransferTracking makes the parser decode the ERC-20 Transfer logs of scanned blocks
+// and store the transfers sent or received by subscribed addresses. the logs are fetched
+// through the blockchain querier, which must implement LogsQuerier or ReceiptsQuerier, and
+// only for blocks whose logs bloom may contain a matching transfer. a VerifyingQuerier takes
+// the transfers from verified receipts.
+func WithTokenTransferTracking() ConfigOptionResolver {
+	return func(c *Config) {
+		c.trackTokenTransfers = true
+	}
+}
+
+// WithNFTTracking makes the parser decode the ERC-721 Transfer and ERC-1155 TransferSingle and
+// TransferBatch logs of scanned blocks, store the NFTs sent or received by subscribed addresses
+// and keep their holdings up to date. the logs are fetched like with WithTokenTransferTracking.
+func WithNFTTracking() ConfigOptionResolver {
+	return func(c *Config) {
+		c.trackNFTs = true
+	}
+}
+
+// WithWatchPrefilter makes the parser rule out most addresses that are not subscribed with a
+// bloom filter of the subscribed addresses before decoding them, which speeds up matching when
+// thousands of addresses are subscribed.
+func WithWatchPrefilter() ConfigOptionResolver {
+	return func(c *Config) {
+		c.watchPrefilter = true
+	}
+}
+
+// WithContractAutoSubscribe makes the parser subscribe to the contracts deployed by
+// subscribed addresses as soon as their deployment is scanned.
+func WithContractAutoSubscribe() ConfigOptionResolver {
+	return func(c *Config) {
+		c.autoSubscribeContracts = true
+	}
+}
+
+// WithReorgWindow sets how many recently scanned blocks are kept to detect chain
+// reorganizations and roll back the activity of abandoned blocks.
+func WithReorgWindow(blocks int) ConfigOptionResolver {
+	return func(c *Config) {
+		c.reorgWindow = blocks
+	}
+}
+
+// WithConfirmationDepth sets the number of confirmations, counting the block of the
+// activity itself, after which activity is reported as confirmed. with 0 activity is
+// confirmed as soon as it is scanned.
+func WithConfirmationDepth(confirmations uint64) ConfigOptionResolver {
+	return func(c *Config) {
+		c.confirmationDepth = &confirmations
+	}
+}
+
+// WithFinalizedTag makes the parser follow the finalized block of the chain and report
+// activity in finalized blocks as finalized. the blockchain querier must implement
+// FinalizedBlockQuerier.
+func WithFinalizedTag() ConfigOptionResolver {
+	return func(c *Config) {
+		c.followFinalized = true
+	}
+}
+
+// WithFailedBlockPolicy sets what the scanner does when a block cannot be fetched. it
+// defaults to RetryFailedBlocks.
+func WithFailedBlockPolicy(policy FailedBlockPolicy) ConfigOptionResolver {
+	return func(c *Config) {
+		c.failedBlockPolicy = policy
+	}
+}
+
+// WithGapRetryBackoff sets the delay before the first retry of a block that could not be
+// fetched, doubled after every failed retry up to maxBackoff.
+func WithGapRetryBackoff(backoff, maxBackoff time.Duration) ConfigOptionResolver {
+	return func(c *Config) {
+		c.gapRetryBackoff = backoff
+		c.maxGapRetryBackoff = maxBackoff
+	}
+}
+
+// WithFetchWorkers sets how many blocks are fetched concurrently when the parser has
+// several blocks to scan. blocks are still committed one at a time in block order.
+func WithFetchWorkers(workers int) ConfigOptionResolver {
+	return func(c *Config) {
+		c.fetchWorkers = workers
+	}
+}
+
+// WithCheckpointStore makes the parser save the last scanned block to the store as it
+// scans, and resume scanning after it when it starts instead of at the chain head.
+func WithCheckpointStore(store CheckpointStore) ConfigOptionResolver {
+	return func(c *Config) {
+		c.checkpointStore = store
+	}
+}
+
+// WithCatchUpLimit limits how many blocks behind the chain head the parser resumes scanning
+// from its checkpoint. when the checkpoint is further behind, the policy decides where
+// scanning resumes and the blocks in between are skipped.
+func WithCatchUpLimit(blocks int64, policy CatchUpPolicy) ConfigOptionResolver {
+	return func(c *Config) {
+		c.maxCatchUp = blocks
+		c.catchUpPolicy = policy
+	}
+}
+
+// WithStartBlock makes the parser start scanning at the given block instead of resuming from
+// its checkpoint or starting at the chain head.
+func WithStartBlock(blockNumber int64) ConfigOptionResolver {
+	return func(c *Config) {
+		c.startBlock = &blockNumber
+	}
+}
+
+// WithEndBlock makes the parser stop scanning once it scanned the given block.
+func WithEndBlock(blockNumber int64) ConfigOptionResolver {
+	return func(c *Config) {
+		c.endBlock = &blockNumber
+	}
+}
+
+// WithHistoryPolicy sets whether the stored activity of an address is kept or deleted when
+// it is unsubscribed. it defaults to KeepHistory.
+func WithHistoryPolicy(policy HistoryPolicy) ConfigOptionResolver {
+	return func(c *Config) {
+		c.historyPolicy = policy
+	}
+}
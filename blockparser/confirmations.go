@@ -0,0 +1,160 @@
+package blockparser
+
+import (
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// defaultConfirmationDepth is the number of confirmations after which activity is
+// considered confirmed.
+const defaultConfirmationDepth = 12
+
+// ConfirmationStatus is how settled the block of an activity entry is.
+type ConfirmationStatus string
+
+const (
+	// StatusUnconfirmed is activity in a block with fewer confirmations than the
+	// confirmation depth of the parser, which may still be reorganized away.
+	StatusUnconfirmed ConfirmationStatus = "unconfirmed"
+	// StatusConfirmed is activity in a block with at least as many confirmations as the
+	// confirmation depth of the parser.
+	StatusConfirmed ConfirmationStatus = "confirmed"
+	// StatusFinalized is activity in a block finalized by the beacon chain. it is only
+	// set when the parser follows the finalized block (see WithFinalizedTag).
+	StatusFinalized ConfirmationStatus = "finalized"
+)
+
+// FinalizedBlockQuerier is an interface for querying the latest finalized block. blockchain
+// queriers can optionally implement it to let the parser track finality.
+type FinalizedBlockQuerier interface {
+	GetFinalizedBlock() (string, error)
+}
+
+// QueryOption filters the activity returned by the parser.
+type QueryOption func(*activityQuery)
+
+type activityQuery struct {
+	minConfirmations uint64
+	direction        Direction
+	token            string
+}
+
+// MinConfirmations only returns activity with at least the given number of confirmations.
+func MinConfirmations(confirmations uint64) QueryOption {
+	return func(q *activityQuery) {
+		q.minConfirmations = confirmations
+	}
+}
+
+// InDirection only returns activity in the given direction. transactions sent by the
+// address to itself are returned for every direction.
+func InDirection(direction Direction) QueryOption {
+	return func(q *activityQuery) {
+		q.direction = direction
+	}
+}
+
+// OfToken only returns the token and NFT transfers of the token contract at the given address.
+func OfToken(token string) QueryOption {
+	return func(q *activityQuery) {
+		q.token, _ = blockchain.NormalizeAddress(token)
+	}
+}
+
+// matchesToken reports whether the entry is a transfer of the token of the query.
+func (q activityQuery) matchesToken(entry Activity) bool {
+	switch {
+	case q.token == "":
+		return true
+	case entry.TokenTransfer != nil:
+		return entry.TokenTransfer.Token == q.token
+	case entry.NFTTransfer != nil:
+		return entry.NFTTransfer.Contract == q.token
+	default:
+		return false
+	}
+}
+
+// matchesDirection reports whether the entry is in the direction of the query.
+func (q activityQuery) matchesDirection(entry Activity) bool {
+	return q.direction == "" || entry.Direction == q.direction || entry.Direction == DirectionSelf
+}
+
+func newActivityQuery(opts []QueryOption) activityQuery {
+	var query activityQuery
+
+	for _, opt := range opts {
+		opt(&query)
+	}
+
+	return query
+}
+
+// withConfirmations sets the confirmations and status of the activity entries from the
+// current chain head and finalized block, and drops the ones the query filters out by
+// confirmations, direction or token.
+func (p *Parser) withConfirmations(activity []Activity, query activityQuery) []Activity {
+	head := max(p.headBlock.Load(), p.lastScannedBlock.Load())
+	finalized := p.finalizedBlock.Load()
+
+	filtered := make([]Activity, 0, len(activity))
+
+	for _, entry := range activity {
+		entry.Confirmations = confirmations(entry.BlockNumber, head)
+
+		switch {
+		case p.followFinalized && finalized > 0 && entry.BlockNumber <= uint64(finalized):
+			entry.Status = StatusFinalized
+		case entry.Confirmations >= p.confirmationDepth:
+			entry.Status = StatusConfirmed
+		default:
+			entry.Status = StatusUnconfirmed
+		}
+
+		if entry.Confirmations < query.minConfirmations || !query.matchesDirection(entry) ||
+			!query.matchesToken(entry) {
+			continue
+		}
+
+		filtered = append(filtered, entry)
+	}
+
+	return filtered
+}
+
+// confirmations returns the number of blocks on top of and including the block.
+func confirmations(blockNumber uint64, head int64) uint64 {
+	if head < 0 || blockNumber > uint64(head) {
+		return 0
+	}
+
+	return uint64(head) - blockNumber + 1
+}
+
+// updateFinalizedBlock fetches the latest finalized block when the parser follows it.
+func (p *Parser) updateFinalizedBlock() {
+	if !p.followFinalized {
+		return
+	}
+
+	querier, ok := p.blockchainQuerier.(FinalizedBlockQuerier)
+	if !ok {
+		p.logger.Warn(fmt.Sprintf("%T cannot fetch the finalized block", p.blockchainQuerier))
+		return
+	}
+
+	blockNumberStr, err := querier.GetFinalizedBlock()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("error fetching finalized block: %v", err))
+		return
+	}
+
+	blockNumber, err := blockchain.ParseQuantity(blockNumberStr)
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("invalid finalized block number: %v", err))
+		return
+	}
+
+	p.finalizedBlock.Store(int64(blockNumber)) //nolint: gosec // block numbers fit in int64.
+}
@@ -0,0 +1,126 @@
+package blockparser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+type MockFinalizedChainQuerier struct {
+	MockChainQuerier
+	FinalizedBlock int64
+}
+
+func (m *MockFinalizedChainQuerier) GetFinalizedBlock() (string, error) {
+	return blockchain.Quantity(m.FinalizedBlock).String(), nil
+}
+
+func TestParserReportsConfirmations(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 100, 110, map[int64][]blockchain.Transaction{101: {sampleBlock.Transactions[0]}})
+
+	blockchainQuerier := &MockFinalizedChainQuerier{MockChainQuerier: MockChainQuerier{LatestBlock: 100, Blocks: chain}}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithConfirmationDepth(3), WithFinalizedTag())
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	tests := []struct {
+		name             string
+		head             int64
+		finalized        int64
+		minConfirmations uint64
+		wantEntries      int
+		wantStatus       ConfirmationStatus
+		wantConfirmation uint64
+	}{
+		{name: "at the chain tip", head: 101, wantEntries: 1, wantStatus: StatusUnconfirmed, wantConfirmation: 1},
+		{name: "filtered by min confirmations", head: 101, minConfirmations: 2, wantEntries: 0},
+		{name: "confirmed", head: 103, minConfirmations: 2, wantEntries: 1, wantStatus: StatusConfirmed, wantConfirmation: 3},
+		{name: "finalized", head: 110, finalized: 102, wantEntries: 1, wantStatus: StatusFinalized, wantConfirmation: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockchainQuerier.LatestBlock = tt.head
+			blockchainQuerier.FinalizedBlock = tt.finalized
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			activity := parser.GetActivity(address, MinConfirmations(tt.minConfirmations))
+			if len(activity) != tt.wantEntries {
+				t.Fatalf("GetActivity() = %v, want %d entries", activity, tt.wantEntries)
+			}
+
+			if tt.wantEntries == 0 {
+				return
+			}
+
+			if activity[0].Status != tt.wantStatus || activity[0].Confirmations != tt.wantConfirmation {
+				t.Errorf("status = %s with %d confirmations, want %s with %d",
+					activity[0].Status, activity[0].Confirmations, tt.wantStatus, tt.wantConfirmation)
+			}
+
+			transactions := parser.GetTransactions(address, MinConfirmations(tt.minConfirmations))
+			if len(transactions) != tt.wantEntries {
+				t.Errorf("GetTransactions() = %v, want %d transactions", transactions, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestParserConfirmationDepth(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ConfigOptionResolver
+		head       int64
+		wantStatus ConfirmationStatus
+	}{
+		{name: "default depth", head: 111, wantStatus: StatusUnconfirmed},
+		{name: "default depth reached", head: 112, wantStatus: StatusConfirmed},
+		{name: "zero depth", opts: []ConfigOptionResolver{WithConfirmationDepth(0)}, head: 101, wantStatus: StatusConfirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := sampleBlock.Transactions[0].From
+
+			chain := make(map[int64]*blockchain.Block)
+			extendChain(chain, "a", 100, 112, map[int64][]blockchain.Transaction{101: {sampleBlock.Transactions[0]}})
+
+			blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+			opts := append([]ConfigOptionResolver{WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(blockchainQuerier)}, tt.opts...)
+			parser := NewBlockParser(opts...)
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			blockchainQuerier.LatestBlock = tt.head
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			activity := parser.GetActivity(address)
+			if len(activity) != 1 {
+				t.Fatalf("GetActivity() = %v, want 1 entry", activity)
+			}
+
+			if activity[0].Status != tt.wantStatus {
+				t.Errorf("status = %s with %d confirmations, want %s",
+					activity[0].Status, activity[0].Confirmations, tt.wantStatus)
+			}
+		})
+	}
+}
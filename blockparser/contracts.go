@@ -0,0 +1,88 @@
+package blockparser
+
+import (
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// failedStatus is the receipt status of a reverted transaction.
+const failedStatus = "0x0"
+
+// contractActivity returns the contracts deployed by watched addresses in the block,
+// keyed by the canonical storage key of the deployers. the deployed address is taken
+// from the receipt of the deployment when receipts can be fetched, which also tells
+// reverted deployments apart, and computed from the sender and nonce otherwise. when
+// autoSubscribe is true, the deployed contracts are subscribed and their creation is
+// stored in their history too.
+func (p *Parser) contractActivity(block *blockchain.Block, blockReceipts *blockReceipts, watched *watchSet,
+	autoSubscribe bool,
+) map[string][]Activity {
+	blockNumber, err := block.NumberUint64()
+	if err != nil {
+		return nil
+	}
+
+	activity := make(map[string][]Activity)
+
+	for i := range block.Transactions {
+		transaction := &block.Transactions[i]
+		if !transaction.IsContractCreation() {
+			continue
+		}
+
+		deployer, ok := watched.match(transaction.From)
+		if !ok {
+			continue
+		}
+
+		contract, ok := p.deployedAddress(transaction, blockReceipts)
+		if !ok {
+			continue
+		}
+
+		// the transaction is copied so the stored activity does not keep the block alive.
+		deployment := *transaction
+		entry := Activity{
+			Kind:            ActivityKindContractCreated,
+			BlockNumber:     blockNumber,
+			BlockHash:       block.Hash,
+			Transaction:     &deployment,
+			ContractAddress: contract,
+			Direction:       DirectionOut,
+			Counterparty:    contract,
+		}
+		activity[deployer] = append(activity[deployer], entry)
+
+		if autoSubscribe && p.Subscribe(contract) {
+			p.logger.Info(fmt.Sprintf("subscribed to contract %s deployed by %s", contract, deployer))
+
+			entry.Direction, entry.Counterparty = DirectionIn, deployer
+			activity[contract] = append(activity[contract], entry)
+		}
+	}
+
+	return activity
+}
+
+// deployedAddress returns the storage key of the address of the contract deployed by
+// the transaction. ok is false when the deployment reverted or the address is unknown.
+func (p *Parser) deployedAddress(transaction *blockchain.Transaction, blockReceipts *blockReceipts) (string, bool) {
+	if receipt, ok := blockReceipts.find(transaction.Hash); ok {
+		if receipt.Status == failedStatus {
+			return "", false
+		}
+
+		if key, err := blockchain.NormalizeAddress(receipt.ContractAddress); err == nil {
+			return key, true
+		}
+	}
+
+	address, err := transaction.ContractAddress()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("could not compute address of contract deployed by %s: %v", transaction.Hash, err))
+		return "", false
+	}
+
+	return address.Key(), true
+}
@@ -0,0 +1,105 @@
+package blockparser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestParserDetectsContractCreation(t *testing.T) {
+	deployer := "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"
+	deployment := blockchain.Transaction{
+		Hash:  "0x5a1f0b9e3c6d0d1b33c4e88e0bd2b66d81a7b1b2c1e8f3a7e76c1d0bb2f7a6c1",
+		From:  deployer,
+		Nonce: "0x1",
+		Input: "0x6080604052",
+	}
+
+	tests := []struct {
+		name          string
+		querier       BlockchainQuerier
+		autoSubscribe bool
+		wantContract  string
+	}{
+		{
+			name:         "address computed from sender and nonce",
+			wantContract: "0x343c43a37d37dff08ae8c4a11544c718abb4fcf8",
+		},
+		{
+			name: "address taken from the receipt",
+			querier: &MockReceiptsQuerier{Receipts: []blockchain.Receipt{
+				{TransactionHash: deployment.Hash, Status: "0x1", ContractAddress: "0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91"},
+			}},
+			autoSubscribe: true,
+			wantContract:  "0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91",
+		},
+		{
+			name: "reverted deployment",
+			querier: &MockReceiptsQuerier{Receipts: []blockchain.Receipt{
+				{TransactionHash: deployment.Hash, Status: "0x0"},
+			}},
+			autoSubscribe: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := sampleBlock
+			block.Transactions = []blockchain.Transaction{deployment}
+
+			querier := tt.querier
+			switch q := querier.(type) {
+			case *MockReceiptsQuerier:
+				q.MockBlockchainQuerier = MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+			default:
+				querier = &MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block}
+			}
+
+			opts := []ConfigOptionResolver{WithDataStore(newMemoryDataStore[Activity]()), WithBlockchainQuerier(querier)}
+			if tt.autoSubscribe {
+				opts = append(opts, WithContractAutoSubscribe())
+			}
+
+			parser := NewBlockParser(opts...)
+
+			if subscribed := parser.Subscribe(deployer); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", deployer, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			var created []Activity
+
+			for _, entry := range parser.GetActivity(deployer) {
+				if entry.Kind == ActivityKindContractCreated {
+					created = append(created, entry)
+				}
+			}
+
+			if tt.wantContract == "" {
+				if len(created) != 0 {
+					t.Errorf("GetActivity() = %v, want no contract creation", created)
+				}
+
+				return
+			}
+
+			if len(created) != 1 || created[0].ContractAddress != tt.wantContract {
+				t.Fatalf("GetActivity() = %v, want the creation of %s", created, tt.wantContract)
+			}
+
+			contractActivity := parser.GetActivity(tt.wantContract)
+			if tt.autoSubscribe && (len(contractActivity) != 1 || contractActivity[0].Kind != ActivityKindContractCreated) {
+				t.Errorf("contract should be subscribed with its creation; got %v", contractActivity)
+			}
+
+			if !tt.autoSubscribe && contractActivity != nil {
+				t.Errorf("contract should not be subscribed; got %v", contractActivity)
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"sync"
 )
 
-var ErrInvalidKey = errors.New("invalid key")
+var (
+	ErrInvalidKey  = errors.New("invalid key")
+	ErrKeyNotFound = errors.New("key not found")
+)
 
 type memoryStore[T any] struct {
 	mu sync.RWMutex
@@ -61,3 +64,30 @@ func (s *memoryStore[T]) GetKeys() []string {
 
 	return keys
 }
+
+func (s *memoryStore[T]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[key]; !ok {
+		return ErrKeyNotFound
+	}
+
+	delete(s.data, key)
+
+	return nil
+}
+
+func (s *memoryStore[T]) Update(key string, update func([]T) []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.data[key]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	s.data[key] = update(value)
+
+	return nil
+}
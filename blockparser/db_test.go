@@ -1,6 +1,7 @@
 package blockparser
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/spankie/tw-interview/blockchain"
@@ -141,3 +142,47 @@ func TestAddEmptyKey(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdate(t *testing.T) {
+	store := newMemoryDataStore[blockchain.Transaction]()
+
+	if err := store.Update("testKey", nil); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	if err := store.Add("testKey", transactions); err != nil {
+		t.Fatalf("expected nil err, got: %v", err)
+	}
+
+	err := store.Update("testKey", func(stored []blockchain.Transaction) []blockchain.Transaction {
+		return stored[1:]
+	})
+	if err != nil {
+		t.Errorf("expected nil err, got: %v", err)
+	}
+
+	gotTransactions, _ := store.Get("testKey")
+	if len(gotTransactions) != len(transactions)-1 || gotTransactions[0].From != transactions[1].From {
+		t.Errorf("Get() = %v, want all but the first transaction", gotTransactions)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	store := newMemoryDataStore[blockchain.Transaction]()
+
+	if err := store.Add("testKey", transactions); err != nil {
+		t.Fatalf("expected nil err, got: %v", err)
+	}
+
+	if err := store.Delete("testKey"); err != nil {
+		t.Errorf("expected nil err, got: %v", err)
+	}
+
+	if _, ok := store.Get("testKey"); ok {
+		t.Errorf("Get() found deleted key")
+	}
+
+	if err := store.Delete("testKey"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,75 @@
+package blockparser
+
+import (
+	"context"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// defaultFetchWorkers is the number of blocks fetched concurrently when catching up.
+const defaultFetchWorkers = 4
+
+// fetchResult is a fetched block or the error fetching it.
+type fetchResult struct {
+	blockNumber int64
+	block       *blockchain.Block
+	err         error
+}
+
+// fetchBlocks fetches the blocks from..to with up to p.fetchWorkers requests in flight and
+// delivers them strictly in block order, so a slow block holds back the later ones instead
+// of letting them be committed first. at most p.fetchWorkers blocks are fetched ahead of
+// the block being consumed. fetching stops when the context is cancelled.
+func (p *Parser) fetchBlocks(ctx context.Context, from, to int64) <-chan fetchResult {
+	workers := max(p.fetchWorkers, 1)
+
+	// slots bounds the blocks fetched but not consumed yet, pending delivers their
+	// results in block order.
+	slots := make(chan struct{}, workers)
+	pending := make(chan chan fetchResult, workers)
+
+	go func() {
+		defer close(pending)
+
+		for blockNumber := from; blockNumber <= to; blockNumber++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			result := make(chan fetchResult, 1)
+			pending <- result
+
+			go func() {
+				block, err := p.getBlockByNumber(blockNumber)
+				result <- fetchResult{blockNumber: blockNumber, block: block, err: err}
+			}()
+		}
+	}()
+
+	results := make(chan fetchResult)
+
+	go func() {
+		defer close(results)
+
+		for result := range pending {
+			var fetched fetchResult
+
+			select {
+			case fetched = <-result:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case results <- fetched:
+				<-slots
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results
+}
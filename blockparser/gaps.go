@@ -0,0 +1,213 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// ErrGapNotLinked is returned when the block of a gap does not link to the scanned blocks
+// around it, e.g. when the node returned a block of an abandoned fork.
+var ErrGapNotLinked = errors.New("block does not link to the scanned blocks around it")
+
+const (
+	defaultGapRetryBackoff    = 15 * time.Second
+	defaultMaxGapRetryBackoff = 30 * time.Minute
+)
+
+// FailedBlockPolicy is what the scanner does when a block cannot be fetched.
+type FailedBlockPolicy int
+
+const (
+	// RetryFailedBlocks records blocks that could not be fetched as gaps, keeps scanning
+	// the following blocks and retries the gaps with an exponential backoff until they are filled.
+	RetryFailedBlocks FailedBlockPolicy = iota
+	// HaltOnFailedBlock stops scanning at a block that could not be fetched, the block is
+	// retried on the next scan.
+	HaltOnFailedBlock
+)
+
+// Gap is a scanned block that could not be fetched and whose activity is missing.
+type Gap struct {
+	BlockNumber   int64     `json:"blockNumber"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError"`
+	FirstFailedAt time.Time `json:"firstFailedAt"`
+	NextRetryAt   time.Time `json:"nextRetryAt"`
+}
+
+// gapQueue holds the gaps left by the scanner until they are filled.
+type gapQueue struct {
+	mu           sync.Mutex
+	gaps         map[int64]*Gap
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	timeProvider func() time.Time
+}
+
+func newGapQueue(backoff, maxBackoff time.Duration) *gapQueue {
+	return &gapQueue{
+		gaps:         make(map[int64]*Gap),
+		backoff:      backoff,
+		maxBackoff:   maxBackoff,
+		timeProvider: time.Now,
+	}
+}
+
+// fail records a failed attempt to fetch the block and schedules its next retry.
+func (q *gapQueue) fail(blockNumber int64, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.timeProvider()
+
+	gap, ok := q.gaps[blockNumber]
+	if !ok {
+		gap = &Gap{BlockNumber: blockNumber, FirstFailedAt: now}
+		q.gaps[blockNumber] = gap
+	}
+
+	gap.Attempts++
+	gap.LastError = err.Error()
+	gap.NextRetryAt = now.Add(q.retryDelay(gap.Attempts))
+}
+
+// retryDelay doubles the backoff with every failed attempt, up to the maximum backoff.
+func (q *gapQueue) retryDelay(attempts int) time.Duration {
+	delay := q.backoff
+
+	for i := 1; i < attempts && delay < q.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, q.maxBackoff)
+}
+
+// restore queues a gap left before a restart, due for retry right away.
+func (q *gapQueue) restore(blockNumber int64, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.gaps[blockNumber]; ok {
+		return
+	}
+
+	now := q.timeProvider()
+	q.gaps[blockNumber] = &Gap{BlockNumber: blockNumber, LastError: err.Error(), FirstFailedAt: now, NextRetryAt: now}
+}
+
+func (q *gapQueue) fill(blockNumber int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.gaps, blockNumber)
+}
+
+// due returns the gaps whose retry is due, lowest block first.
+func (q *gapQueue) due() []int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.timeProvider()
+	due := make([]int64, 0)
+
+	for blockNumber, gap := range q.gaps {
+		if !gap.NextRetryAt.After(now) {
+			due = append(due, blockNumber)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+
+	return due
+}
+
+func (q *gapQueue) list() []Gap {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	gaps := make([]Gap, 0, len(q.gaps))
+	for _, gap := range q.gaps {
+		gaps = append(gaps, *gap)
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].BlockNumber < gaps[j].BlockNumber })
+
+	return gaps
+}
+
+// Gaps returns the blocks the parser could not fetch yet, lowest block first. their
+// activity is stored once they are retried successfully.
+func (p *Parser) Gaps() []Gap {
+	return p.gaps.list()
+}
+
+// retryGaps refetches the gaps whose retry is due and stores their activity.
+func (p *Parser) retryGaps() {
+	for _, blockNumber := range p.gaps.due() {
+		if err := p.fillGap(blockNumber); err != nil {
+			p.gaps.fail(blockNumber, err)
+			p.logger.Error(fmt.Sprintf("retry of block %d failed: %v", blockNumber, err))
+			p.publish(Event{Kind: EventError, BlockNumber: blockNumber, Error: err.Error()})
+
+			continue
+		}
+
+		p.gaps.fill(blockNumber)
+		p.logger.Info(fmt.Sprintf("gap at block %d filled", blockNumber))
+		p.markCheckpoint()
+	}
+}
+
+// fillGap fetches the block of a gap and stores its activity, which is merged with the
+// activity of the later blocks already stored.
+func (p *Parser) fillGap(blockNumber int64) error {
+	block, err := p.getBlockByNumber(blockNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkGapLinks(blockNumber, block); err != nil {
+		return err
+	}
+
+	if err := p.saveSubscribedAddressActivity(block); err != nil {
+		return err
+	}
+
+	p.recentBlocks.add(blockNumber, block.Hash)
+
+	return nil
+}
+
+// checkGapLinks checks that the block of a gap is the child of the scanned block before it
+// and the parent of the scanned block after it, when they are still in the reorg window. a
+// gap whose block does not link to them is retried later, once the scanner rolled back the
+// blocks around it or the node serves the block of the scanned chain.
+func (p *Parser) checkGapLinks(blockNumber int64, block *blockchain.Block) error {
+	if parentHash, ok := p.recentBlocks.hash(blockNumber - 1); ok && block.ParentHash != parentHash {
+		return fmt.Errorf("%w: parent %s of block %d is not the scanned block %s",
+			ErrGapNotLinked, block.ParentHash, blockNumber, parentHash)
+	}
+
+	childHash, ok := p.recentBlocks.hash(blockNumber + 1)
+	if !ok {
+		return nil
+	}
+
+	child, err := p.getBlockByNumber(blockNumber + 1)
+	if err != nil {
+		return err
+	}
+
+	if child.Hash != childHash || child.ParentHash != block.Hash {
+		return fmt.Errorf("%w: block %d is not the parent of the scanned block %s",
+			ErrGapNotLinked, blockNumber, childHash)
+	}
+
+	return nil
+}
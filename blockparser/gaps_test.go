@@ -0,0 +1,285 @@
+package blockparser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestParserRetriesGaps(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 100, 104, map[int64][]blockchain.Transaction{
+		102: {sampleBlock.Transactions[0]},
+		103: {sampleBlock.Transactions[0]},
+	})
+
+	missing := chain[102]
+	delete(chain, 102)
+
+	blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithGapRetryBackoff(time.Minute, time.Hour))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	parser.gaps.timeProvider = func() time.Time { return now }
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	blockchainQuerier.LatestBlock = 104
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if block := parser.GetCurrentBlock(); block != 104 {
+		t.Errorf("GetCurrentBlock() = %d, want 104", block)
+	}
+
+	gaps := parser.Gaps()
+	if len(gaps) != 1 || gaps[0].BlockNumber != 102 || gaps[0].Attempts != 1 ||
+		!gaps[0].NextRetryAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Gaps() = %+v, want block 102 retried in a minute", gaps)
+	}
+
+	// the retry is not due yet.
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if gaps := parser.Gaps(); len(gaps) != 1 || gaps[0].Attempts != 1 {
+		t.Fatalf("Gaps() = %+v, want block 102 not retried yet", gaps)
+	}
+
+	// the retry fails again, doubling the backoff.
+	now = now.Add(time.Minute)
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if gaps := parser.Gaps(); len(gaps) != 1 || gaps[0].Attempts != 2 ||
+		!gaps[0].NextRetryAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("Gaps() = %+v, want block 102 retried in 2 minutes", gaps)
+	}
+
+	chain[102] = missing
+	now = now.Add(2 * time.Minute)
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if gaps := parser.Gaps(); len(gaps) != 0 {
+		t.Errorf("Gaps() = %+v, want no gaps", gaps)
+	}
+
+	// the activity of the filled gap is stored before the activity of the later blocks, once.
+	parser.retryGaps()
+
+	activity := parser.GetActivity(address)
+	if len(activity) != 2 || activity[0].BlockNumber != 102 || activity[1].BlockNumber != 103 {
+		t.Errorf("GetActivity() = %+v, want the activity of blocks 102 and 103", activity)
+	}
+
+	if hash, ok := parser.recentBlocks.hash(102); !ok || hash != missing.Hash {
+		t.Errorf("recent block 102 = %q, %v, want %q", hash, ok, missing.Hash)
+	}
+}
+
+func TestParserHaltsOnFailedBlock(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 100, 104, nil)
+	delete(chain, 102)
+
+	blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithFailedBlockPolicy(HaltOnFailedBlock))
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	blockchainQuerier.LatestBlock = 104
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if block := parser.GetCurrentBlock(); block != 101 {
+		t.Errorf("GetCurrentBlock() = %d, want 101", block)
+	}
+
+	if gaps := parser.Gaps(); len(gaps) != 0 {
+		t.Errorf("Gaps() = %+v, want no gaps", gaps)
+	}
+}
+
+// receiptsChainQuerier serves the receipts of the blocks of a chain, failing for the
+// blocks in failing.
+type receiptsChainQuerier struct {
+	MockChainQuerier
+	Receipts map[int64][]blockchain.Receipt
+	Failing  map[int64]bool
+}
+
+func (m *receiptsChainQuerier) GetBlockReceipts(blockNumber string) ([]blockchain.Receipt, error) {
+	number, err := blockchain.ParseQuantity(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Failing[int64(number)] {
+		return nil, fmt.Errorf("receipts of block %s not available", blockNumber)
+	}
+
+	return m.Receipts[int64(number)], nil
+}
+
+func TestParserRetriesBlocksWithFailedReceipts(t *testing.T) {
+	subscriber := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	transfer := blockchain.Log{
+		Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+		Topics: []string{
+			transferTopic,
+			"0x0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			"0x000000000000000000000000fb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		},
+		Data: "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}
+
+	tests := []struct {
+		name        string
+		policy      FailedBlockPolicy
+		wantCurrent int
+		wantGaps    int
+	}{
+		{name: "retried as a gap", policy: RetryFailedBlocks, wantCurrent: 104, wantGaps: 1},
+		{name: "halted", policy: HaltOnFailedBlock, wantCurrent: 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bloom, err := blockchain.CreateBloom([]blockchain.Log{transfer})
+			if err != nil {
+				t.Fatalf("CreateBloom() error = %v", err)
+			}
+
+			chain := make(map[int64]*blockchain.Block)
+			extendChain(chain, "a", 100, 104, nil)
+			chain[102].LogsBloom = bloom.String()
+
+			blockchainQuerier := &receiptsChainQuerier{
+				MockChainQuerier: MockChainQuerier{LatestBlock: 100, Blocks: chain},
+				Receipts:         map[int64][]blockchain.Receipt{102: {{Logs: []blockchain.Log{transfer}}}},
+				Failing:          map[int64]bool{102: true},
+			}
+
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(blockchainQuerier), WithLogTracking(), WithFailedBlockPolicy(tt.policy),
+				WithGapRetryBackoff(time.Nanosecond, time.Nanosecond))
+
+			if subscribed := parser.Subscribe(subscriber); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			blockchainQuerier.LatestBlock = 104
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			if block := parser.GetCurrentBlock(); block != tt.wantCurrent {
+				t.Errorf("GetCurrentBlock() = %d, want %d", block, tt.wantCurrent)
+			}
+
+			if gaps := parser.Gaps(); len(gaps) != tt.wantGaps {
+				t.Errorf("Gaps() = %+v, want %d gaps", gaps, tt.wantGaps)
+			}
+
+			if activity := parser.GetActivity(subscriber); len(activity) != 0 {
+				t.Fatalf("GetActivity() = %+v, want no activity without the receipts", activity)
+			}
+
+			blockchainQuerier.Failing = nil
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			if activity := parser.GetActivity(subscriber); len(activity) != 1 || activity[0].BlockNumber != 102 {
+				t.Errorf("GetActivity() = %+v, want the log of block 102", activity)
+			}
+
+			if gaps := parser.Gaps(); len(gaps) != 0 {
+				t.Errorf("Gaps() = %+v, want no gaps", gaps)
+			}
+		})
+	}
+}
+
+func TestParserDoesNotFillGapsWithAbandonedBlocks(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	tests := []struct {
+		name       string
+		parentHash string
+	}{
+		{name: "fork before the gap", parentHash: "0xb101"},
+		{name: "fork at the gap", parentHash: "0xa101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := make(map[int64]*blockchain.Block)
+			extendChain(chain, "a", 100, 104, map[int64][]blockchain.Transaction{102: {sampleBlock.Transactions[0]}})
+
+			canonical := chain[102]
+			delete(chain, 102)
+
+			blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(blockchainQuerier), WithGapRetryBackoff(time.Nanosecond, time.Nanosecond))
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			blockchainQuerier.LatestBlock = 104
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			// the node serves block 102 of an abandoned fork.
+			chain[102] = &blockchain.Block{
+				Number:       canonical.Number,
+				Hash:         "0xb102",
+				ParentHash:   tt.parentHash,
+				Transactions: canonical.Transactions,
+			}
+			parser.retryGaps()
+
+			if gaps := parser.Gaps(); len(gaps) != 1 || gaps[0].BlockNumber != 102 {
+				t.Fatalf("Gaps() = %+v, want block 102 retried", gaps)
+			}
+
+			if activity := parser.GetActivity(address); len(activity) != 0 {
+				t.Fatalf("GetActivity() = %+v, want no activity from the abandoned block", activity)
+			}
+
+			chain[102] = canonical
+			parser.retryGaps()
+
+			if gaps := parser.Gaps(); len(gaps) != 0 {
+				t.Errorf("Gaps() = %+v, want no gaps", gaps)
+			}
+
+			if activity := parser.GetActivity(address); len(activity) != 1 || activity[0].BlockHash != canonical.Hash {
+				t.Errorf("GetActivity() = %+v, want the activity of the canonical block 102", activity)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package blockparser
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// BloomStats counts how the logs bloom of scanned blocks was used to skip fetching
+// their receipts.
+type BloomStats struct {
+	// BlocksChecked is the number of blocks whose bloom was tested.
+	BlocksChecked uint64 `json:"blocksChecked"`
+	// BlocksSkipped is the number of blocks whose bloom ruled out any matching log.
+	BlocksSkipped uint64 `json:"blocksSkipped"`
+	// BloomHits is the number of blocks whose bloom may have contained a matching
+	// log, and whose receipts were fetched.
+	BloomHits uint64 `json:"bloomHits"`
+	// FalsePositives is the number of bloom hits without any matching log.
+	FalsePositives uint64 `json:"falsePositives"`
+}
+
+// HitRate returns the share of checked blocks whose receipts had to be fetched.
+func (s BloomStats) HitRate() float64 {
+	if s.BlocksChecked == 0 {
+		return 0
+	}
+
+	return float64(s.BloomHits) / float64(s.BlocksChecked)
+}
+
+// FalsePositiveRate returns the share of bloom hits that had no matching log.
+func (s BloomStats) FalsePositiveRate() float64 {
+	if s.BloomHits == 0 {
+		return 0
+	}
+
+	return float64(s.FalsePositives) / float64(s.BloomHits)
+}
+
+type bloomCounters struct {
+	blocksChecked  atomic.Uint64
+	blocksSkipped  atomic.Uint64
+	bloomHits      atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// BloomStats returns the logs bloom pre-filtering counters of the parser.
+func (p *Parser) BloomStats() BloomStats {
+	return BloomStats{
+		BlocksChecked:  p.bloomStats.blocksChecked.Load(),
+		BlocksSkipped:  p.bloomStats.blocksSkipped.Load(),
+		BloomHits:      p.bloomStats.bloomHits.Load(),
+		FalsePositives: p.bloomStats.falsePositives.Load(),
+	}
+}
+
+// logActivity returns the logs of the block that involve subscribed addresses keyed by the
+// canonical storage key of the addresses. a log involves an address when the address emitted
+// it or is one of its indexed topics. when topics are watched, only logs whose event
+// signature (the first topic) is watched are returned. the receipts of the block are only
+// fetched when its logs bloom may contain a matching log.
+func (p *Parser) logActivity(block *blockchain.Block, blockReceipts *blockReceipts,
+	watched *watchSet,
+) map[string][]Activity {
+	if !p.trackLogs || !blockReceipts.available() {
+		return nil
+	}
+
+	mayMatch, tested := p.bloomMayMatch(block, watched)
+	if !mayMatch {
+		return nil
+	}
+
+	receipts, ok := blockReceipts.get()
+	if !ok {
+		return nil
+	}
+
+	blockNumber, err := block.NumberUint64()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("invalid block %s: %v", block.Hash, err))
+		return nil
+	}
+
+	activity := make(map[string][]Activity)
+
+	for i := range receipts {
+		for j := range receipts[i].Logs {
+			keys := p.logAddresses(&receipts[i].Logs[j], watched)
+			if len(keys) == 0 {
+				continue
+			}
+
+			// the log is copied so the stored activity does not keep the receipts alive.
+			log := receipts[i].Logs[j]
+
+			for _, key := range keys {
+				activity[key] = append(activity[key], Activity{
+					Kind:        ActivityKindLog,
+					BlockNumber: blockNumber,
+					BlockHash:   block.Hash,
+					Log:         &log,
+				})
+			}
+		}
+	}
+
+	if tested && len(activity) == 0 {
+		p.bloomStats.falsePositives.Add(1)
+		p.logger.Debug(fmt.Sprintf("logs bloom of block %s was a false positive", block.Number))
+	}
+
+	return activity
+}
+
+// bloomMayMatch tests the logs bloom of the block for the subscribed addresses, as
+// emitters or indexed topics, and the watched topics. tested is false when the block
+// has no valid bloom, in which case it may always match.
+func (p *Parser) bloomMayMatch(block *blockchain.Block, watched *watchSet) (bool, bool) {
+	bloom, err := block.Bloom()
+	if err != nil {
+		p.logger.Warn(fmt.Sprintf("could not use logs bloom of block %s: %v", block.Number, err))
+		return true, false
+	}
+
+	p.bloomStats.blocksChecked.Add(1)
+
+	if bloomHasTopic(bloom, p.watchedTopics) && watched.inBloom(&bloom, true) {
+		p.bloomStats.bloomHits.Add(1)
+		return true, true
+	}
+
+	p.bloomStats.blocksSkipped.Add(1)
+
+	return false, true
+}
+
+// bloomHasTopic reports whether any of the topics may be in the bloom. no topics means
+// any event is watched.
+func bloomHasTopic(bloom blockchain.Bloom, topics map[[32]byte]bool) bool {
+	if len(topics) == 0 {
+		return true
+	}
+
+	for topic := range topics {
+		if bloom.TestTopic(topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logAddresses returns the storage keys of the subscribed addresses the log involves.
+func (p *Parser) logAddresses(log *blockchain.Log, watched *watchSet) []string {
+	if len(p.watchedTopics) > 0 {
+		if len(log.Topics) == 0 {
+			return nil
+		}
+
+		signature, err := blockchain.ParseTopic(log.Topics[0])
+		if err != nil || !p.watchedTopics[signature] {
+			return nil
+		}
+	}
+
+	keys := make([]string, 0)
+
+	if key, ok := watched.match(log.Address); ok {
+		keys = append(keys, key)
+	}
+
+	for _, value := range log.Topics {
+		topic, err := blockchain.ParseTopic(value)
+		if err != nil {
+			continue
+		}
+
+		// only topics left padded with zeros can be indexed addresses.
+		address := blockchain.BytesToAddress(topic[:])
+		if blockchain.AddressTopic(address) != topic {
+			continue
+		}
+
+		if key, ok := watched.lookup(address); ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// parseTopics parses the watched topics, logging the ones that are invalid.
+func parseTopics(topics []string, logger Logger) map[[32]byte]bool {
+	parsed := make(map[[32]byte]bool, len(topics))
+
+	for _, value := range topics {
+		topic, err := blockchain.ParseTopic(value)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("ignoring invalid watched topic %s: %v", value, err))
+			continue
+		}
+
+		parsed[topic] = true
+	}
+
+	return parsed
+}
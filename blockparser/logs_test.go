@@ -0,0 +1,144 @@
+package blockparser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+type MockReceiptsQuerier struct {
+	MockBlockchainQuerier
+	Receipts      []blockchain.Receipt
+	ReceiptsCalls int
+}
+
+func (m *MockReceiptsQuerier) GetBlockReceipts(_ string) ([]blockchain.Receipt, error) {
+	m.ReceiptsCalls++
+
+	return m.Receipts, nil
+}
+
+func TestParserTracksLogs(t *testing.T) {
+	subscriber := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	token := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+	transfer := blockchain.Log{
+		Address: token,
+		Topics: []string{
+			transferTopic,
+			"0x0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			"0x000000000000000000000000fb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		},
+		Data: "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}
+	approval := blockchain.Log{
+		Address: token,
+		Topics: []string{
+			"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+			"0x000000000000000000000000dbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+			"0x000000000000000000000000fb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		bloomLogs    []blockchain.Log
+		receiptLogs  []blockchain.Log
+		topics       []string
+		wantLogs     int
+		wantReceipts int
+		wantStats    BloomStats
+	}{
+		{
+			name:         "matching transfer",
+			bloomLogs:    []blockchain.Log{transfer},
+			receiptLogs:  []blockchain.Log{transfer, approval},
+			wantLogs:     1,
+			wantReceipts: 1,
+			wantStats:    BloomStats{BlocksChecked: 1, BloomHits: 1},
+		},
+		{
+			name:         "bloom rules out the block",
+			bloomLogs:    []blockchain.Log{approval},
+			receiptLogs:  []blockchain.Log{approval},
+			wantReceipts: 0,
+			wantStats:    BloomStats{BlocksChecked: 1, BlocksSkipped: 1},
+		},
+		{
+			name:         "false positive",
+			bloomLogs:    []blockchain.Log{transfer},
+			receiptLogs:  []blockchain.Log{approval},
+			wantReceipts: 1,
+			wantStats:    BloomStats{BlocksChecked: 1, BloomHits: 1, FalsePositives: 1},
+		},
+		{
+			name:         "event topic not watched",
+			bloomLogs:    []blockchain.Log{transfer},
+			receiptLogs:  []blockchain.Log{transfer},
+			topics:       []string{"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"},
+			wantReceipts: 0,
+			wantStats:    BloomStats{BlocksChecked: 1, BlocksSkipped: 1},
+		},
+		{
+			name:         "event topic watched",
+			bloomLogs:    []blockchain.Log{transfer},
+			receiptLogs:  []blockchain.Log{transfer},
+			topics:       []string{transferTopic},
+			wantLogs:     1,
+			wantReceipts: 1,
+			wantStats:    BloomStats{BlocksChecked: 1, BloomHits: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bloom, err := blockchain.CreateBloom(tt.bloomLogs)
+			if err != nil {
+				t.Fatalf("CreateBloom() error = %v", err)
+			}
+
+			block := sampleBlock
+			block.Transactions = nil
+			block.LogsBloom = bloom.String()
+
+			blockchainQuerier := &MockReceiptsQuerier{
+				MockBlockchainQuerier: MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block},
+				Receipts:              []blockchain.Receipt{{Logs: tt.receiptLogs}},
+			}
+
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(blockchainQuerier), WithLogTracking(tt.topics...))
+
+			if subscribed := parser.Subscribe(subscriber); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			activity := parser.GetActivity(subscriber)
+			if len(activity) != tt.wantLogs {
+				t.Fatalf("GetActivity() = %v, want %d logs", activity, tt.wantLogs)
+			}
+
+			for _, entry := range activity {
+				if entry.Kind != ActivityKindLog || entry.Log.Topics[0] != transferTopic {
+					t.Errorf("GetActivity() = %v, want the transfer log", entry)
+				}
+			}
+
+			if blockchainQuerier.ReceiptsCalls != tt.wantReceipts {
+				t.Errorf("receipts fetched %d times, want %d", blockchainQuerier.ReceiptsCalls, tt.wantReceipts)
+			}
+
+			if stats := parser.BloomStats(); stats != tt.wantStats {
+				t.Errorf("BloomStats() = %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
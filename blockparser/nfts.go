@@ -0,0 +1,234 @@
+package blockparser
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// NFTHolding is an amount of an NFT held by an address. ERC-721 tokens are held once.
+type NFTHolding struct {
+	Standard blockchain.NFTStandard `json:"standard"`
+	Contract string                 `json:"contract"`
+	TokenID  string                 `json:"tokenId"`
+	Amount   string                 `json:"amount"`
+}
+
+// nftActivity returns the ERC-721 and ERC-1155 transfers of the block sent or received by
+// subscribed addresses, keyed by the canonical storage key of the addresses. the transfer
+// logs are only fetched when the logs bloom of the block may contain a transfer involving a
+// subscribed address.
+func (p *Parser) nftActivity(block *blockchain.Block, transferLogs *blockTransferLogs,
+	watched *watchSet,
+) map[string][]Activity {
+	if !p.trackNFTs {
+		return nil
+	}
+
+	if !transferBloomMayMatch(block, watched, p.transferTopics()...) {
+		return nil
+	}
+
+	logs, ok := transferLogs.get()
+	if !ok {
+		return nil
+	}
+
+	blockNumber, err := block.NumberUint64()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("invalid block %s: %v", block.Hash, err))
+		return nil
+	}
+
+	activity := make(map[string][]Activity)
+
+	for i := range logs {
+		if logs[i].Removed || !blockchain.IsNFTTransferLog(logs[i]) {
+			continue
+		}
+
+		// ERC-20 transfers share the event signature of ERC-721 transfers and are skipped.
+		transfers, err := blockchain.ParseNFTTransfers(logs[i])
+		if err != nil {
+			p.logger.Debug(fmt.Sprintf("skipping transfer log %s of %s: %v", logs[i].LogIndex,
+				logs[i].TransactionHash, err))
+
+			continue
+		}
+
+		for j := range transfers {
+			transfer := &transfers[j]
+			stored := make(map[string]bool, 2)
+
+			for _, address := range []string{transfer.From, transfer.To} {
+				key, ok := watched.match(address)
+				if !ok || stored[key] {
+					continue
+				}
+
+				entry := Activity{
+					Kind:        ActivityKindNFTTransfer,
+					BlockNumber: blockNumber,
+					BlockHash:   block.Hash,
+					NFTTransfer: transfer,
+				}
+				entry.Direction, entry.Counterparty = direction(transfer.From, transfer.To, key)
+
+				stored[key] = true
+				activity[key] = append(activity[key], entry)
+			}
+		}
+	}
+
+	return activity
+}
+
+// GetNFTTransfers returns the ERC-721 and ERC-1155 transfers sent or received by an address.
+func (p *Parser) GetNFTTransfers(address string, opts ...QueryOption) []blockchain.NFTTransfer {
+	activity := p.GetActivity(address, opts...)
+	if activity == nil {
+		return nil
+	}
+
+	transfers := make([]blockchain.NFTTransfer, 0)
+
+	for _, entry := range activity {
+		if entry.Kind == ActivityKindNFTTransfer {
+			transfers = append(transfers, *entry.NFTTransfer)
+		}
+	}
+
+	return transfers
+}
+
+// GetNFTHoldings returns the NFTs held by an address, by contract and token id. holdings
+// are projected from the NFT transfers stored for the address, so tokens received before
+// the scanned blocks are not known.
+func (p *Parser) GetNFTHoldings(address string) []NFTHolding {
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return nil
+	}
+
+	return p.holdings.list(key)
+}
+
+type holdingKey struct {
+	contract string
+	tokenID  string
+}
+
+type holding struct {
+	standard blockchain.NFTStandard
+	amount   *big.Int
+}
+
+// holdings is the projection of the NFT transfers stored for each address into the amount
+// of each token the address holds.
+type holdings struct {
+	mu        sync.Mutex
+	byAddress map[string]map[holdingKey]*holding
+}
+
+func newHoldings() *holdings {
+	return &holdings{byAddress: make(map[string]map[holdingKey]*holding)}
+}
+
+// apply adds the NFTs received and subtracts the NFTs sent in the activity of an address
+// to its holdings, or the opposite when sign is negative to revert activity.
+func (h *holdings) apply(key string, activity []Activity, sign int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range activity {
+		if entry.NFTTransfer == nil || entry.Direction == DirectionSelf {
+			continue
+		}
+
+		amount, ok := new(big.Int).SetString(entry.NFTTransfer.Amount, 10)
+		if !ok {
+			continue
+		}
+
+		if (entry.Direction == DirectionOut) != (sign < 0) {
+			amount.Neg(amount)
+		}
+
+		tokens, ok := h.byAddress[key]
+		if !ok {
+			tokens = make(map[holdingKey]*holding)
+			h.byAddress[key] = tokens
+		}
+
+		token := holdingKey{contract: entry.NFTTransfer.Contract, tokenID: entry.NFTTransfer.TokenID}
+
+		held, ok := tokens[token]
+		if !ok {
+			held = &holding{standard: entry.NFTTransfer.Standard, amount: new(big.Int)}
+			tokens[token] = held
+		}
+
+		// the amount is negative while the transfers of a token received before the scanned
+		// blocks have not been backfilled.
+		if held.amount.Add(held.amount, amount).Sign() == 0 {
+			delete(tokens, token)
+		}
+	}
+}
+
+func (h *holdings) remove(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.byAddress, key)
+}
+
+// list returns the tokens held by the address, by contract and token id.
+func (h *holdings) list(key string) []NFTHolding {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := make([]NFTHolding, 0, len(h.byAddress[key]))
+
+	for token, held := range h.byAddress[key] {
+		if held.amount.Sign() <= 0 {
+			continue
+		}
+
+		list = append(list, NFTHolding{
+			Standard: held.standard,
+			Contract: token.contract,
+			TokenID:  token.tokenID,
+			Amount:   held.amount.String(),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Contract != list[j].Contract {
+			return list[i].Contract < list[j].Contract
+		}
+
+		return compareTokenIDs(list[i].TokenID, list[j].TokenID) < 0
+	})
+
+	return list
+}
+
+// compareTokenIDs compares decimal token ids by value.
+func compareTokenIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,141 @@
+package blockparser
+
+import (
+	"context"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// logsChainQuerier serves the blocks of a chain by number and their logs by block hash.
+type logsChainQuerier struct {
+	MockChainQuerier
+	Logs map[string][]blockchain.Log
+}
+
+func (m *logsChainQuerier) GetLogs(blockHash string, _ ...string) ([]blockchain.Log, error) {
+	return m.Logs[blockHash], nil
+}
+
+func TestParserTracksNFTHoldings(t *testing.T) {
+	const (
+		subscriber = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		other      = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+		erc721     = "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d"
+		erc1155    = "0x76be3b62873462d2142405439777e971754e8e77"
+	)
+
+	word := func(value string) string {
+		return strings.Repeat("0", 64-len(value)) + value
+	}
+	topic := func(value [32]byte) string {
+		return "0x" + hex.EncodeToString(value[:])
+	}
+	addressTopic := func(address string) string {
+		return "0x" + word(address[2:])
+	}
+
+	received721 := blockchain.Log{
+		Address: erc721,
+		Topics: []string{
+			topic(blockchain.TransferEventTopic), addressTopic(other), addressTopic(subscriber), "0x" + word("2a"),
+		},
+		TransactionHash: "0x01", LogIndex: "0x0",
+	}
+	sent721 := received721
+	sent721.Topics = []string{
+		topic(blockchain.TransferEventTopic), addressTopic(subscriber), addressTopic(other), "0x" + word("2a"),
+	}
+	sent721.TransactionHash = "0x04"
+	receivedBatch := blockchain.Log{
+		Address: erc1155,
+		Topics: []string{
+			topic(blockchain.TransferBatchEventTopic), addressTopic(other), addressTopic(other), addressTopic(subscriber),
+		},
+		Data: "0x" + word("40") + word("a0") + word("2") + word("1") + word("2") +
+			word("2") + word("a") + word("b"),
+		TransactionHash: "0x02", LogIndex: "0x0",
+	}
+	sentSingle := blockchain.Log{
+		Address: erc1155,
+		Topics: []string{
+			topic(blockchain.TransferSingleEventTopic), addressTopic(subscriber), addressTopic(subscriber),
+			addressTopic(other),
+		},
+		Data:            "0x" + word("1") + word("4"),
+		TransactionHash: "0x03", LogIndex: "0x0",
+	}
+	erc20 := blockchain.Log{
+		Address:         "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+		Topics:          []string{topic(blockchain.TransferEventTopic), addressTopic(other), addressTopic(subscriber)},
+		Data:            "0x" + word("1"),
+		TransactionHash: "0x05", LogIndex: "0x1",
+	}
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 100, 103, nil)
+
+	blockchainQuerier := &logsChainQuerier{
+		MockChainQuerier: MockChainQuerier{LatestBlock: 100, Blocks: chain},
+		Logs: map[string][]blockchain.Log{
+			"0xa101": {received721, erc20},
+			"0xa102": {receivedBatch},
+			"0xa103": {sentSingle},
+			"0xb103": {sent721},
+		},
+	}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier), WithNFTTracking())
+
+	if subscribed := parser.Subscribe(subscriber); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", subscriber, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	blockchainQuerier.LatestBlock = 103
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if transfers := parser.GetNFTTransfers(subscriber); len(transfers) != 4 {
+		t.Errorf("GetNFTTransfers() = %+v, want the erc-721 transfer and 3 erc-1155 transfers", transfers)
+	}
+
+	if transfers := parser.GetNFTTransfers(subscriber, OfToken(erc721)); len(transfers) != 1 ||
+		transfers[0].TokenID != "42" || transfers[0].Standard != blockchain.ERC721 {
+		t.Errorf("GetNFTTransfers() = %+v, want the erc-721 token 42", transfers)
+	}
+
+	want := []NFTHolding{
+		{Standard: blockchain.ERC1155, Contract: erc1155, TokenID: "1", Amount: "6"},
+		{Standard: blockchain.ERC1155, Contract: erc1155, TokenID: "2", Amount: "11"},
+		{Standard: blockchain.ERC721, Contract: erc721, TokenID: "42", Amount: "1"},
+	}
+	if holdings := parser.GetNFTHoldings(subscriber); !reflect.DeepEqual(holdings, want) {
+		t.Errorf("GetNFTHoldings() = %+v, want %+v", holdings, want)
+	}
+
+	// block 103 is replaced by a branch where the subscriber sent the erc-721 token instead.
+	extendChain(chain, "b", 103, 104, nil)
+
+	blockchainQuerier.LatestBlock = 104
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	want = []NFTHolding{
+		{Standard: blockchain.ERC1155, Contract: erc1155, TokenID: "1", Amount: "10"},
+		{Standard: blockchain.ERC1155, Contract: erc1155, TokenID: "2", Amount: "11"},
+	}
+	if holdings := parser.GetNFTHoldings(subscriber); !reflect.DeepEqual(holdings, want) {
+		t.Errorf("GetNFTHoldings() after reorg = %+v, want %+v", holdings, want)
+	}
+
+	if sent := parser.GetActivity(subscriber, InDirection(DirectionOut)); len(sent) != 1 ||
+		sent[0].NFTTransfer.TransactionHash != sent721.TransactionHash || sent[0].Counterparty != other {
+		t.Errorf("GetActivity() = %+v, want the erc-721 token sent in the canonical block 103", sent)
+	}
+}
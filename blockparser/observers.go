@@ -0,0 +1,309 @@
+package blockparser
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultObserverBuffer is the number of events buffered for an observer.
+const defaultObserverBuffer = 64
+
+// EventKind is the kind of an event published to observers.
+type EventKind string
+
+const (
+	// EventActivityMatched is activity of a subscribed address stored while scanning.
+	EventActivityMatched EventKind = "activity_matched"
+	// EventBlockScanned is a block whose activity was stored.
+	EventBlockScanned EventKind = "block_scanned"
+	// EventReorg is a chain reorganization whose abandoned activity was removed.
+	EventReorg EventKind = "reorg"
+	// EventError is a block that could not be scanned or a failure to query the chain.
+	EventError EventKind = "error"
+)
+
+// Event is published to observers as the parser follows the chain. only the fields
+// matching the kind of the event are set, ChainID is only set when the parser has one.
+type Event struct {
+	Kind        EventKind `json:"kind"`
+	ChainID     uint64    `json:"chainId,omitempty"`
+	Time        time.Time `json:"time"`
+	BlockNumber int64     `json:"blockNumber,omitempty"`
+	BlockHash   string    `json:"blockHash,omitempty"`
+	// Address and Activity are the subscribed address and its matched activity.
+	Address  string    `json:"address,omitempty"`
+	Activity *Activity `json:"activity,omitempty"`
+	// Reorg describes the rolled back blocks of a reorganization detected at BlockNumber.
+	Reorg *ReorgEvent `json:"reorg,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// ReorgEvent describes a chain reorganization.
+type ReorgEvent struct {
+	CommonAncestor  int64 `json:"commonAncestor"`
+	AbandonedBlocks int   `json:"abandonedBlocks"`
+	RemovedActivity int   `json:"removedActivity"`
+}
+
+// SlowConsumerPolicy is what happens to the events of an observer whose buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// DropEvents drops the events that do not fit in the buffer, they are counted by
+	// Observer.Dropped.
+	DropEvents SlowConsumerPolicy = iota
+	// BlockOnSlowConsumer waits until the buffer has room, which holds back scanning.
+	// only use it with observers that keep up with the chain.
+	BlockOnSlowConsumer
+	// DisconnectSlowConsumer closes the observer when its buffer is full.
+	DisconnectSlowConsumer
+)
+
+// ObserverOption configures an observer.
+type ObserverOption func(*observerConfig)
+
+type observerConfig struct {
+	addresses map[string]bool
+	kinds     map[EventKind]bool
+	buffer    int
+	policy    SlowConsumerPolicy
+}
+
+// ForAddresses only delivers the activity of the given addresses. events that are not
+// about an address (blocks scanned, reorganizations and errors) are still delivered.
+func ForAddresses(addresses ...string) ObserverOption {
+	return func(c *observerConfig) {
+		if c.addresses == nil {
+			c.addresses = make(map[string]bool, len(addresses))
+		}
+
+		for _, address := range addresses {
+			c.addresses[address] = true
+		}
+	}
+}
+
+// ForEvents only delivers events of the given kinds.
+func ForEvents(kinds ...EventKind) ObserverOption {
+	return func(c *observerConfig) {
+		if c.kinds == nil {
+			c.kinds = make(map[EventKind]bool, len(kinds))
+		}
+
+		for _, kind := range kinds {
+			c.kinds[kind] = true
+		}
+	}
+}
+
+// WithBuffer sets the number of events buffered for the observer.
+func WithBuffer(size int) ObserverOption {
+	return func(c *observerConfig) {
+		c.buffer = size
+	}
+}
+
+// OnSlowConsumer sets what happens to events when the buffer of the observer is full. it
+// defaults to DropEvents.
+func OnSlowConsumer(policy SlowConsumerPolicy) ObserverOption {
+	return func(c *observerConfig) {
+		c.policy = policy
+	}
+}
+
+// Observer receives the events published by the parser until it is closed.
+type Observer struct {
+	config observerConfig
+	events chan Event
+	done   chan struct{}
+	// mu is held to send events and to close the events channel, so an event is never sent
+	// on the channel of a closed observer.
+	mu      sync.RWMutex
+	once    sync.Once
+	dropped atomic.Uint64
+	// disconnected is set when the observer was closed for being too slow.
+	disconnected atomic.Bool
+	registry     *observers
+}
+
+// Events returns the channel the events are delivered on. it is closed when the observer is closed.
+func (o *Observer) Events() <-chan Event {
+	return o.events
+}
+
+// Dropped returns the number of events dropped because the buffer of the observer was full.
+func (o *Observer) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// Disconnected reports whether the observer was closed because its buffer was full.
+func (o *Observer) Disconnected() bool {
+	return o.disconnected.Load()
+}
+
+// Close unregisters the observer and closes its events channel. it is safe to call more than once.
+func (o *Observer) Close() {
+	o.once.Do(func() {
+		// unblock a publisher waiting for room in the buffer before unregistering.
+		close(o.done)
+		o.registry.remove(o)
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		close(o.events)
+	})
+}
+
+func (o *Observer) wants(event Event) bool {
+	if o.config.kinds != nil && !o.config.kinds[event.Kind] {
+		return false
+	}
+
+	return event.Address == "" || o.config.addresses == nil || o.config.addresses[event.Address]
+}
+
+// deliver sends the event to the observer following its slow consumer policy. it returns
+// false when the observer must be disconnected.
+func (o *Observer) deliver(event Event) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	select {
+	case <-o.done:
+		return true
+	default:
+	}
+
+	switch o.config.policy {
+	case BlockOnSlowConsumer:
+		select {
+		case o.events <- event:
+		case <-o.done:
+		}
+	case DisconnectSlowConsumer:
+		select {
+		case o.events <- event:
+		default:
+			return false
+		}
+	default:
+		select {
+		case o.events <- event:
+		default:
+			o.dropped.Add(1)
+		}
+	}
+
+	return true
+}
+
+// observers are the observers registered with the parser. events are sent to a snapshot of
+// the observers taken under the lock, so an observer blocking the publisher does not block
+// the others from being added or closed.
+type observers struct {
+	mu   sync.RWMutex
+	list []*Observer
+}
+
+func (r *observers) add(observer *Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.list = append(r.list, observer)
+}
+
+func (r *observers) remove(observer *Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, registered := range r.list {
+		if registered == observer {
+			r.list = append(r.list[:i], r.list[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *observers) publish(event Event) {
+	r.mu.RLock()
+	list := slices.Clone(r.list)
+	r.mu.RUnlock()
+
+	slow := make([]*Observer, 0)
+
+	for _, observer := range list {
+		if observer.wants(event) && !observer.deliver(event) {
+			slow = append(slow, observer)
+		}
+	}
+
+	for _, observer := range slow {
+		observer.disconnected.Store(true)
+		observer.Close()
+	}
+}
+
+// Observe registers an observer of the events published by the parser as it follows the
+// chain: activity matched for subscribed addresses, blocks scanned, reorganizations and
+// errors. activity found by backfills and range scans is not published. the observer must
+// be closed once it is not used anymore.
+func (p *Parser) Observe(opts ...ObserverOption) *Observer {
+	config := observerConfig{buffer: defaultObserverBuffer}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.addresses != nil {
+		keys := make(map[string]bool, len(config.addresses))
+
+		for address := range config.addresses {
+			if key, ok := p.resolveAddress(address); ok {
+				keys[key] = true
+			}
+		}
+
+		config.addresses = keys
+	}
+
+	observer := &Observer{
+		config:   config,
+		events:   make(chan Event, max(config.buffer, 0)),
+		done:     make(chan struct{}),
+		registry: p.observers,
+	}
+	p.observers.add(observer)
+
+	return observer
+}
+
+// OnEvent calls the handler with the events published by the parser, one at a time, from a
+// separate goroutine. see Observe. calling the returned function unregisters the handler,
+// the events still buffered are not handled.
+func (p *Parser) OnEvent(handler func(Event), opts ...ObserverOption) func() {
+	observer := p.Observe(opts...)
+
+	go func() {
+		for event := range observer.Events() {
+			select {
+			case <-observer.done:
+				return
+			default:
+			}
+
+			handler(event)
+		}
+	}()
+
+	return observer.Close
+}
+
+// publish sends the event to the observers of the parser.
+func (p *Parser) publish(event Event) {
+	event.ChainID = p.chainID
+	event.Time = time.Now().UTC()
+	p.observers.publish(event)
+}
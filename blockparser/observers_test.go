@@ -0,0 +1,234 @@
+package blockparser
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// receive returns the events received by the observer until none is received for a while.
+func receive(observer *Observer) []Event {
+	events := make([]Event, 0)
+
+	for {
+		select {
+		case event, ok := <-observer.Events():
+			if !ok {
+				return events
+			}
+
+			events = append(events, event)
+		case <-time.After(50 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func eventKinds(events []Event) []EventKind {
+	kinds := make([]EventKind, 0, len(events))
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+
+	return kinds
+}
+
+func TestParserPublishesEvents(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	recipient := sampleBlock.Transactions[0].To
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 3, map[int64][]blockchain.Transaction{2: {sampleBlock.Transactions[0]}})
+
+	blockchainQuerier := &MockChainQuerier{LatestBlock: 0, Blocks: chain}
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	for _, subscribed := range []string{address, recipient} {
+		if ok := parser.Subscribe(subscribed); !ok {
+			t.Fatalf("should subscribe address %s; got %v, want true", subscribed, ok)
+		}
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	all := parser.Observe()
+	defer all.Close()
+
+	matched := parser.Observe(ForAddresses(recipient), ForEvents(EventActivityMatched))
+	defer matched.Close()
+
+	// block 3 is replaced by a block of another branch, revealing a reorganization.
+	blockchainQuerier.LatestBlock = 3
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	chain[3] = &blockchain.Block{Number: "0x3", Hash: "0xb3", ParentHash: "0xb2"}
+	chain[2] = &blockchain.Block{Number: "0x2", Hash: "0xb2", ParentHash: "0xa1"}
+	blockchainQuerier.LatestBlock = 4
+	chain[4] = &blockchain.Block{Number: "0x4", Hash: "0xb4", ParentHash: "0xb3"}
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	wantKinds := []EventKind{
+		EventBlockScanned, EventActivityMatched, EventActivityMatched, EventBlockScanned, EventBlockScanned,
+		EventReorg, EventBlockScanned, EventBlockScanned, EventBlockScanned,
+	}
+
+	events := receive(all)
+	if kinds := eventKinds(events); !reflect.DeepEqual(kinds, wantKinds) {
+		t.Fatalf("Observe() events = %v, want %v", kinds, wantKinds)
+	}
+
+	if reorg := events[5].Reorg; events[5].BlockNumber != 4 || reorg.CommonAncestor != 1 ||
+		reorg.AbandonedBlocks != 2 || reorg.RemovedActivity != 2 {
+		t.Errorf("reorg event = %+v %+v, want a reorg at block 4 back to block 1", events[5], reorg)
+	}
+
+	events = receive(matched)
+	if len(events) != 1 || events[0].Address != recipient || events[0].Activity.Direction != DirectionIn {
+		t.Errorf("Observe(ForAddresses()) events = %+v, want the inbound transaction of %s", events, recipient)
+	}
+}
+
+func TestObserverSlowConsumerPolicies(t *testing.T) {
+	event := Event{Kind: EventBlockScanned, BlockNumber: 1}
+
+	t.Run("drop", func(t *testing.T) {
+		parser := NewBlockParser(WithBlockchainQuerier(&MockBlockchainQuerier{}))
+		observer := parser.Observe(WithBuffer(1))
+
+		parser.publish(event)
+		parser.publish(event)
+
+		if events := receive(observer); len(events) != 1 || observer.Dropped() != 1 {
+			t.Errorf("received %d events and dropped %d, want 1 and 1", len(events), observer.Dropped())
+		}
+
+		observer.Close()
+		observer.Close()
+
+		if _, ok := <-observer.Events(); ok {
+			t.Errorf("Events() is open after Close()")
+		}
+
+		// publishing after the observer is closed does not panic.
+		parser.publish(event)
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		parser := NewBlockParser(WithBlockchainQuerier(&MockBlockchainQuerier{}))
+		observer := parser.Observe(WithBuffer(1), OnSlowConsumer(DisconnectSlowConsumer))
+
+		parser.publish(event)
+		parser.publish(event)
+		parser.publish(event)
+
+		if events := receive(observer); len(events) != 1 || !observer.Disconnected() {
+			t.Errorf("received %d events, disconnected %v, want 1 event and a disconnection",
+				len(events), observer.Disconnected())
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		parser := NewBlockParser(WithBlockchainQuerier(&MockBlockchainQuerier{}))
+		observer := parser.Observe(WithBuffer(1), OnSlowConsumer(BlockOnSlowConsumer))
+
+		published := make(chan struct{})
+
+		go func() {
+			defer close(published)
+
+			for range 3 {
+				parser.publish(event)
+			}
+		}()
+
+		select {
+		case <-published:
+			t.Fatalf("publish() did not wait for the observer")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		if events := receive(observer); len(events) != 3 || observer.Dropped() != 0 {
+			t.Errorf("received %d events and dropped %d, want 3 and 0", len(events), observer.Dropped())
+		}
+
+		<-published
+
+		// closing unblocks a waiting publisher.
+		go parser.publish(event)
+		go parser.publish(event)
+		time.Sleep(10 * time.Millisecond)
+		observer.Close()
+	})
+
+	t.Run("stalled observer does not block closing others", func(t *testing.T) {
+		parser := NewBlockParser(WithBlockchainQuerier(&MockBlockchainQuerier{}))
+		stalled := parser.Observe(WithBuffer(0), OnSlowConsumer(BlockOnSlowConsumer))
+		other := parser.Observe()
+
+		go parser.publish(event)
+		time.Sleep(10 * time.Millisecond)
+
+		closed := make(chan struct{})
+
+		go func() {
+			defer close(closed)
+
+			other.Close()
+			parser.Observe().Close()
+		}()
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Errorf("Close() waited for the stalled observer")
+		}
+
+		stalled.Close()
+	})
+}
+
+func TestParserOnEvent(t *testing.T) {
+	parser := NewBlockParser(WithBlockchainQuerier(&MockBlockchainQuerier{}))
+
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+
+	received := make(chan struct{}, 1)
+
+	unregister := parser.OnEvent(func(event Event) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+
+		received <- struct{}{}
+	}, ForEvents(EventError))
+
+	parser.publish(Event{Kind: EventBlockScanned, BlockNumber: 1})
+	parser.publish(Event{Kind: EventError, Error: "boom"})
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatalf("OnEvent() handler was not called")
+	}
+
+	unregister()
+	parser.publish(Event{Kind: EventError, Error: "after unregister"})
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(events) != 1 || events[0].Error != "boom" {
+		t.Errorf("OnEvent() handled %+v, want the error event only", events)
+	}
+}
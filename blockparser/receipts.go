@@ -0,0 +1,100 @@
+package blockparser
+
+import (
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// blockReceipts fetches the receipts of a block at most once, on behalf of all the
+// matchers of the block that need them.
+type blockReceipts struct {
+	parser   *Parser
+	block    *blockchain.Block
+	receipts []blockchain.Receipt
+	fetched  bool
+	ok       bool
+	// err is the error fetching the receipts, the activity of the block is then incomplete.
+	err error
+}
+
+func (p *Parser) newBlockReceipts(block *blockchain.Block) *blockReceipts {
+	return &blockReceipts{parser: p, block: block}
+}
+
+// get returns the receipts of the block, fetching them on first use. ok is false when
+// the blockchain querier cannot fetch receipts or fetching them failed, which sets err.
+func (r *blockReceipts) get() ([]blockchain.Receipt, bool) {
+	if r.fetched {
+		return r.receipts, r.ok
+	}
+
+	r.fetched = true
+
+	receiptsQuerier, ok := r.parser.receiptsQuerier()
+	if !ok {
+		return nil, false
+	}
+
+	var (
+		receipts []blockchain.Receipt
+		err      error
+	)
+
+	// the block is already fetched, a querier verifying receipts checks them against it.
+	if fetchedQuerier, ok := receiptsQuerier.(FetchedBlockReceiptsQuerier); ok {
+		receipts, err = fetchedQuerier.GetReceiptsOfBlock(r.block)
+	} else {
+		receipts, err = receiptsQuerier.GetBlockReceipts(r.block.Number)
+	}
+
+	if err != nil {
+		r.err = fmt.Errorf("error fetching receipts of block %s: %w", r.block.Number, err)
+		return nil, false
+	}
+
+	r.receipts, r.ok = receipts, true
+
+	return r.receipts, r.ok
+}
+
+// available reports whether the receipts can be fetched without fetching them.
+func (r *blockReceipts) available() bool {
+	if r.fetched {
+		return r.ok
+	}
+
+	_, ok := r.parser.receiptsQuerier()
+
+	return ok
+}
+
+// receiptsQuerier returns the blockchain querier when it can fetch receipts. a
+// VerifyingQuerier can only fetch them when the querier it wraps can.
+func (p *Parser) receiptsQuerier() (ReceiptsQuerier, bool) {
+	if verifying, ok := p.blockchainQuerier.(*VerifyingQuerier); ok {
+		if _, ok := verifying.querier.(ReceiptsQuerier); !ok {
+			return nil, false
+		}
+	}
+
+	receiptsQuerier, ok := p.blockchainQuerier.(ReceiptsQuerier)
+
+	return receiptsQuerier, ok
+}
+
+// find returns the receipt of the transaction.
+func (r *blockReceipts) find(transactionHash string) (*blockchain.Receipt, bool) {
+	receipts, ok := r.get()
+	if !ok {
+		return nil, false
+	}
+
+	for i := range receipts {
+		if receipts[i].TransactionHash == transactionHash {
+			return &receipts[i], true
+		}
+	}
+
+	return nil, false
+}
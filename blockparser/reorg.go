@@ -0,0 +1,190 @@
+package blockparser
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// defaultReorgWindow is the number of recently scanned block hashes kept to detect
+// chain reorganizations. reorganizations deeper than the window cannot be fully rolled back.
+const defaultReorgWindow = 64
+
+// blockWindow is a sliding window of the hashes of the most recently scanned blocks.
+type blockWindow struct {
+	mu     sync.Mutex
+	size   int64
+	hashes map[int64]string
+}
+
+func newBlockWindow(size int) *blockWindow {
+	if size <= 0 {
+		size = defaultReorgWindow
+	}
+
+	return &blockWindow{size: int64(size), hashes: make(map[int64]string, size)}
+}
+
+// add records the hash of a scanned block, evicting the blocks that fall out of the window.
+func (w *blockWindow) add(number int64, hash string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.hashes[number] = hash
+
+	for recorded := range w.hashes {
+		if recorded <= number-w.size {
+			delete(w.hashes, recorded)
+		}
+	}
+}
+
+func (w *blockWindow) hash(number int64) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hash, ok := w.hashes[number]
+
+	return hash, ok
+}
+
+// snapshot returns the hashes of the blocks in the window by block number, nil when it is empty.
+func (w *blockWindow) snapshot() map[int64]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.hashes) == 0 {
+		return nil
+	}
+
+	return maps.Clone(w.hashes)
+}
+
+// truncate forgets the blocks after the given block and returns their hashes.
+func (w *blockWindow) truncate(after int64) map[string]bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	removed := make(map[string]bool)
+
+	for number, hash := range w.hashes {
+		if number > after {
+			removed[hash] = true
+
+			delete(w.hashes, number)
+		}
+	}
+
+	return removed
+}
+
+func (w *blockWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	clear(w.hashes)
+}
+
+// detectReorg checks that the block builds on the previously scanned block. when it does
+// not, the chain was reorganized: the activity stored from the abandoned blocks is removed
+// and the number of the last block the scanned and canonical chains have in common is
+// returned so the canonical branch can be rescanned from there. nothing is removed when the
+// common ancestor cannot be found because a block could not be fetched, the error is returned
+// so the block is scanned again later.
+func (p *Parser) detectReorg(blockNumber int64, block *blockchain.Block) (int64, bool, error) {
+	parentHash, ok := p.recentBlocks.hash(blockNumber - 1)
+	if !ok || block.Hash == "" || block.ParentHash == parentHash {
+		return 0, false, nil
+	}
+
+	ancestor, err := p.findCommonAncestor(blockNumber - 1)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not find the common ancestor of reorganized block %d: %w", blockNumber, err)
+	}
+
+	if ancestor == blockNumber-1 {
+		// the previous block is still canonical, the node returned a block of a branch
+		// it has not switched to (yet). rolling back would not change anything.
+		p.logger.Warn(fmt.Sprintf("block %d %s does not build on the canonical block %d %s",
+			blockNumber, block.Hash, ancestor, parentHash))
+
+		return 0, false, nil
+	}
+
+	abandoned := p.recentBlocks.truncate(ancestor)
+	removed := p.removeBlockActivity(abandoned)
+
+	p.logger.Warn(fmt.Sprintf(
+		"chain reorganization detected at block %d: %d blocks abandoned after common ancestor %d, "+
+			"%d activity entries removed", blockNumber, len(abandoned), ancestor, removed))
+	p.publish(Event{
+		Kind:        EventReorg,
+		BlockNumber: blockNumber,
+		BlockHash:   block.Hash,
+		Reorg:       &ReorgEvent{CommonAncestor: ancestor, AbandonedBlocks: len(abandoned), RemovedActivity: removed},
+	})
+
+	return ancestor, true, nil
+}
+
+// findCommonAncestor walks back from the given block until the hash of a scanned block
+// matches the hash of the canonical block with the same number. it stops at the first block
+// that cannot be fetched, as the blocks before it cannot be told apart from abandoned ones.
+func (p *Parser) findCommonAncestor(from int64) (int64, error) {
+	for number := from; ; number-- {
+		scannedHash, ok := p.recentBlocks.hash(number)
+		if !ok {
+			p.logger.Error(fmt.Sprintf(
+				"chain reorganization is deeper than the %d scanned blocks kept, activity before block %d "+
+					"may be from abandoned blocks", p.recentBlocks.size, number+1))
+
+			return number, nil
+		}
+
+		canonical, err := p.getBlockByNumber(number)
+		if err != nil {
+			return 0, err
+		}
+
+		if canonical.Hash == scannedHash {
+			return number, nil
+		}
+	}
+}
+
+// removeBlockActivity removes the activity stored from the given blocks, reverting the NFT
+// holdings it changed, and returns the number of entries removed.
+func (p *Parser) removeBlockActivity(blockHashes map[string]bool) int {
+	removed := 0
+
+	for _, key := range p.datastore.GetKeys() {
+		abandoned := make([]Activity, 0)
+
+		err := p.datastore.Update(key, func(activity []Activity) []Activity {
+			kept := make([]Activity, 0, len(activity))
+
+			for _, entry := range activity {
+				if blockHashes[entry.BlockHash] {
+					abandoned = append(abandoned, entry)
+					continue
+				}
+
+				kept = append(kept, entry)
+			}
+
+			return kept
+		})
+		if err != nil {
+			p.logger.Error(fmt.Sprintf("error removing abandoned activity of address %s: %v", key, err))
+			continue
+		}
+
+		removed += len(abandoned)
+		// the holdings of the address are rolled back to before the abandoned blocks.
+		p.holdings.apply(key, abandoned, -1)
+	}
+
+	return removed
+}
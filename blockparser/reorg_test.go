@@ -0,0 +1,188 @@
+package blockparser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// MockChainQuerier serves the blocks of a chain by number.
+type MockChainQuerier struct {
+	LatestBlock int64
+	Blocks      map[int64]*blockchain.Block
+}
+
+func (m *MockChainQuerier) GetLatestBlock() (string, error) {
+	return blockchain.Quantity(m.LatestBlock).String(), nil
+}
+
+func (m *MockChainQuerier) GetBlock(blockNumber string) (*blockchain.Block, error) {
+	number, err := blockchain.ParseQuantity(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	block, ok := m.Blocks[int64(number)]
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", blockNumber)
+	}
+
+	return block, nil
+}
+
+// extendChain adds blocks from..to on top of the chain, the blocks of the branch having
+// hashes derived from the branch name. transactions are included in the block with their number.
+func extendChain(chain map[int64]*blockchain.Block, branch string, from, to int64,
+	transactions map[int64][]blockchain.Transaction,
+) {
+	for number := from; number <= to; number++ {
+		parentHash := ""
+		if parent, ok := chain[number-1]; ok {
+			parentHash = parent.Hash
+		}
+
+		chain[number] = &blockchain.Block{
+			Number:       blockchain.Quantity(number).String(),
+			Hash:         fmt.Sprintf("0x%s%d", branch, number),
+			ParentHash:   parentHash,
+			Transactions: transactions[number],
+		}
+	}
+}
+
+func TestParserRollsBackReorganizedBlocks(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	orphaned := sampleBlock.Transactions[0]
+	canonical := sampleBlock.Transactions[1]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 100, 103, map[int64][]blockchain.Transaction{102: {orphaned}})
+
+	blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	blockchainQuerier.LatestBlock = 103
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	if transactions := parser.GetTransactions(address); len(transactions) != 1 || transactions[0].Hash != orphaned.Hash {
+		t.Fatalf("GetTransactions() = %v, want the transaction of block 102", transactions)
+	}
+
+	// blocks 102 and 103 are replaced by a branch where the address sent another
+	// transaction in block 103.
+	extendChain(chain, "b", 102, 104, map[int64][]blockchain.Transaction{103: {canonical}})
+
+	blockchainQuerier.LatestBlock = 104
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	transactions := parser.GetTransactions(address)
+	if len(transactions) != 1 || transactions[0].Hash != canonical.Hash {
+		t.Fatalf("GetTransactions() = %v, want only the transaction of the canonical block 103", transactions)
+	}
+
+	activity := parser.GetActivity(address)
+	if activity[0].BlockHash != "0xb103" {
+		t.Errorf("activity block hash = %s, want 0xb103", activity[0].BlockHash)
+	}
+
+	if block := parser.GetCurrentBlock(); block != 104 {
+		t.Errorf("GetCurrentBlock() = %d, want 104", block)
+	}
+}
+
+func TestParserKeepsActivityWhenCommonAncestorCannotBeFetched(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(chain map[int64]*blockchain.Block, number int64)
+	}{
+		{name: "block not found", remove: func(chain map[int64]*blockchain.Block, number int64) { delete(chain, number) }},
+		{name: "null block", remove: func(chain map[int64]*blockchain.Block, number int64) { chain[number] = nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := sampleBlock.Transactions[0].From
+
+			chain := make(map[int64]*blockchain.Block)
+			extendChain(chain, "a", 100, 103, map[int64][]blockchain.Transaction{102: {sampleBlock.Transactions[0]}})
+
+			blockchainQuerier := &MockChainQuerier{LatestBlock: 100, Blocks: chain}
+
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(blockchainQuerier))
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			blockchainQuerier.LatestBlock = 103
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			// block 103 is replaced and block 102, still canonical, cannot be fetched.
+			extendChain(chain, "b", 103, 104, nil)
+
+			canonical := chain[102]
+			tt.remove(chain, 102)
+
+			blockchainQuerier.LatestBlock = 104
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			if transactions := parser.GetTransactions(address); len(transactions) != 1 {
+				t.Fatalf("GetTransactions() = %v, want the transaction of block 102 kept", transactions)
+			}
+
+			if block := parser.GetCurrentBlock(); block != 103 {
+				t.Fatalf("GetCurrentBlock() = %d, want 103 until the common ancestor is found", block)
+			}
+
+			chain[102] = canonical
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			if transactions := parser.GetTransactions(address); len(transactions) != 1 {
+				t.Errorf("GetTransactions() = %v, want the transaction of block 102 kept", transactions)
+			}
+
+			if hash, _ := parser.recentBlocks.hash(103); hash != "0xb103" {
+				t.Errorf("recent block 103 = %q, want 0xb103", hash)
+			}
+
+			if block := parser.GetCurrentBlock(); block != 104 {
+				t.Errorf("GetCurrentBlock() = %d, want 104", block)
+			}
+		})
+	}
+}
+
+func TestBlockWindowEvictsOldBlocks(t *testing.T) {
+	window := newBlockWindow(3)
+
+	for number := int64(1); number <= 5; number++ {
+		window.add(number, fmt.Sprintf("0x%d", number))
+	}
+
+	for number, want := range map[int64]bool{2: false, 3: true, 5: true} {
+		if _, ok := window.hash(number); ok != want {
+			t.Errorf("hash(%d) found = %v, want %v", number, ok, want)
+		}
+	}
+
+	if removed := window.truncate(3); len(removed) != 2 || !removed["0x4"] || !removed["0x5"] {
+		t.Errorf("truncate(3) = %v, want blocks 4 and 5", removed)
+	}
+}
@@ -0,0 +1,122 @@
+package blockparser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestParserScanRange(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	transaction := sampleBlock.Transactions[0]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 20, map[int64][]blockchain.Transaction{5: {transaction}, 12: {transaction}})
+	// block 8 is not found.
+	chain[8] = &blockchain.Block{}
+
+	tests := []struct {
+		name        string
+		from, to    int64
+		opts        []ConfigOptionResolver
+		wantErr     error
+		wantScanned int64
+		wantMatches int
+		wantErrors  []int64
+	}{
+		{name: "range", from: 1, to: 7, wantScanned: 7, wantMatches: 1},
+		{name: "failed block", from: 1, to: 20, wantScanned: 20, wantMatches: 2, wantErrors: []int64{8}},
+		{
+			name: "halt on failed block", from: 1, to: 20, wantScanned: 7, wantMatches: 1,
+			opts:    []ConfigOptionResolver{WithFailedBlockPolicy(HaltOnFailedBlock)},
+			wantErr: ErrBlockNotFound,
+		},
+		{name: "invalid range", from: 10, to: 9, wantErr: ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ConfigOptionResolver{
+				WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(&MockChainQuerier{LatestBlock: 20, Blocks: chain}),
+			}, tt.opts...)
+			parser := NewBlockParser(opts...)
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			for range 2 {
+				summary, err := parser.ScanRange(context.Background(), tt.from, tt.to)
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+					t.Fatalf("ScanRange() error = %v, want %v", err, tt.wantErr)
+				}
+
+				if summary.BlocksScanned != tt.wantScanned {
+					t.Errorf("ScanRange() blocks scanned = %d, want %d", summary.BlocksScanned, tt.wantScanned)
+				}
+
+				if tt.wantScanned > 0 && summary.Duration <= 0 {
+					t.Errorf("ScanRange() duration = %s, want the time the scan took", summary.Duration)
+				}
+
+				failed := make([]int64, 0)
+				for _, blockError := range summary.Errors {
+					failed = append(failed, blockError.BlockNumber)
+				}
+
+				if len(failed) != len(tt.wantErrors) || len(failed) > 0 && !reflect.DeepEqual(failed, tt.wantErrors) {
+					t.Errorf("ScanRange() errors = %v, want %v", summary.Errors, tt.wantErrors)
+				}
+			}
+
+			// the second scan of the range does not duplicate activity.
+			if activity := parser.GetActivity(address); len(activity) != tt.wantMatches {
+				t.Errorf("GetActivity() = %d entries, want %d", len(activity), tt.wantMatches)
+			}
+
+			if parser.GetCurrentBlock() != 0 {
+				t.Errorf("GetCurrentBlock() = %d, want 0", parser.GetCurrentBlock())
+			}
+		})
+	}
+}
+
+func TestParserScansBetweenStartAndEndBlocks(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	transaction := sampleBlock.Transactions[0]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 20, map[int64][]blockchain.Transaction{
+		4: {transaction}, 5: {transaction}, 12: {transaction}, 18: {transaction},
+	})
+
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(&MockChainQuerier{LatestBlock: 20, Blocks: chain}),
+		WithStartBlock(5), WithEndBlock(12))
+
+	if subscribed := parser.Subscribe(address); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	parser.querySubscribedAddressTransactions(context.Background())
+
+	blocks := make([]uint64, 0)
+	for _, entry := range parser.GetActivity(address) {
+		blocks = append(blocks, entry.BlockNumber)
+	}
+
+	if !reflect.DeepEqual(blocks, []uint64{5, 12}) {
+		t.Errorf("GetActivity() blocks = %v, want [5 12]", blocks)
+	}
+
+	if parser.GetCurrentBlock() != 12 || !parser.reachedEndBlock() {
+		t.Errorf("GetCurrentBlock() = %d, want the end block 12", parser.GetCurrentBlock())
+	}
+}
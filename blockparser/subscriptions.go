@@ -0,0 +1,363 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrInvalidTTL = errors.New("invalid subscription ttl")
+
+// SubscriptionStatus is whether the activity of a subscribed address is being recorded.
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive SubscriptionStatus = "active"
+	// SubscriptionPaused is a subscription whose address is not matched while scanning.
+	// its history is kept and matching resumes when the subscription is resumed.
+	SubscriptionPaused SubscriptionStatus = "paused"
+)
+
+// HistoryPolicy is what happens to the stored activity of an address when it is unsubscribed.
+type HistoryPolicy int
+
+const (
+	// KeepHistory keeps the activity of unsubscribed addresses, it can still be queried
+	// and is extended again if the address is subscribed again.
+	KeepHistory HistoryPolicy = iota
+	// PurgeHistory deletes the activity of an address when it is unsubscribed.
+	PurgeHistory
+)
+
+// Subscription is an address whose activity the parser records.
+type Subscription struct {
+	Address   string             `json:"address"`
+	Status    SubscriptionStatus `json:"status"`
+	CreatedAt time.Time          `json:"createdAt"`
+	// LastActivityAt is when activity of the address was last stored.
+	LastActivityAt *time.Time `json:"lastActivityAt,omitempty"`
+	// ExpiresAt is when the address is unsubscribed, nil when the subscription does not expire.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// SubscriptionOption sets a property of a subscription when subscribing or updating it.
+type SubscriptionOption func(*subscriptionUpdate)
+
+type subscriptionUpdate struct {
+	paused *bool
+	ttl    *time.Duration
+}
+
+// Paused pauses or resumes a subscription.
+func Paused(paused bool) SubscriptionOption {
+	return func(u *subscriptionUpdate) {
+		u.paused = &paused
+	}
+}
+
+// ExpiresAfter makes a subscription expire after the given time from now. a ttl of 0
+// makes the subscription never expire, a negative ttl is rejected with ErrInvalidTTL.
+func ExpiresAfter(ttl time.Duration) SubscriptionOption {
+	return func(u *subscriptionUpdate) {
+		u.ttl = &ttl
+	}
+}
+
+// subscriptions are the subscriptions of the parser by the canonical storage key of their address.
+type subscriptions struct {
+	mu           sync.RWMutex
+	byKey        map[string]*Subscription
+	timeProvider func() time.Time
+	// watch is the snapshot of the addresses matched while scanning. changes to the
+	// subscriptions mark it stale, it is rebuilt once on the next read.
+	watch     atomic.Pointer[watchSet]
+	stale     atomic.Bool
+	prefilter bool
+}
+
+func newSubscriptions(prefilter bool) *subscriptions {
+	s := &subscriptions{
+		byKey:        make(map[string]*Subscription),
+		timeProvider: time.Now,
+		prefilter:    prefilter,
+	}
+	s.watch.Store(newWatchSet(s.byKey, s.timeProvider(), prefilter))
+
+	return s
+}
+
+// watched returns the snapshot of the addresses matched while scanning: subscribed, not
+// paused and whose subscription has not expired. reading it does not lock the subscriptions
+// unless they changed or one of them expired since it was built.
+func (s *subscriptions) watched() *watchSet {
+	if set := s.watch.Load(); !s.outdated(set) {
+		return set
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if set := s.watch.Load(); !s.outdated(set) {
+		return set
+	}
+
+	s.stale.Store(false)
+
+	set := newWatchSet(s.byKey, s.timeProvider(), s.prefilter)
+	s.watch.Store(set)
+
+	return set
+}
+
+func (s *subscriptions) outdated(set *watchSet) bool {
+	return s.stale.Load() || (!set.expiresAt.IsZero() && !s.timeProvider().Before(set.expiresAt))
+}
+
+// validateSubscriptionOptions returns an error wrapping ErrInvalidTTL when the ttl set by
+// the options is negative.
+func validateSubscriptionOptions(opts []SubscriptionOption) error {
+	var update subscriptionUpdate
+
+	for _, opt := range opts {
+		opt(&update)
+	}
+
+	if update.ttl != nil && *update.ttl < 0 {
+		return fmt.Errorf("%w: %s is negative", ErrInvalidTTL, *update.ttl)
+	}
+
+	return nil
+}
+
+// add creates the subscription of the key, it returns false when it already exists.
+func (s *subscriptions) add(key string, opts []SubscriptionOption) (Subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if subscription, ok := s.byKey[key]; ok {
+		return *subscription, false
+	}
+
+	subscription := &Subscription{Address: key, Status: SubscriptionActive, CreatedAt: s.timeProvider().UTC()}
+	s.apply(subscription, opts)
+	s.byKey[key] = subscription
+	s.stale.Store(true)
+
+	return *subscription, true
+}
+
+// restore adds the subscriptions saved in a checkpoint, keeping the subscriptions of the
+// addresses already subscribed.
+func (s *subscriptions) restore(list []Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscription := range list {
+		if _, ok := s.byKey[subscription.Address]; ok {
+			continue
+		}
+
+		s.byKey[subscription.Address] = &subscription
+	}
+
+	s.stale.Store(true)
+}
+
+func (s *subscriptions) update(key string, opts []SubscriptionOption) (Subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.byKey[key]
+	if !ok {
+		return Subscription{}, false
+	}
+
+	s.apply(subscription, opts)
+	s.stale.Store(true)
+
+	return *subscription, true
+}
+
+func (s *subscriptions) apply(subscription *Subscription, opts []SubscriptionOption) {
+	var update subscriptionUpdate
+
+	for _, opt := range opts {
+		opt(&update)
+	}
+
+	if update.paused != nil {
+		subscription.Status = SubscriptionActive
+		if *update.paused {
+			subscription.Status = SubscriptionPaused
+		}
+	}
+
+	if update.ttl != nil {
+		subscription.ExpiresAt = nil
+
+		if *update.ttl > 0 {
+			expiresAt := s.timeProvider().UTC().Add(*update.ttl)
+			subscription.ExpiresAt = &expiresAt
+		}
+	}
+}
+
+func (s *subscriptions) remove(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byKey[key]; !ok {
+		return false
+	}
+
+	delete(s.byKey, key)
+	s.stale.Store(true)
+
+	return true
+}
+
+func (s *subscriptions) get(key string) (Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscription, ok := s.byKey[key]
+	if !ok {
+		return Subscription{}, false
+	}
+
+	return *subscription, true
+}
+
+// list returns the subscriptions ordered by address.
+func (s *subscriptions) list() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Subscription, 0, len(s.byKey))
+	for _, subscription := range s.byKey {
+		list = append(list, *subscription)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+
+	return list
+}
+
+func (s *subscriptions) expired(subscription *Subscription, now time.Time) bool {
+	return subscription.ExpiresAt != nil && !subscription.ExpiresAt.After(now)
+}
+
+// expiredKeys returns the keys of the subscriptions that expired.
+func (s *subscriptions) expiredKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.timeProvider()
+	keys := make([]string, 0)
+
+	for key, subscription := range s.byKey {
+		if s.expired(subscription, now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// touch records that activity of the address of the key was just stored.
+func (s *subscriptions) touch(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if subscription, ok := s.byKey[key]; ok {
+		now := s.timeProvider().UTC()
+		subscription.LastActivityAt = &now
+	}
+}
+
+// Unsubscribe stops recording the activity of an address and stops its running backfill.
+// its stored activity is kept or deleted depending on the history policy of the parser.
+// it returns false when the address is not subscribed.
+func (p *Parser) Unsubscribe(address string) bool {
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return false
+	}
+
+	return p.unsubscribe(key)
+}
+
+func (p *Parser) unsubscribe(key string) bool {
+	if !p.subscriptions.remove(key) {
+		return false
+	}
+
+	defer p.markCheckpoint()
+
+	if _, err := p.CancelBackfill(key); err == nil {
+		p.logger.Info(fmt.Sprintf("backfill of address %s cancelled on unsubscribe", key))
+	}
+
+	if p.historyPolicy == PurgeHistory {
+		if err := p.datastore.Delete(key); err != nil {
+			p.logger.Error(fmt.Sprintf("could not purge the activity of address %s: %v", key, err))
+		}
+
+		p.holdings.remove(key)
+	}
+
+	return true
+}
+
+// UpdateSubscription pauses, resumes or changes the expiry of the subscription of an address.
+func (p *Parser) UpdateSubscription(address string, opts ...SubscriptionOption) (Subscription, error) {
+	if err := validateSubscriptionOptions(opts); err != nil {
+		return Subscription{}, err
+	}
+
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	subscription, ok := p.subscriptions.update(key, opts)
+	if !ok {
+		return Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	p.markCheckpoint()
+
+	return subscription, nil
+}
+
+// GetSubscription returns the subscription of an address.
+func (p *Parser) GetSubscription(address string) (Subscription, error) {
+	key, ok := p.resolveAddress(address)
+	if !ok {
+		return Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	subscription, ok := p.subscriptions.get(key)
+	if !ok {
+		return Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+
+	return subscription, nil
+}
+
+// Subscriptions returns the subscriptions of the parser ordered by address.
+func (p *Parser) Subscriptions() []Subscription {
+	return p.subscriptions.list()
+}
+
+// expireSubscriptions unsubscribes the addresses whose subscription expired.
+func (p *Parser) expireSubscriptions() {
+	for _, key := range p.subscriptions.expiredKeys() {
+		if p.unsubscribe(key) {
+			p.logger.Info(fmt.Sprintf("subscription of address %s expired", key))
+		}
+	}
+}
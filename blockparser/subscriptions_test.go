@@ -0,0 +1,169 @@
+package blockparser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestParserSubscriptionLifecycle(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+	transaction := sampleBlock.Transactions[0]
+
+	chain := make(map[int64]*blockchain.Block)
+	extendChain(chain, "a", 0, 4, map[int64][]blockchain.Transaction{
+		1: {transaction}, 2: {transaction}, 3: {transaction}, 4: {transaction},
+	})
+
+	blockchainQuerier := &MockChainQuerier{LatestBlock: 0, Blocks: chain}
+	parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+		WithBlockchainQuerier(blockchainQuerier))
+
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	parser.subscriptions.timeProvider = func() time.Time { return now }
+
+	if err := parser.initScannedBlockNumber(); err != nil {
+		t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+	}
+
+	scanTo := func(blockNumber int64) {
+		blockchainQuerier.LatestBlock = blockNumber
+		parser.querySubscribedAddressTransactions(context.Background())
+	}
+
+	if subscribed := parser.Subscribe(address, ExpiresAfter(-time.Hour)); subscribed {
+		t.Fatalf("should not subscribe address %s with a negative ttl", address)
+	}
+
+	if subscribed := parser.Subscribe(address, ExpiresAfter(time.Hour)); !subscribed {
+		t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+	}
+
+	if _, err := parser.UpdateSubscription(address, ExpiresAfter(-5*time.Minute)); !errors.Is(err, ErrInvalidTTL) {
+		t.Errorf("UpdateSubscription() error = %v, want %v", err, ErrInvalidTTL)
+	}
+
+	if subscribed := parser.Subscribe(address); subscribed {
+		t.Errorf("should not subscribe address %s twice", address)
+	}
+
+	subscription, err := parser.GetSubscription(address)
+	if err != nil {
+		t.Fatalf("GetSubscription() error = %v, want nil", err)
+	}
+
+	if subscription.Status != SubscriptionActive || !subscription.CreatedAt.Equal(now) ||
+		subscription.LastActivityAt != nil || !subscription.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetSubscription() = %+v, want an active subscription expiring in an hour", subscription)
+	}
+
+	now = now.Add(time.Minute)
+	scanTo(1)
+
+	if subscription, _ := parser.GetSubscription(address); subscription.LastActivityAt == nil ||
+		!subscription.LastActivityAt.Equal(now) {
+		t.Errorf("GetSubscription() last activity = %v, want %v", subscription.LastActivityAt, now)
+	}
+
+	// activity is not recorded while the subscription is paused.
+	if _, err := parser.UpdateSubscription(address, Paused(true)); err != nil {
+		t.Fatalf("UpdateSubscription() error = %v, want nil", err)
+	}
+
+	scanTo(2)
+
+	if _, err := parser.UpdateSubscription(address, Paused(false)); err != nil {
+		t.Fatalf("UpdateSubscription() error = %v, want nil", err)
+	}
+
+	scanTo(3)
+
+	if activity := parser.GetActivity(address); len(activity) != 2 ||
+		activity[0].BlockNumber != 1 || activity[1].BlockNumber != 3 {
+		t.Errorf("GetActivity() = %v, want the activity of blocks 1 and 3", activity)
+	}
+
+	// the subscription expires, its history is kept.
+	now = now.Add(time.Hour)
+	scanTo(4)
+
+	if _, err := parser.GetSubscription(address); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("GetSubscription() error = %v, want %v", err, ErrNotSubscribed)
+	}
+
+	if activity := parser.GetActivity(address); len(activity) != 2 {
+		t.Errorf("GetActivity() = %d entries, want the 2 kept entries", len(activity))
+	}
+
+	if len(parser.Subscriptions()) != 0 {
+		t.Errorf("Subscriptions() = %v, want none", parser.Subscriptions())
+	}
+
+	if _, err := parser.UpdateSubscription(address, Paused(true)); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("UpdateSubscription() error = %v, want %v", err, ErrNotSubscribed)
+	}
+}
+
+func TestParserUnsubscribeHistoryPolicy(t *testing.T) {
+	address := sampleBlock.Transactions[0].From
+
+	tests := []struct {
+		name        string
+		policy      HistoryPolicy
+		wantHistory bool
+	}{
+		{name: "keep history", policy: KeepHistory, wantHistory: true},
+		{name: "purge history", policy: PurgeHistory, wantHistory: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewBlockParser(WithDataStore(newMemoryDataStore[Activity]()),
+				WithBlockchainQuerier(&MockBlockchainQuerier{}), WithHistoryPolicy(tt.policy))
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+			}
+
+			parser.saveSubscribedAddressActivity(&sampleBlock)
+
+			if unsubscribed := parser.Unsubscribe(address); !unsubscribed {
+				t.Fatalf("should unsubscribe address %s; got %v, want true", address, unsubscribed)
+			}
+
+			if unsubscribed := parser.Unsubscribe(address); unsubscribed {
+				t.Errorf("should not unsubscribe address %s twice", address)
+			}
+
+			if history := parser.GetActivity(address) != nil; history != tt.wantHistory {
+				t.Errorf("GetActivity() returned history = %v, want %v", history, tt.wantHistory)
+			}
+
+			// activity of a block scanned while the address was unsubscribed does not bring
+			// back a purged history.
+			parser.storeActivity(&sampleBlock, map[string][]Activity{address: {{BlockHash: sampleBlock.Hash}}})
+
+			if history := parser.GetActivity(address) != nil; history != tt.wantHistory {
+				t.Errorf("GetActivity() returned history = %v after a scan, want %v", history, tt.wantHistory)
+			}
+
+			// the history is not extended while the address is unsubscribed.
+			parser.saveSubscribedAddressActivity(&sampleBlock)
+
+			if _, err := parser.Backfill(address, BackfillFromBlock(0)); !errors.Is(err, ErrNotSubscribed) {
+				t.Errorf("Backfill() error = %v, want %v", err, ErrNotSubscribed)
+			}
+
+			if subscribed := parser.Subscribe(address); !subscribed {
+				t.Fatalf("should subscribe address %s again; got %v, want true", address, subscribed)
+			}
+
+			if activity := parser.GetActivity(address); tt.wantHistory != (len(activity) > 0) {
+				t.Errorf("GetActivity() = %d entries after subscribing again, want history %v",
+					len(activity), tt.wantHistory)
+			}
+		})
+	}
+}
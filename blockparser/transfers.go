@@ -0,0 +1,190 @@
+package blockparser
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// LogsQuerier is an interface for querying the logs of a block whose event signature is one
+// of the given topics. blockchain queriers can optionally implement it to let the parser
+// fetch the token transfers of a block without fetching all its receipts.
+type LogsQuerier interface {
+	GetLogs(blockHash string, topics ...string) ([]blockchain.Log, error)
+}
+
+// blockTransferLogs fetches the token and NFT transfer logs of a block at most once, on
+// behalf of the token and NFT transfer matchers.
+type blockTransferLogs struct {
+	parser        *Parser
+	block         *blockchain.Block
+	blockReceipts *blockReceipts
+	logs          []blockchain.Log
+	fetched       bool
+	ok            bool
+	// err is the error fetching the logs with eth_getLogs, errors fetching the receipts are
+	// set on blockReceipts.
+	err error
+}
+
+func (p *Parser) newBlockTransferLogs(block *blockchain.Block, blockReceipts *blockReceipts) *blockTransferLogs {
+	return &blockTransferLogs{parser: p, block: block, blockReceipts: blockReceipts}
+}
+
+// get returns the logs of the block that may be transfers, fetching them on first use: from
+// the receipts of the block when they were already fetched and with eth_getLogs otherwise.
+// ok is false when they cannot be fetched or fetching them failed.
+func (l *blockTransferLogs) get() ([]blockchain.Log, bool) {
+	if l.fetched {
+		return l.logs, l.ok
+	}
+
+	l.fetched = true
+
+	logsQuerier, ok := l.parser.blockchainQuerier.(LogsQuerier)
+	if l.blockReceipts.fetched || !ok {
+		receipts, ok := l.blockReceipts.get()
+		if !ok {
+			return nil, false
+		}
+
+		for i := range receipts {
+			l.logs = append(l.logs, receipts[i].Logs...)
+		}
+
+		l.ok = true
+
+		return l.logs, l.ok
+	}
+
+	topics := make([]string, 0, 3)
+	for _, topic := range l.parser.transferTopics() {
+		topics = append(topics, "0x"+hex.EncodeToString(topic[:]))
+	}
+
+	logs, err := logsQuerier.GetLogs(l.block.Hash, topics...)
+	if err != nil {
+		l.err = fmt.Errorf("error fetching transfer logs of block %s: %w", l.block.Number, err)
+		return nil, false
+	}
+
+	l.logs, l.ok = logs, true
+
+	return l.logs, l.ok
+}
+
+// transferTopics returns the event signatures of the transfers tracked by the parser.
+func (p *Parser) transferTopics() [][32]byte {
+	topics := [][32]byte{blockchain.TransferEventTopic}
+
+	if p.trackNFTs {
+		topics = append(topics, blockchain.TransferSingleEventTopic, blockchain.TransferBatchEventTopic)
+	}
+
+	return topics
+}
+
+// tokenTransferActivity returns the ERC-20 token transfers of the block sent or received by
+// subscribed addresses, keyed by the canonical storage key of the addresses. a transfer sent
+// by an address to itself is stored once. the transfer logs are only fetched when the logs
+// bloom of the block may contain a transfer involving a subscribed address.
+func (p *Parser) tokenTransferActivity(block *blockchain.Block, transferLogs *blockTransferLogs,
+	watched *watchSet,
+) map[string][]Activity {
+	if !p.trackTokenTransfers {
+		return nil
+	}
+
+	if !transferBloomMayMatch(block, watched, blockchain.TransferEventTopic) {
+		return nil
+	}
+
+	logs, ok := transferLogs.get()
+	if !ok {
+		return nil
+	}
+
+	blockNumber, err := block.NumberUint64()
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("invalid block %s: %v", block.Hash, err))
+		return nil
+	}
+
+	activity := make(map[string][]Activity)
+
+	for i := range logs {
+		if logs[i].Removed || !blockchain.IsTransferLog(logs[i]) {
+			continue
+		}
+
+		transfer, err := blockchain.ParseTokenTransfer(logs[i])
+		if err != nil {
+			// ERC-721 transfers share the event signature of ERC-20 transfers.
+			if !errors.Is(err, blockchain.ErrNotTokenTransfer) {
+				p.logger.Warn(fmt.Sprintf("invalid transfer log %s of %s: %v", logs[i].LogIndex,
+					logs[i].TransactionHash, err))
+			}
+
+			continue
+		}
+
+		stored := make(map[string]bool, 2)
+
+		for _, address := range []string{transfer.From, transfer.To} {
+			key, ok := watched.match(address)
+			if !ok || stored[key] {
+				continue
+			}
+
+			entry := Activity{
+				Kind:          ActivityKindTokenTransfer,
+				BlockNumber:   blockNumber,
+				BlockHash:     block.Hash,
+				TokenTransfer: &transfer,
+			}
+			entry.Direction, entry.Counterparty = direction(transfer.From, transfer.To, key)
+
+			stored[key] = true
+			activity[key] = append(activity[key], entry)
+		}
+	}
+
+	return activity
+}
+
+// transferBloomMayMatch tests the logs bloom of the block for one of the transfer events and
+// the subscribed addresses as indexed topics. blocks without a valid bloom may always match.
+func transferBloomMayMatch(block *blockchain.Block, watched *watchSet, events ...[32]byte) bool {
+	bloom, err := block.Bloom()
+	if err != nil {
+		return true
+	}
+
+	for _, event := range events {
+		if bloom.TestTopic(event) {
+			return watched.inBloom(&bloom, false)
+		}
+	}
+
+	return false
+}
+
+// GetTokenTransfers returns the ERC-20 token transfers sent or received by an address.
+func (p *Parser) GetTokenTransfers(address string, opts ...QueryOption) []blockchain.TokenTransfer {
+	activity := p.GetActivity(address, opts...)
+	if activity == nil {
+		return nil
+	}
+
+	transfers := make([]blockchain.TokenTransfer, 0)
+
+	for _, entry := range activity {
+		if entry.Kind == ActivityKindTokenTransfer {
+			transfers = append(transfers, *entry.TokenTransfer)
+		}
+	}
+
+	return transfers
+}
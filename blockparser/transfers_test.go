@@ -0,0 +1,157 @@
+package blockparser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+type MockLogsQuerier struct {
+	MockReceiptsQuerier
+	Logs      []blockchain.Log
+	LogsCalls int
+	Topics    []string
+}
+
+func (m *MockLogsQuerier) GetLogs(_ string, topics ...string) ([]blockchain.Log, error) {
+	m.LogsCalls++
+	m.Topics = topics
+
+	return m.Logs, nil
+}
+
+func TestParserTracksTokenTransfers(t *testing.T) {
+	subscriber := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	recipient := "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+	token := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+	transfer := blockchain.Log{
+		Address: token,
+		Topics: []string{
+			transferTopic,
+			"0x0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			"0x000000000000000000000000fb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		},
+		Data:            "0x00000000000000000000000000000000000000000000000000000000000f4240",
+		TransactionHash: "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+		LogIndex:        "0x0",
+	}
+	nft := blockchain.Log{
+		Address:         "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d",
+		Topics:          append(append([]string{}, transfer.Topics...), transferTopic),
+		Data:            "0x",
+		TransactionHash: transfer.TransactionHash,
+		LogIndex:        "0x1",
+	}
+	approval := blockchain.Log{
+		Address: token,
+		Topics: []string{
+			"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+			"0x000000000000000000000000dbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+			"0x000000000000000000000000fb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		bloomLogs     []blockchain.Log
+		logsQuerier   bool
+		trackLogs     bool
+		wantTransfers int
+		wantReceipts  int
+		wantLogsCalls int
+	}{
+		{name: "from receipts", bloomLogs: []blockchain.Log{transfer, nft}, wantTransfers: 1, wantReceipts: 1},
+		{
+			name: "with eth_getLogs", bloomLogs: []blockchain.Log{transfer, nft}, logsQuerier: true,
+			wantTransfers: 1, wantLogsCalls: 1,
+		},
+		{
+			name: "from receipts fetched for logs", bloomLogs: []blockchain.Log{transfer, nft}, logsQuerier: true,
+			trackLogs: true, wantTransfers: 1, wantReceipts: 1,
+		},
+		{name: "bloom rules out the block", bloomLogs: []blockchain.Log{approval}, logsQuerier: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bloom, err := blockchain.CreateBloom(tt.bloomLogs)
+			if err != nil {
+				t.Fatalf("CreateBloom() error = %v", err)
+			}
+
+			block := sampleBlock
+			block.Transactions = nil
+			block.LogsBloom = bloom.String()
+
+			receiptsQuerier := MockReceiptsQuerier{
+				MockBlockchainQuerier: MockBlockchainQuerier{LatestBlock: 0x7b, Block: &block},
+				Receipts:              []blockchain.Receipt{{Logs: []blockchain.Log{transfer, nft, approval}}},
+			}
+			logsQuerier := &MockLogsQuerier{MockReceiptsQuerier: receiptsQuerier, Logs: []blockchain.Log{transfer, nft}}
+
+			var blockchainQuerier BlockchainQuerier = &logsQuerier.MockReceiptsQuerier
+			if tt.logsQuerier {
+				blockchainQuerier = logsQuerier
+			}
+
+			opts := []ConfigOptionResolver{
+				WithDataStore(newMemoryDataStore[Activity]()), WithBlockchainQuerier(blockchainQuerier),
+				WithTokenTransferTracking(),
+			}
+			if tt.trackLogs {
+				opts = append(opts, WithLogTracking(transferTopic))
+			}
+
+			parser := NewBlockParser(opts...)
+
+			for _, address := range []string{subscriber, recipient} {
+				if subscribed := parser.Subscribe(address); !subscribed {
+					t.Fatalf("should subscribe address %s; got %v, want true", address, subscribed)
+				}
+			}
+
+			if err := parser.initScannedBlockNumber(); err != nil {
+				t.Fatalf("initScannedBlockNumber() = %v, want nil error", err)
+			}
+
+			parser.querySubscribedAddressTransactions(context.Background())
+
+			transfers := parser.GetTokenTransfers(subscriber)
+			if len(transfers) != tt.wantTransfers {
+				t.Fatalf("GetTokenTransfers() = %v, want %d transfers", transfers, tt.wantTransfers)
+			}
+
+			for _, transfer := range transfers {
+				if transfer.Token != token || transfer.From != subscriber || transfer.To != recipient ||
+					transfer.Amount != "1000000" {
+					t.Errorf("GetTokenTransfers() = %+v, want 1000000 of %s sent to %s", transfer, token, recipient)
+				}
+			}
+
+			if receipts := logsQuerier.ReceiptsCalls; receipts != tt.wantReceipts {
+				t.Errorf("receipts fetched %d times, want %d", receipts, tt.wantReceipts)
+			}
+
+			if logsQuerier.LogsCalls != tt.wantLogsCalls {
+				t.Errorf("logs fetched %d times, want %d", logsQuerier.LogsCalls, tt.wantLogsCalls)
+			}
+
+			if tt.wantTransfers == 0 {
+				return
+			}
+
+			sent := parser.GetActivity(subscriber, InDirection(DirectionOut), OfToken(token))
+			received := parser.GetActivity(recipient, InDirection(DirectionIn), OfToken(token))
+
+			if len(sent) != 1 || sent[0].Counterparty != recipient || len(received) != 1 ||
+				received[0].Counterparty != subscriber {
+				t.Errorf("GetActivity() = %v and %v, want the transfer sent and received", sent, received)
+			}
+
+			if other := parser.GetTokenTransfers(subscriber, OfToken(recipient)); len(other) != 0 {
+				t.Errorf("GetTokenTransfers() = %v, want no transfers of another token", other)
+			}
+		})
+	}
+}
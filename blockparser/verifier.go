@@ -0,0 +1,131 @@
+package blockparser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+var ErrBlockVerificationFailed = errors.New("block verification failed")
+
+// ReceiptsQuerier is an interface for querying the receipts of a block. blockchain
+// queriers can optionally implement it to let the parser use transaction receipts.
+type ReceiptsQuerier interface {
+	GetBlockReceipts(blockNumber string) ([]blockchain.Receipt, error)
+}
+
+// FetchedBlockReceiptsQuerier is an interface for querying the receipts of a block that was
+// already fetched. blockchain queriers that check receipts against their block can optionally
+// implement it so the block is not fetched again.
+type FetchedBlockReceiptsQuerier interface {
+	GetReceiptsOfBlock(block *blockchain.Block) ([]blockchain.Receipt, error)
+}
+
+// VerifyingQuerier is a BlockchainQuerier that rejects blocks whose transactions,
+// withdrawals or header do not match the roots and hash the node returned with them.
+// when the wrapped querier can fetch receipts, the receipts it returns are checked
+// against the receipts root of their block too.
+type VerifyingQuerier struct {
+	querier BlockchainQuerier
+}
+
+// NewVerifyingQuerier wraps a blockchain querier so every fetched block is verified.
+func NewVerifyingQuerier(querier BlockchainQuerier) *VerifyingQuerier {
+	return &VerifyingQuerier{querier: querier}
+}
+
+func (q *VerifyingQuerier) GetLatestBlock() (string, error) {
+	blockNumber, err := q.querier.GetLatestBlock()
+	if err != nil {
+		return "", fmt.Errorf("error fetching latest block: %w", err)
+	}
+
+	return blockNumber, nil
+}
+
+// GetBlock fetches the block and returns an error wrapping ErrBlockVerificationFailed
+// if it does not pass verification.
+func (q *VerifyingQuerier) GetBlock(blockNumber string) (*blockchain.Block, error) {
+	block, err := q.querier.GetBlock(blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching block %s: %w", blockNumber, err)
+	}
+
+	if err := blockchain.VerifyBlock(*block); err != nil {
+		return nil, fmt.Errorf("%w: block %s: %w", ErrBlockVerificationFailed, blockNumber, err)
+	}
+
+	return block, nil
+}
+
+// GetBlockReceipts fetches the block and its receipts and returns the receipts if they
+// match the receipts root of the verified block.
+func (q *VerifyingQuerier) GetBlockReceipts(blockNumber string) ([]blockchain.Receipt, error) {
+	receiptsQuerier, ok := q.querier.(ReceiptsQuerier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T cannot fetch receipts", errors.ErrUnsupported, q.querier)
+	}
+
+	block, err := q.GetBlock(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.receiptsOfBlock(receiptsQuerier, block)
+}
+
+// GetReceiptsOfBlock fetches the receipts of a block returned by GetBlock and returns them if
+// they match the receipts root of the block, without fetching the block again.
+func (q *VerifyingQuerier) GetReceiptsOfBlock(block *blockchain.Block) ([]blockchain.Receipt, error) {
+	receiptsQuerier, ok := q.querier.(ReceiptsQuerier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T cannot fetch receipts", errors.ErrUnsupported, q.querier)
+	}
+
+	return q.receiptsOfBlock(receiptsQuerier, block)
+}
+
+func (q *VerifyingQuerier) receiptsOfBlock(receiptsQuerier ReceiptsQuerier, block *blockchain.Block,
+) ([]blockchain.Receipt, error) {
+	receipts, err := receiptsQuerier.GetBlockReceipts(block.Number)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching receipts of block %s: %w", block.Number, err)
+	}
+
+	if err := blockchain.VerifyReceipts(*block, receipts); err != nil {
+		return nil, fmt.Errorf("%w: receipts of block %s: %w", ErrBlockVerificationFailed, block.Number, err)
+	}
+
+	return receipts, nil
+}
+
+// GetFinalizedBlock returns the latest finalized block of the wrapped querier.
+func (q *VerifyingQuerier) GetFinalizedBlock() (string, error) {
+	finalizedQuerier, ok := q.querier.(FinalizedBlockQuerier)
+	if !ok {
+		return "", fmt.Errorf("%w: %T cannot fetch the finalized block", errors.ErrUnsupported, q.querier)
+	}
+
+	blockNumber, err := finalizedQuerier.GetFinalizedBlock()
+	if err != nil {
+		return "", fmt.Errorf("error fetching finalized block: %w", err)
+	}
+
+	return blockNumber, nil
+}
+
+// GetChainID returns the chain id of the wrapped querier.
+func (q *VerifyingQuerier) GetChainID() (string, error) {
+	chainIDQuerier, ok := q.querier.(ChainIDQuerier)
+	if !ok {
+		return "", fmt.Errorf("%w: %T cannot fetch the chain id", errors.ErrUnsupported, q.querier)
+	}
+
+	chainID, err := chainIDQuerier.GetChainID()
+	if err != nil {
+		return "", fmt.Errorf("error fetching chain id: %w", err)
+	}
+
+	return chainID, nil
+}
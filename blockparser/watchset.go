@@ -0,0 +1,252 @@
+package blockparser
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+// minPrefilterBits is the smallest size of the address pre-filter of a watch set.
+const minPrefilterBits = 1 << 10
+
+// watchedAddress is an address matched while scanning, with its bits in logs blooms
+// computed once.
+type watchedAddress struct {
+	address blockchain.Address
+	key     string
+	// emitter and topic are the bloom bits of the address as the emitter of a log and
+	// as an indexed topic.
+	emitter blockchain.BloomIndex
+	topic   blockchain.BloomIndex
+}
+
+// watchSet is an immutable snapshot of the addresses matched while scanning. it is rebuilt
+// whenever a subscription changes and swapped in atomically, so scanning reads it without
+// locking or copying the subscriptions.
+type watchSet struct {
+	byAddress map[blockchain.Address]*watchedAddress
+	watched   []watchedAddress
+	// prefilter rules out most addresses that are not watched before decoding them, nil
+	// when disabled.
+	prefilter *addressFilter
+	// expiresAt is when the first subscription of the set expires and the set has to be
+	// rebuilt, zero when no subscription expires.
+	expiresAt time.Time
+}
+
+func newWatchSet(subscriptions map[string]*Subscription, now time.Time, prefilter bool) *watchSet {
+	set := &watchSet{byAddress: make(map[blockchain.Address]*watchedAddress, len(subscriptions))}
+
+	for key, subscription := range subscriptions {
+		if subscription.Status != SubscriptionActive {
+			continue
+		}
+
+		if expiresAt := subscription.ExpiresAt; expiresAt != nil {
+			if !expiresAt.After(now) {
+				continue
+			}
+
+			if set.expiresAt.IsZero() || expiresAt.Before(set.expiresAt) {
+				set.expiresAt = *expiresAt
+			}
+		}
+
+		address, err := blockchain.ParseAddress(key)
+		if err != nil {
+			continue
+		}
+
+		topic := blockchain.AddressTopic(address)
+		set.watched = append(set.watched, watchedAddress{
+			address: address,
+			key:     key,
+			emitter: blockchain.BloomIndexOf(address[:]),
+			topic:   blockchain.BloomIndexOf(topic[:]),
+		})
+	}
+
+	addresses := make([]blockchain.Address, len(set.watched))
+
+	for i := range set.watched {
+		set.byAddress[set.watched[i].address] = &set.watched[i]
+		addresses[i] = set.watched[i].address
+	}
+
+	if prefilter {
+		set.prefilter = newAddressFilter(addresses)
+	}
+
+	return set
+}
+
+// addressWatchSet returns a watch set matching only the address of the storage key.
+func addressWatchSet(key string) *watchSet {
+	return newWatchSet(map[string]*Subscription{key: {Status: SubscriptionActive}}, time.Now(), false)
+}
+
+func (w *watchSet) len() int {
+	return len(w.watched)
+}
+
+// match returns the canonical storage key of the "0x" prefixed hex encoded address if it is
+// watched. like blockchain.NormalizeAddress, mixed case addresses must carry a valid checksum.
+func (w *watchSet) match(address string) (string, bool) {
+	if len(w.watched) == 0 || (w.prefilter != nil && !w.prefilter.mayContain(address)) {
+		return "", false
+	}
+
+	decoded, ok := decodeAddress(address)
+	if !ok {
+		return "", false
+	}
+
+	watched, ok := w.byAddress[decoded]
+	if !ok {
+		return "", false
+	}
+
+	if isMixedCase(address) && decoded.Hex() != address {
+		return "", false
+	}
+
+	return watched.key, true
+}
+
+// lookup returns the canonical storage key of the address if it is watched.
+func (w *watchSet) lookup(address blockchain.Address) (string, bool) {
+	watched, ok := w.byAddress[address]
+	if !ok {
+		return "", false
+	}
+
+	return watched.key, true
+}
+
+// inBloom reports whether any watched address may be an indexed topic of a log in the bloom,
+// or its emitter when emitters is true.
+func (w *watchSet) inBloom(bloom *blockchain.Bloom, emitters bool) bool {
+	for i := range w.watched {
+		if bloom.TestIndex(w.watched[i].topic) || (emitters && bloom.TestIndex(w.watched[i].emitter)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addressFilter is a bloom filter of the watched addresses probed with two bit positions
+// taken straight from the address bytes, addresses being uniformly distributed hashes. it
+// reads 16 hex digits of an address instead of decoding all of it.
+type addressFilter struct {
+	bits []uint64
+	mask uint32
+}
+
+func newAddressFilter(addresses []blockchain.Address) *addressFilter {
+	size := uint32(minPrefilterBits)
+	for int(size) < 16*len(addresses) && size < 1<<31 {
+		size <<= 1
+	}
+
+	filter := &addressFilter{bits: make([]uint64, size/64), mask: size - 1}
+
+	for _, address := range addresses {
+		first, second := filterPositions(address[:4], address[16:])
+		filter.set(first)
+		filter.set(second)
+	}
+
+	return filter
+}
+
+func filterPositions(head, tail []byte) (uint32, uint32) {
+	return binary.BigEndian.Uint32(head), binary.BigEndian.Uint32(tail)
+}
+
+func (f *addressFilter) set(position uint32) {
+	position &= f.mask
+	f.bits[position/64] |= 1 << (position % 64)
+}
+
+func (f *addressFilter) test(position uint32) bool {
+	position &= f.mask
+	return f.bits[position/64]&(1<<(position%64)) != 0
+}
+
+// mayContain reports whether the "0x" prefixed hex encoded address may be watched. false
+// means it is certainly not, or is not a valid address.
+func (f *addressFilter) mayContain(address string) bool {
+	if len(address) != 2+2*blockchain.AddressLength {
+		return false
+	}
+
+	var head, tail [4]byte
+	if !decodeHex(head[:], address[2:10]) || !decodeHex(tail[:], address[34:]) {
+		return false
+	}
+
+	first, second := filterPositions(head[:], tail[:])
+
+	return f.test(first) && f.test(second)
+}
+
+// decodeAddress decodes a "0x" prefixed hex encoded address without allocating.
+func decodeAddress(s string) (blockchain.Address, bool) {
+	var address blockchain.Address
+
+	if len(s) != 2+2*blockchain.AddressLength || s[0] != '0' || s[1] != 'x' {
+		return address, false
+	}
+
+	return address, decodeHex(address[:], s[2:])
+}
+
+// isMixedCase reports whether the hex digits of the address mix lower and upper case letters.
+func isMixedCase(s string) bool {
+	lower, upper := false, false
+
+	for i := 2; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'f':
+			lower = true
+		case c >= 'A' && c <= 'F':
+			upper = true
+		}
+	}
+
+	return lower && upper
+}
+
+// hexValues maps the hex digits to their value and every other byte to invalidHexValue.
+var hexValues = func() [256]byte {
+	var values [256]byte
+
+	for i := range values {
+		values[i] = invalidHexValue
+	}
+
+	for i, digit := range "0123456789abcdef" {
+		values[digit] = byte(i)
+		values[digit-'a'+'A'] = byte(i)
+	}
+
+	return values
+}()
+
+const invalidHexValue = 0xff
+
+// decodeHex decodes the hex digits into dst, which must be half their length.
+func decodeHex(dst []byte, digits string) bool {
+	for i := range dst {
+		high, low := hexValues[digits[2*i]], hexValues[digits[2*i+1]]
+		if high == invalidHexValue || low == invalidHexValue {
+			return false
+		}
+
+		dst[i] = high<<4 | low
+	}
+
+	return true
+}
@@ -0,0 +1,190 @@
+package blockparser
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+func TestWatchSetMatch(t *testing.T) {
+	watched := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	paused := "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+	expiring := "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb"
+
+	tests := []struct {
+		address string
+		wantKey string
+		wantOk  bool
+	}{
+		{address: watched, wantKey: watched, wantOk: true},
+		{address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", wantKey: watched, wantOk: true},
+		{address: strings.ToUpper(watched[2:]), wantOk: false},
+		{address: "0x" + strings.ToUpper(watched[2:]), wantKey: watched, wantOk: true},
+		{address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", wantOk: false},
+		{address: paused, wantOk: false},
+		{address: expiring, wantKey: expiring, wantOk: true},
+		{address: "0xd8da6bf26964af9d7eed9e03e53415d37aa96045", wantOk: false},
+		{address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beae", wantOk: false},
+		{address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg", wantOk: false},
+	}
+	for _, prefilter := range []bool{false, true} {
+		now := time.Now()
+		registry := newSubscriptions(prefilter)
+		registry.timeProvider = func() time.Time { return now }
+
+		registry.add(watched, nil)
+		registry.add(paused, []SubscriptionOption{Paused(true)})
+		registry.add(expiring, []SubscriptionOption{ExpiresAfter(time.Minute)})
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s prefilter %v", tt.address, prefilter), func(t *testing.T) {
+				key, ok := registry.watched().match(tt.address)
+				if key != tt.wantKey || ok != tt.wantOk {
+					t.Errorf("match() = %q, %v, want %q, %v", key, ok, tt.wantKey, tt.wantOk)
+				}
+			})
+		}
+
+		if set := registry.watched(); set.len() != 2 {
+			t.Errorf("watched() has %d addresses, want 2", set.len())
+		}
+
+		// the snapshot is rebuilt when a subscription expires or changes.
+		now = now.Add(time.Minute)
+		if _, ok := registry.watched().match(expiring); ok {
+			t.Errorf("match() of an expired subscription = true, want false")
+		}
+
+		registry.update(paused, []SubscriptionOption{Paused(false)})
+		registry.remove(watched)
+
+		if _, ok := registry.watched().match(paused); !ok {
+			t.Errorf("match() of a resumed subscription = false, want true")
+		}
+
+		if _, ok := registry.watched().match(watched); ok {
+			t.Errorf("match() of an unsubscribed address = true, want false")
+		}
+	}
+}
+
+func TestAddressFilterHasNoFalseNegatives(t *testing.T) {
+	addresses := randomAddresses(rand.New(rand.NewSource(1)), 10000)
+	filter := newAddressFilter(addresses)
+
+	for _, address := range addresses {
+		if !filter.mayContain(address.Key()) || !filter.mayContain(address.Hex()) {
+			t.Fatalf("mayContain(%s) = false, want true", address.Key())
+		}
+	}
+
+	falsePositives := 0
+
+	for _, address := range randomAddresses(rand.New(rand.NewSource(2)), 10000) {
+		if filter.mayContain(address.Key()) {
+			falsePositives++
+		}
+	}
+
+	if falsePositives > 500 {
+		t.Errorf("mayContain() has %d false positives out of 10000, want fewer than 500", falsePositives)
+	}
+}
+
+func randomAddresses(random *rand.Rand, n int) []blockchain.Address {
+	addresses := make([]blockchain.Address, n)
+	for i := range addresses {
+		random.Read(addresses[i][:])
+	}
+
+	return addresses
+}
+
+// benchmarkBlock returns a block of 200 transactions between random addresses, two of them
+// sent by watched addresses.
+func benchmarkBlock(random *rand.Rand, watched []blockchain.Address) *blockchain.Block {
+	block := &blockchain.Block{Number: "0x1", Hash: "0x01"}
+	addresses := randomAddresses(random, 400)
+
+	for i := range 200 {
+		from := addresses[2*i].Key()
+		if i%100 == 0 {
+			from = watched[i/100*(len(watched)-1)].Hex()
+		}
+
+		block.Transactions = append(block.Transactions, blockchain.Transaction{
+			Hash: fmt.Sprintf("0x%064x", i),
+			From: from,
+			To:   addresses[2*i+1].Hex(),
+		})
+	}
+
+	return block
+}
+
+// BenchmarkBlockActivity measures the cost of matching the transactions of a block against
+// large watch lists.
+func BenchmarkBlockActivity(b *testing.B) {
+	for _, size := range []int{100, 10000, 100000} {
+		for _, prefilter := range []bool{false, true} {
+			b.Run(fmt.Sprintf("watched=%d/prefilter=%v", size, prefilter), func(b *testing.B) {
+				random := rand.New(rand.NewSource(1))
+				watched := randomAddresses(random, size)
+
+				opts := []ConfigOptionResolver{
+					WithDataStore(newMemoryDataStore[Activity]()),
+					WithBlockchainQuerier(&MockBlockchainQuerier{}),
+				}
+				if prefilter {
+					opts = append(opts, WithWatchPrefilter())
+				}
+
+				parser := NewBlockParser(opts...)
+				for _, address := range watched {
+					parser.Subscribe(address.Key())
+				}
+
+				block := benchmarkBlock(random, watched)
+
+				if activity := parser.blockActivity(block, parser.subscriptions.watched()); len(activity) != 2 {
+					b.Fatalf("blockActivity() matched %d addresses, want 2", len(activity))
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for range b.N {
+					parser.blockActivity(block, parser.subscriptions.watched())
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkWatchSetMatch measures the cost of matching a single address that is not watched.
+func BenchmarkWatchSetMatch(b *testing.B) {
+	for _, prefilter := range []bool{false, true} {
+		b.Run(fmt.Sprintf("prefilter=%v", prefilter), func(b *testing.B) {
+			random := rand.New(rand.NewSource(1))
+			registry := newSubscriptions(prefilter)
+
+			for _, address := range randomAddresses(random, 10000) {
+				registry.add(address.Key(), nil)
+			}
+
+			address := randomAddresses(random, 1)[0].Hex()
+			registry.watched()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				registry.watched().match(address)
+			}
+		})
+	}
+}
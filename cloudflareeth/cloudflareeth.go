@@ -7,11 +7,22 @@ import (
 	"github.com/spankie/tw-interview/blockchain"
 )
 
-var ErrInvalidBlockResponse = errors.New("invalid block response")
+var (
+	ErrInvalidBlockResponse = errors.New("invalid block response")
+	ErrInvalidCallResponse  = errors.New("invalid call response")
+	ErrInvalidReceipts      = errors.New("invalid receipts response")
+	ErrInvalidLogs          = errors.New("invalid logs response")
+	ErrInvalidChainID       = errors.New("invalid chain id response")
+)
 
 const (
 	ethBlockNumberMethod      = "eth_blockNumber"
 	ethGetBlockByNumberMethod = "eth_getBlockByNumber"
+	ethCallMethod             = "eth_call"
+	ethGetBlockReceiptsMethod = "eth_getBlockReceipts"
+	ethGetLogsMethod          = "eth_getLogs"
+	ethChainIDMethod          = "eth_chainId"
+	finalizedBlockTag         = "finalized"
 )
 
 type requester interface {
@@ -65,6 +76,34 @@ func (c Client) GetLatestBlock() (string, error) {
 	return blockNumberStr, nil
 }
 
+// GetChainID queries the id of the chain of the node represented in hex.
+func (c Client) GetChainID() (string, error) {
+	rpcReq := rpcRequestBody{
+		Jsonrpc: c.jsonRPCVersion,
+		Method:  ethChainIDMethod,
+		Params:  []any{},
+		ID:      1,
+	}
+
+	var res response
+
+	err := c.client.Post("", rpcReq, &res)
+	if err != nil {
+		return "", fmt.Errorf("http error getting chain id: %w", err)
+	}
+
+	if res.Error != nil {
+		return "", fmt.Errorf("error getting chain id: %w", res.Error)
+	}
+
+	chainID, ok := res.Result.(string)
+	if !ok {
+		return "", ErrInvalidChainID
+	}
+
+	return chainID, nil
+}
+
 // getBlock queries the etheruem blockchain to the block identified by the blockNumber
 // represented in hex.
 func (c Client) GetBlock(blockNumber string) (*blockchain.Block, error) {
@@ -89,3 +128,114 @@ func (c Client) GetBlock(blockNumber string) (*blockchain.Block, error) {
 
 	return block, nil
 }
+
+// GetFinalizedBlock queries the number of the latest finalized block represented in hex.
+func (c Client) GetFinalizedBlock() (string, error) {
+	rpcReq := rpcRequestBody{
+		Jsonrpc: c.jsonRPCVersion,
+		Method:  ethGetBlockByNumberMethod,
+		Params:  []any{finalizedBlockTag, false},
+		ID:      1,
+	}
+
+	res := &response{Result: &blockchain.Block{}}
+
+	err := c.client.Post("", rpcReq, res)
+	if err != nil {
+		return "", fmt.Errorf("http error getting finalized block: %w", err)
+	}
+
+	block, ok := res.Result.(*blockchain.Block)
+	if !ok || block.Number == "" {
+		return "", ErrInvalidBlockResponse
+	}
+
+	return block.Number, nil
+}
+
+// GetBlockReceipts queries the receipts of all transactions in the block identified by
+// the blockNumber represented in hex.
+func (c Client) GetBlockReceipts(blockNumber string) ([]blockchain.Receipt, error) {
+	rpcReq := rpcRequestBody{
+		Jsonrpc: c.jsonRPCVersion,
+		Method:  ethGetBlockReceiptsMethod,
+		Params:  []any{blockNumber},
+		ID:      1,
+	}
+
+	res := &response{Result: &[]blockchain.Receipt{}}
+
+	err := c.client.Post("", rpcReq, res)
+	if err != nil {
+		return nil, fmt.Errorf("http error getting receipts of block #%s: %w", blockNumber, err)
+	}
+
+	if res.Error != nil {
+		return nil, fmt.Errorf("error getting receipts of block #%s: %w", blockNumber, res.Error)
+	}
+
+	receipts, ok := res.Result.(*[]blockchain.Receipt)
+	if !ok {
+		return nil, ErrInvalidReceipts
+	}
+
+	return *receipts, nil
+}
+
+// GetLogs queries the logs of the block identified by its hash whose event signature
+// (the first topic) is one of the given topics.
+func (c Client) GetLogs(blockHash string, topics ...string) ([]blockchain.Log, error) {
+	rpcReq := rpcRequestBody{
+		Jsonrpc: c.jsonRPCVersion,
+		Method:  ethGetLogsMethod,
+		Params:  []any{map[string]any{"blockHash": blockHash, "topics": [][]string{topics}}},
+		ID:      1,
+	}
+
+	res := &response{Result: &[]blockchain.Log{}}
+
+	err := c.client.Post("", rpcReq, res)
+	if err != nil {
+		return nil, fmt.Errorf("http error getting logs of block %s: %w", blockHash, err)
+	}
+
+	if res.Error != nil {
+		return nil, fmt.Errorf("error getting logs of block %s: %w", blockHash, res.Error)
+	}
+
+	logs, ok := res.Result.(*[]blockchain.Log)
+	if !ok {
+		return nil, ErrInvalidLogs
+	}
+
+	return *logs, nil
+}
+
+// Call executes a read only message call against the contract at address `to` with the
+// hex encoded calldata at the latest block and returns the hex encoded return data.
+func (c Client) Call(to, data string) (string, error) {
+	rpcReq := rpcRequestBody{
+		Jsonrpc: c.jsonRPCVersion,
+		Method:  ethCallMethod,
+		Params:  []any{map[string]string{"to": to, "data": data}, "latest"},
+		ID:      1,
+	}
+
+	var res response
+
+	err := c.client.Post("", rpcReq, &res)
+	if err != nil {
+		return "", fmt.Errorf("http error calling %s: %w", to, err)
+	}
+
+	if res.Error != nil {
+		return "", fmt.Errorf("error calling %s: %w", to, res.Error)
+	}
+
+	result, ok := res.Result.(string)
+	if !ok {
+		return "", ErrInvalidCallResponse
+	}
+
+	return result, nil
+}
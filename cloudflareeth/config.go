@@ -1,6 +1,9 @@
 package cloudflareeth
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 const defaultCloudFlareBaseURL = "https://cloudflare-eth.com"
 
@@ -20,6 +23,14 @@ func WithHTTPClient(requestClient requester) ConfigOptionResolver {
 	}
 }
 
+// WithRPCURL makes the client query the json-rpc node at the given url instead of cloudflare,
+// e.g. a node of another EVM chain.
+func WithRPCURL(url string) ConfigOptionResolver {
+	return func(c *Config) {
+		c.requester = newRequester(url)
+	}
+}
+
 func LoadDefaultConfig() Config {
 	// Load default config
 	var config Config
@@ -33,8 +44,13 @@ func LoadDefaultConfig() Config {
 
 // defaultRequester creates a new http client with a base url.
 func defaultRequester() requester {
+	return newRequester(defaultCloudFlareBaseURL)
+}
+
+// newRequester creates a new http client with the given base url.
+func newRequester(baseURL string) requester {
 	c := *http.DefaultClient
 	c.Timeout = defaultTimeout
 
-	return httpClient{baseURL: defaultCloudFlareBaseURL, httpClient: c}
+	return httpClient{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: c}
 }
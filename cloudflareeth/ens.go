@@ -0,0 +1,203 @@
+package cloudflareeth
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spankie/tw-interview/blockchain"
+)
+
+var (
+	ErrENSNameNotFound    = errors.New("ens name not found")
+	ErrInvalidENSName     = errors.New("invalid ens name")
+	ErrInvalidABIResponse = errors.New("invalid abi encoded response")
+)
+
+const (
+	// ensRegistryAddress is the address of the ENS registry on ethereum mainnet.
+	ensRegistryAddress = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+	// function selectors of the ENS registry and resolver contracts.
+	ensResolverSelector = "0178b8bf" // resolver(bytes32)
+	ensAddrSelector     = "3b3b57de" // addr(bytes32)
+	ensNameSelector     = "691f3431" // name(bytes32)
+
+	ensReverseSuffix = ".addr.reverse"
+	abiWordSize      = 32
+)
+
+// Namehash computes the ENS namehash of a name as described in EIP-137.
+// NOTE: the name is only lowercased and not fully UTS-46 normalized.
+func Namehash(name string) [32]byte {
+	var node [32]byte
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		labelHash := blockchain.Keccak256([]byte(labels[i]))
+		node = blockchain.Keccak256Hash(node[:], labelHash)
+	}
+
+	return node
+}
+
+// IsENSName reports whether name looks like an ENS name (e.g. vitalik.eth).
+func IsENSName(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.HasPrefix(name, "0x") {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return false
+		}
+	}
+
+	return strings.Contains(name, ".")
+}
+
+// ResolveName resolves an ENS name to the address it points to by querying the
+// ENS registry for the name's resolver and then the resolver for the address.
+func (c Client) ResolveName(name string) (string, error) {
+	if !IsENSName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidENSName, name)
+	}
+
+	node := Namehash(name)
+
+	resolver, err := c.ensResolver(node)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := c.Call(resolver, encodeNodeCall(ensAddrSelector, node))
+	if err != nil {
+		return "", fmt.Errorf("error resolving address of %s: %w", name, err)
+	}
+
+	address, err := decodeABIAddress(result)
+	if err != nil {
+		return "", err
+	}
+
+	if isZeroAddress(address) {
+		return "", fmt.Errorf("%w: %s has no address", ErrENSNameNotFound, name)
+	}
+
+	return address, nil
+}
+
+// LookupAddress reverse resolves an address to its primary ENS name. An empty name and a
+// nil error are returned when the address has no primary name or the primary name does not
+// resolve back to the address.
+func (c Client) LookupAddress(address string) (string, error) {
+	if !blockchain.IsValidEthereumAddress(address) {
+		return "", fmt.Errorf("%w: %q", blockchain.ErrInvalidAddress, address)
+	}
+
+	node := Namehash(strings.ToLower(strings.TrimPrefix(address, "0x")) + ensReverseSuffix)
+
+	resolver, err := c.ensResolver(node)
+	if errors.Is(err, ErrENSNameNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	result, err := c.Call(resolver, encodeNodeCall(ensNameSelector, node))
+	if err != nil {
+		return "", fmt.Errorf("error reverse resolving %s: %w", address, err)
+	}
+
+	name, err := decodeABIString(result)
+	if err != nil || name == "" {
+		return "", err
+	}
+
+	// a reverse record can be set to any name, so it is only trusted when the
+	// name resolves back to the same address.
+	resolved, err := c.ResolveName(name)
+	if errors.Is(err, ErrENSNameNotFound) || errors.Is(err, ErrInvalidENSName) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.EqualFold(resolved, address) {
+		return "", nil
+	}
+
+	return name, nil
+}
+
+// ensResolver returns the address of the resolver set for node in the ENS registry.
+func (c Client) ensResolver(node [32]byte) (string, error) {
+	result, err := c.Call(ensRegistryAddress, encodeNodeCall(ensResolverSelector, node))
+	if err != nil {
+		return "", fmt.Errorf("error querying ens registry: %w", err)
+	}
+
+	resolver, err := decodeABIAddress(result)
+	if err != nil {
+		return "", err
+	}
+
+	if isZeroAddress(resolver) {
+		return "", fmt.Errorf("%w: no resolver set", ErrENSNameNotFound)
+	}
+
+	return resolver, nil
+}
+
+// encodeNodeCall encodes the calldata of a function taking a single bytes32 argument.
+func encodeNodeCall(selector string, node [32]byte) string {
+	return "0x" + selector + hex.EncodeToString(node[:])
+}
+
+// decodeABIAddress decodes an abi encoded address return value.
+func decodeABIAddress(result string) (string, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil || len(data) < abiWordSize {
+		return "", fmt.Errorf("%w: %q", ErrInvalidABIResponse, result)
+	}
+
+	return "0x" + hex.EncodeToString(data[12:abiWordSize]), nil
+}
+
+// decodeABIString decodes an abi encoded string return value.
+func decodeABIString(result string) (string, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil || len(data) < 2*abiWordSize {
+		return "", fmt.Errorf("%w: %q", ErrInvalidABIResponse, result)
+	}
+
+	offset := new(big.Int).SetBytes(data[:abiWordSize])
+	if !offset.IsInt64() || offset.Int64() > int64(len(data)-abiWordSize) {
+		return "", fmt.Errorf("%w: string offset out of range", ErrInvalidABIResponse)
+	}
+
+	start := offset.Int64() + abiWordSize
+
+	length := new(big.Int).SetBytes(data[start-abiWordSize : start])
+	if !length.IsInt64() || start+length.Int64() > int64(len(data)) {
+		return "", fmt.Errorf("%w: string length out of range", ErrInvalidABIResponse)
+	}
+
+	return string(data[start : start+length.Int64()]), nil
+}
+
+func isZeroAddress(address string) bool {
+	return strings.Trim(strings.TrimPrefix(address, "0x"), "0") == ""
+}
@@ -0,0 +1,99 @@
+package cloudflareeth
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestNamehash(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "empty name",
+			input: "",
+			want:  "0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name:  "top level domain",
+			input: "eth",
+			want:  "93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae",
+		},
+		{
+			name:  "second level domain",
+			input: "foo.eth",
+			want:  "de9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+		},
+		{
+			name:  "mixed case name",
+			input: "Foo.ETH",
+			want:  "de9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := Namehash(tt.input)
+			if got := hex.EncodeToString(node[:]); got != tt.want {
+				t.Errorf("Namehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockCallRequester answers eth_call requests by the function selector of the calldata.
+type mockCallRequester map[string]string
+
+func (m mockCallRequester) Post(_ string, body rpcRequestBody, res any) error {
+	call, _ := body.Params[0].(map[string]string)
+
+	result, ok := m[call["data"][2:10]]
+	if !ok {
+		result = "0x"
+	}
+
+	res.(*response).Result = result
+
+	return nil
+}
+
+func abiWord(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+func TestResolveName(t *testing.T) {
+	resolver := "0x4976fb03c32e5b8cfe2b6ccb31c09ba78ebaba41"
+	address := "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+	name := "vitalik.eth"
+
+	client := NewClient(WithHTTPClient(mockCallRequester{
+		ensResolverSelector: "0x" + abiWord(resolver[2:]),
+		ensAddrSelector:     "0x" + abiWord(address[2:]),
+		ensNameSelector: "0x" + abiWord("20") + abiWord("b") +
+			hex.EncodeToString([]byte(name)) + strings.Repeat("0", 42),
+	}))
+
+	got, err := client.ResolveName(name)
+	if err != nil {
+		t.Fatalf("ResolveName() error = %v, want nil", err)
+	}
+
+	if got != address {
+		t.Errorf("ResolveName() = %v, want %v", got, address)
+	}
+
+	gotName, err := client.LookupAddress(address)
+	if err != nil {
+		t.Fatalf("LookupAddress() error = %v, want nil", err)
+	}
+
+	if gotName != name {
+		t.Errorf("LookupAddress() = %v, want %v", gotName, name)
+	}
+
+	if _, err := client.ResolveName("0x1234"); err == nil {
+		t.Errorf("ResolveName() should return an error for a non ens name")
+	}
+}
@@ -0,0 +1,90 @@
+package cloudflareeth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultENSCacheTTL = 10 * time.Minute
+
+// ENSResolver resolves ENS names to addresses and addresses to their primary names.
+type ENSResolver interface {
+	ResolveName(name string) (string, error)
+	LookupAddress(address string) (string, error)
+}
+
+type ensCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// CachedENSResolver wraps an ENSResolver and caches successful lookups, including
+// addresses without a primary name, for a fixed time to live.
+type CachedENSResolver struct {
+	resolver ENSResolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	names   map[string]ensCacheEntry
+	reverse map[string]ensCacheEntry
+}
+
+// NewCachedENSResolver creates a caching resolver. a ttl of zero uses the default ttl.
+func NewCachedENSResolver(resolver ENSResolver, ttl time.Duration) *CachedENSResolver {
+	if ttl <= 0 {
+		ttl = defaultENSCacheTTL
+	}
+
+	return &CachedENSResolver{
+		resolver: resolver,
+		ttl:      ttl,
+		now:      time.Now,
+		names:    make(map[string]ensCacheEntry),
+		reverse:  make(map[string]ensCacheEntry),
+	}
+}
+
+// ResolveName resolves an ENS name to an address, serving it from the cache when possible.
+func (c *CachedENSResolver) ResolveName(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+
+	return c.lookup(c.names, key, func() (string, error) {
+		return c.resolver.ResolveName(key)
+	})
+}
+
+// LookupAddress reverse resolves an address, serving it from the cache when possible.
+func (c *CachedENSResolver) LookupAddress(address string) (string, error) {
+	key := strings.ToLower(address)
+
+	return c.lookup(c.reverse, key, func() (string, error) {
+		return c.resolver.LookupAddress(address)
+	})
+}
+
+func (c *CachedENSResolver) lookup(cache map[string]ensCacheEntry, key string,
+	resolve func() (string, error),
+) (string, error) {
+	c.mu.Lock()
+	entry, ok := cache[key]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	// NOTE: the lock is not held while resolving so a slow node does not block
+	// other lookups. concurrent misses for the same key may resolve twice.
+	value, err := resolve()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	cache[key] = ensCacheEntry{value: value, expiresAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return value, nil
+}
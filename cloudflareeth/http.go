@@ -20,9 +20,20 @@ type rpcRequestBody struct {
 }
 
 type response struct {
-	ID      int    `json:"id"`
-	JSONRPC string `json:"jsonrpc"`
-	Result  any    `json:"result"`
+	ID      int       `json:"id"`
+	JSONRPC string    `json:"jsonrpc"`
+	Result  any       `json:"result"`
+	Error   *rpcError `json:"error,omitempty"`
+}
+
+// rpcError is the error object returned by a json-rpc node when a call fails.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
 }
 
 type httpClient struct {
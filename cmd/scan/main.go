@@ -0,0 +1,153 @@
+// Command scan scans a range of blocks for the activity of a list of addresses and exits.
+//
+//	scan -from 18000000 -to 18100000 -addresses addresses.txt -out results.json
+//
+// the addresses file has one address or ENS name per line, blank lines and lines starting
+// with # are ignored. the results are written as json to stdout unless -out is set.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spankie/tw-interview/blockparser"
+	"github.com/spankie/tw-interview/cloudflareeth"
+)
+
+var errNoAddresses = errors.New("no addresses to scan")
+
+// results is the output of a scan: its summary and the activity of every address.
+type results struct {
+	Summary  blockparser.ScanSummary           `json:"summary"`
+	Error    string                            `json:"error,omitempty"`
+	Activity map[string][]blockparser.Activity `json:"activity"`
+}
+
+// readAddresses reads the addresses in the file, one per line.
+func readAddresses(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open addresses file: %w", err)
+	}
+	defer file.Close()
+
+	addresses := make([]string, 0)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		addresses = append(addresses, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read addresses file: %w", err)
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w in %s", errNoAddresses, path)
+	}
+
+	return addresses, nil
+}
+
+func writeResults(path string, res results) error {
+	var out io.Writer = os.Stdout
+
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create output file: %w", err)
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(res); err != nil {
+		return fmt.Errorf("could not write results: %w", err)
+	}
+
+	return nil
+}
+
+func main() {
+	from := flag.Int64("from", -1, "first block to scan")
+	to := flag.Int64("to", -1, "last block to scan")
+	addressesPath := flag.String("addresses", "", "file with the addresses to scan for, one per line")
+	outPath := flag.String("out", "", "file to write the results to, stdout when empty")
+	workers := flag.Int("workers", 0, "number of blocks fetched concurrently")
+	halt := flag.Bool("halt", false, "stop at the first block that cannot be fetched")
+	flag.Parse()
+
+	if *addressesPath == "" || *from < 0 || *to < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// logs go to stderr so they do not mix with results written to stdout.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	addresses, err := readAddresses(*addressesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := cloudflareeth.NewClient()
+	parserOpts := []blockparser.ConfigOptionResolver{
+		blockparser.WithBlockchainQuerier(client),
+		blockparser.WithFetchWorkers(*workers),
+	}
+
+	if *halt {
+		parserOpts = append(parserOpts, blockparser.WithFailedBlockPolicy(blockparser.HaltOnFailedBlock))
+	}
+
+	parser := blockparser.NewBlockParser(parserOpts...)
+
+	for _, address := range addresses {
+		if !parser.Subscribe(address) {
+			log.Fatalf("could not subscribe to address %s", address)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	summary, scanErr := parser.ScanRange(ctx, *from, *to)
+
+	res := results{Summary: summary, Activity: make(map[string][]blockparser.Activity, len(addresses))}
+	if scanErr != nil {
+		res.Error = scanErr.Error()
+	}
+
+	for _, address := range addresses {
+		res.Activity[address] = parser.GetActivity(address)
+	}
+
+	if err := writeResults(*outPath, res); err != nil {
+		log.Fatal(err)
+	}
+
+	if scanErr != nil {
+		slog.Error(fmt.Sprintf("scan failed: %v", scanErr))
+		os.Exit(1)
+	}
+}
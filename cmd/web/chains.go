@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spankie/tw-interview/blockparser"
+	"github.com/spankie/tw-interview/cloudflareeth"
+)
+
+// ethereumChainID is the id of ethereum mainnet, whose node resolves ENS names when it is followed.
+const ethereumChainID = 1
+
+// chainConfig is a chain to follow and the json-rpc node of the chain.
+type chainConfig struct {
+	id     uint64
+	rpcURL string
+}
+
+// chainConfigs reads the chains to follow from TW_CHAINS, a comma separated list of
+// chainId=rpcURL entries, e.g. 1=https://cloudflare-eth.com,137=https://polygon-rpc.com.
+// TW_CHECKPOINT_FILE is rejected with TW_CHAINS, each chain has its own checkpoint file.
+func chainConfigs() ([]chainConfig, error) {
+	configs := make([]chainConfig, 0)
+
+	for _, entry := range strings.Split(os.Getenv("TW_CHAINS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		idStr, rpcURL, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(rpcURL) == "" {
+			return nil, fmt.Errorf("invalid chain %q, want chainId=rpcURL", entry)
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid chain id %q", idStr)
+		}
+
+		configs = append(configs, chainConfig{id: id, rpcURL: strings.TrimSpace(rpcURL)})
+	}
+
+	if len(configs) > 0 && os.Getenv("TW_CHECKPOINT_FILE") != "" {
+		return nil, errors.New("with TW_CHAINS set, use TW_CHAIN_<chainId>_CHECKPOINT_FILE instead of TW_CHECKPOINT_FILE")
+	}
+
+	return configs, nil
+}
+
+// chainParserOptions are the options of the parser of a chain on top of the ones shared by
+// every chain: its node, and the scanning interval and checkpoint file set for the chain
+// with TW_CHAIN_<chainId>_SCANNING_INTERVAL and TW_CHAIN_<chainId>_CHECKPOINT_FILE.
+func chainParserOptions(chain chainConfig, client *cloudflareeth.Client) []blockparser.ConfigOptionResolver {
+	opts := []blockparser.ConfigOptionResolver{
+		blockparser.WithChainID(chain.id),
+		blockparser.WithBlockchainQuerier(client),
+		blockparser.WithLogger(slog.Default().With("chainId", chain.id)),
+	}
+
+	prefix := fmt.Sprintf("TW_CHAIN_%d_", chain.id)
+
+	if interval, err := time.ParseDuration(os.Getenv(prefix + "SCANNING_INTERVAL")); err == nil {
+		opts = append(opts, blockparser.WithScanningInterval(interval))
+	}
+
+	if path := os.Getenv(prefix + "CHECKPOINT_FILE"); path != "" {
+		opts = append(opts, blockparser.WithCheckpointStore(blockparser.NewFileCheckpointStore(path)))
+	}
+
+	return opts
+}
+
+// chainNames returns the lookup of the primary names of the addresses of the chain, nil for
+// the chains other than ethereum where ENS names do not apply.
+func chainNames(chainID uint64, names addressLookup) addressLookup {
+	if chainID != ethereumChainID {
+		return nil
+	}
+
+	return names
+}
+
+// ensClient returns the client resolving ENS names: the node of ethereum when it is one of
+// the chains followed, cloudflare otherwise.
+func ensClient(configs []chainConfig) *cloudflareeth.Client {
+	for _, chain := range configs {
+		if chain.id == ethereumChainID {
+			return cloudflareeth.NewClient(cloudflareeth.WithRPCURL(chain.rpcURL))
+		}
+	}
+
+	return cloudflareeth.NewClient()
+}
+
+// loadChains creates the parsers of the chains and checks their nodes serve the configured chain.
+func loadChains(configs []chainConfig, parserOpts []blockparser.ConfigOptionResolver) (*blockparser.Chains, error) {
+	chains := blockparser.NewChains()
+
+	for _, chain := range configs {
+		client := cloudflareeth.NewClient(cloudflareeth.WithRPCURL(chain.rpcURL))
+		opts := append(slices.Clone(parserOpts), chainParserOptions(chain, client)...)
+
+		if err := chains.Add(blockparser.NewBlockParser(opts...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return chains, nil
+}
@@ -9,10 +9,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/spankie/tw-interview/abi"
 	"github.com/spankie/tw-interview/blockparser"
+	"github.com/spankie/tw-interview/cloudflareeth"
+	"github.com/spankie/tw-interview/webhook"
 )
 
 func gracefulShutdown(ctx context.Context, apiServer *http.Server) {
@@ -69,18 +74,182 @@ func run(ctx context.Context, apiServer *http.Server) {
 	gracefulShutdown(ctx, apiServer)
 }
 
+// ensCacheTTL reads how long resolved ENS names are cached from the environment.
+func ensCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("TW_ENS_CACHE_TTL"))
+	if err != nil {
+		return 0
+	}
+
+	return ttl
+}
+
+// watchedTopics reads the comma separated event topics to track logs of from the environment.
+func watchedTopics() []string {
+	topics := make([]string, 0)
+
+	for _, topic := range strings.Split(os.Getenv("TW_WATCHED_TOPICS"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// loadABIRegistry loads the known function signatures, including the ones in the file
+// TW_ABI_SIGNATURES points to if it is set.
+func loadABIRegistry() (*abi.Registry, error) {
+	path := os.Getenv("TW_ABI_SIGNATURES")
+	if path == "" {
+		return abi.NewRegistry()
+	}
+
+	return abi.LoadRegistry(path)
+}
+
 func main() {
 	err := configureLogger(os.Getenv("TW_LOG_LEVEL"))
 	if err != nil {
 		log.Fatalf("could not configure logger: %v", err)
 	}
 
-	blockParser := blockparser.NewBlockParser()
+	calls, err := loadABIRegistry()
+	if err != nil {
+		log.Fatalf("could not load abi signatures: %v", err)
+	}
+
+	configs, err := chainConfigs()
+	if err != nil {
+		log.Fatalf("could not read chains: %v", err)
+	}
+
+	client := ensClient(configs)
+	names := cloudflareeth.NewCachedENSResolver(client, ensCacheTTL())
+
+	parserOpts := []blockparser.ConfigOptionResolver{
+		blockparser.WithNameResolver(names),
+	}
+
+	if os.Getenv("TW_VERIFY_TRANSACTIONS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithTransactionVerification())
+	}
+
+	if os.Getenv("TW_VERIFY_BLOCKS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithBlockVerification())
+	}
+
+	if window, err := strconv.Atoi(os.Getenv("TW_REORG_WINDOW")); err == nil {
+		parserOpts = append(parserOpts, blockparser.WithReorgWindow(window))
+	}
+
+	if value := os.Getenv("TW_CONFIRMATION_DEPTH"); value != "" {
+		depth, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid TW_CONFIRMATION_DEPTH %q, want 0 or more confirmations", value)
+		}
+
+		parserOpts = append(parserOpts, blockparser.WithConfirmationDepth(depth))
+	}
+
+	if os.Getenv("TW_FOLLOW_FINALIZED") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithFinalizedTag())
+	}
+
+	if workers, err := strconv.Atoi(os.Getenv("TW_FETCH_WORKERS")); err == nil {
+		parserOpts = append(parserOpts, blockparser.WithFetchWorkers(workers))
+	}
+
+	if os.Getenv("TW_HALT_ON_FAILED_BLOCK") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithFailedBlockPolicy(blockparser.HaltOnFailedBlock))
+	}
+
+	if blocks, err := strconv.ParseInt(os.Getenv("TW_MAX_CATCH_UP"), 10, 64); err == nil {
+		policy := blockparser.CatchUpToLimit
+		if os.Getenv("TW_CATCH_UP_SKIP_TO_HEAD") == "true" {
+			policy = blockparser.SkipToHead
+		}
+
+		parserOpts = append(parserOpts, blockparser.WithCatchUpLimit(blocks, policy))
+	}
+
+	if os.Getenv("TW_WATCH_PREFILTER") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithWatchPrefilter())
+	}
+
+	if os.Getenv("TW_PURGE_UNSUBSCRIBED_HISTORY") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithHistoryPolicy(blockparser.PurgeHistory))
+	}
+
+	if os.Getenv("TW_AUTO_SUBSCRIBE_CONTRACTS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithContractAutoSubscribe())
+	}
+
+	if os.Getenv("TW_TRACK_TOKEN_TRANSFERS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithTokenTransferTracking())
+	}
+
+	if os.Getenv("TW_TRACK_NFTS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithNFTTracking())
+	}
+
+	if os.Getenv("TW_TRACK_LOGS") == "true" {
+		parserOpts = append(parserOpts, blockparser.WithLogTracking(watchedTopics()...))
+	}
+
+	chains, err := loadChains(configs, parserOpts)
+	if err != nil {
+		log.Fatalf("could not load chains: %v", err)
+	}
+
+	// without TW_CHAINS a single parser follows ethereum through cloudflare, otherwise the
+	// routes without a chain prefix are served by the first chain.
+	parsers := make([]*blockparser.Parser, 0, len(configs))
+
+	for _, chain := range configs {
+		parser, err := chains.Parser(chain.id)
+		if err != nil {
+			log.Fatalf("could not load chains: %v", err)
+		}
+
+		parsers = append(parsers, parser)
+	}
+
+	if len(parsers) == 0 {
+		parserOpts = append(parserOpts, blockparser.WithBlockchainQuerier(client))
+		if path := os.Getenv("TW_CHECKPOINT_FILE"); path != "" {
+			parserOpts = append(parserOpts, blockparser.WithCheckpointStore(blockparser.NewFileCheckpointStore(path)))
+		}
+
+		parsers = append(parsers, blockparser.NewBlockParser(parserOpts...))
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	blockParser.StartBlockScanning(ctx)
+	webhookOpts := []webhook.ConfigOptionResolver{}
+	if attempts, err := strconv.Atoi(os.Getenv("TW_WEBHOOK_MAX_ATTEMPTS")); err == nil {
+		webhookOpts = append(webhookOpts, webhook.WithMaxAttempts(attempts))
+	}
+
+	webhooks := webhook.NewDispatcher(webhookOpts...)
+	webhooks.Start(ctx)
+
+	for _, parser := range parsers {
+		webhooks.Observe(parser)
+		parser.StartBlockScanning(ctx)
+	}
 
-	run(ctx, newServer(blockParser))
+	// the routes without a chain prefix look up ENS names unless they serve another chain.
+	var serverNames addressLookup = names
+	if len(configs) > 0 {
+		serverNames = chainNames(configs[0].id, names)
+	}
+
+	run(ctx, newServer(parsers[0], chains, serverNames, calls, webhooks))
+
+	// the subscription changes made since the last scan are checkpointed before exiting.
+	for _, parser := range parsers {
+		parser.SaveCheckpoint()
+	}
 }
@@ -1,18 +1,75 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/spankie/tw-interview/abi"
+	"github.com/spankie/tw-interview/blockchain"
 	"github.com/spankie/tw-interview/blockparser"
+	"github.com/spankie/tw-interview/webhook"
 )
 
+// addressLookup reverse resolves addresses to their primary names.
+type addressLookup interface {
+	LookupAddress(address string) (string, error)
+}
+
 type Server struct {
 	parser blockparser.BlockParser
+	// chains are the parsers of the chains served under /chains/{chainId}, parser serves the
+	// routes without a chain prefix.
+	chains   *blockparser.Chains
+	names    addressLookup
+	calls    *abi.Registry
+	webhooks *webhook.Dispatcher
+	// adminToken is the bearer token required by the admin endpoints, which are disabled when it is empty.
+	adminToken string
+}
+
+// transactionView is a transaction as rendered by the api, with checksummed addresses, its
+// confirmations, its direction and optionally the primary names of its counterparties and its decoded calldata.
+type transactionView struct {
+	blockchain.Transaction
+	Confirmations uint64                         `json:"confirmations"`
+	Status        blockparser.ConfirmationStatus `json:"status"`
+	Direction     blockparser.Direction          `json:"direction,omitempty"`
+	Counterparty  string                         `json:"counterparty,omitempty"`
+	FromName      string                         `json:"fromName,omitempty"`
+	ToName        string                         `json:"toName,omitempty"`
+	Call          *abi.Call                      `json:"call,omitempty"`
+}
+
+// transferView is a token transfer as rendered by the api, with checksummed addresses, the
+// block it was included in, its confirmations and its direction.
+type transferView struct {
+	blockchain.TokenTransfer
+	BlockNumber   uint64                         `json:"blockNumber"`
+	BlockHash     string                         `json:"blockHash"`
+	Confirmations uint64                         `json:"confirmations"`
+	Status        blockparser.ConfirmationStatus `json:"status"`
+	Direction     blockparser.Direction          `json:"direction"`
+	Counterparty  string                         `json:"counterparty"`
+}
+
+// nftTransferView is an NFT transfer as rendered by the api, with checksummed addresses, the
+// block it was included in, its confirmations and its direction.
+type nftTransferView struct {
+	blockchain.NFTTransfer
+	BlockNumber   uint64                         `json:"blockNumber"`
+	BlockHash     string                         `json:"blockHash"`
+	Confirmations uint64                         `json:"confirmations"`
+	Status        blockparser.ConfirmationStatus `json:"status"`
+	Direction     blockparser.Direction          `json:"direction"`
+	Counterparty  string                         `json:"counterparty"`
 }
 
 type response struct {
@@ -31,15 +88,48 @@ func respond(w http.ResponseWriter, status int, res any) {
 	}
 }
 
-func newServer(blockParser blockparser.BlockParser) *http.Server {
+func newServer(blockParser blockparser.BlockParser, chains *blockparser.Chains, names addressLookup,
+	calls *abi.Registry, webhooks *webhook.Dispatcher,
+) *http.Server {
 	server := &Server{
-		parser: blockParser,
+		parser:     blockParser,
+		chains:     chains,
+		names:      names,
+		calls:      calls,
+		webhooks:   webhooks,
+		adminToken: os.Getenv("TW_ADMIN_TOKEN"),
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /block", server.getCurrentBlockNumber)
-	mux.HandleFunc("GET /transactions/{address}", server.getTransactionsByAddress)
-	mux.HandleFunc("GET /subscribe/{address}", server.subscribeToAddress)
+	server.handleParserRoutes(mux)
+
+	// the routes of every chain are namespaced by its id, e.g. /chains/137/transactions/{address}.
+	for _, chainID := range chains.IDs() {
+		parser, err := chains.Parser(chainID)
+		if err != nil {
+			continue
+		}
+
+		chainServer := &Server{parser: parser, names: chainNames(chainID, names), calls: calls}
+		chainMux := http.NewServeMux()
+		chainServer.handleParserRoutes(chainMux)
+
+		prefix := fmt.Sprintf("/chains/%d", chainID)
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, chainMux))
+	}
+
+	mux.HandleFunc("GET /chains", server.getChains)
+
+	// the admin endpoints are only served when a token protects them.
+	if server.adminToken != "" {
+		mux.HandleFunc("POST /webhooks", server.admin(server.addWebhook))
+		mux.HandleFunc("GET /webhooks", server.admin(server.getWebhooks))
+		mux.HandleFunc("DELETE /webhooks/{id}", server.admin(server.removeWebhook))
+		mux.HandleFunc("GET /webhooks/dead-letters", server.admin(server.getDeadLetters))
+		mux.HandleFunc("POST /webhooks/dead-letters/{id}/redeliver", server.admin(server.redeliverWebhook))
+	} else {
+		slog.Warn("TW_ADMIN_TOKEN is not set, the webhook endpoints are disabled")
+	}
 
 	port := os.Getenv("TW_PORT")
 	if port == "" {
@@ -54,6 +144,53 @@ func newServer(blockParser blockparser.BlockParser) *http.Server {
 	}
 }
 
+// handleParserRoutes registers the routes served by the parser of the server.
+func (s *Server) handleParserRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /block", s.getCurrentBlockNumber)
+	mux.HandleFunc("GET /transactions/{address}", s.getTransactionsByAddress)
+	mux.HandleFunc("GET /activity/{address}", s.getActivityByAddress)
+	mux.HandleFunc("GET /transfers/{address}", s.getTransfersByAddress)
+	mux.HandleFunc("GET /nfts/{address}", s.getNFTTransfersByAddress)
+	mux.HandleFunc("GET /nfts/{address}/holdings", s.getNFTHoldings)
+	mux.HandleFunc("GET /subscribe/{address}", s.subscribeToAddress)
+	mux.HandleFunc("POST /subscribe/{address}", s.subscribeToAddress)
+	mux.HandleFunc("PATCH /subscribe/{address}", s.updateSubscription)
+	mux.HandleFunc("DELETE /subscribe/{address}", s.unsubscribeFromAddress)
+	mux.HandleFunc("GET /subscriptions", s.getSubscriptions)
+	mux.HandleFunc("GET /subscriptions/{address}", s.getSubscription)
+	mux.HandleFunc("GET /stats/bloom", s.getBloomStats)
+	mux.HandleFunc("GET /gaps", s.getGaps)
+	mux.HandleFunc("POST /subscriptions/{address}/backfill", s.startBackfill)
+	mux.HandleFunc("GET /subscriptions/{address}/backfill", s.getBackfill)
+	mux.HandleFunc("DELETE /subscriptions/{address}/backfill", s.cancelBackfill)
+}
+
+// chainView is a chain followed by the server and the last block scanned on it.
+type chainView struct {
+	ChainID      uint64 `json:"chainId"`
+	CurrentBlock int    `json:"currentBlock"`
+}
+
+// getChains lists the chains whose routes are served under /chains/{chainId}.
+func (s *Server) getChains(w http.ResponseWriter, _ *http.Request) {
+	views := make([]chainView, 0)
+
+	for _, chainID := range s.chains.IDs() {
+		parser, err := s.chains.Parser(chainID)
+		if err != nil {
+			continue
+		}
+
+		views = append(views, chainView{ChainID: chainID, CurrentBlock: parser.GetCurrentBlock()})
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    views,
+		Error:   "",
+	})
+}
+
 func (s *Server) getCurrentBlockNumber(w http.ResponseWriter, _ *http.Request) {
 	respond(w, http.StatusOK, response{
 		Message: "success",
@@ -64,16 +201,373 @@ func (s *Server) getCurrentBlockNumber(w http.ResponseWriter, _ *http.Request) {
 
 func (s *Server) getTransactionsByAddress(w http.ResponseWriter, r *http.Request) {
 	address := r.PathValue("address")
+	query := r.URL.Query()
+
+	opts, err := queryOptions(r)
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	views := s.transactionViews(s.parser.GetActivity(address, opts...),
+		query.Get("names") == "true", query.Get("decode") == "true")
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    views,
+		Error:   "",
+	})
+}
+
+// queryOptions reads the activity filters of a request: the minConfirmations, direction and
+// token query parameters.
+func queryOptions(r *http.Request) ([]blockparser.QueryOption, error) {
+	opts := make([]blockparser.QueryOption, 0)
+
+	switch direction := blockparser.Direction(r.URL.Query().Get("direction")); direction {
+	case "":
+	case blockparser.DirectionIn, blockparser.DirectionOut, blockparser.DirectionSelf:
+		opts = append(opts, blockparser.InDirection(direction))
+	default:
+		return nil, fmt.Errorf("invalid direction %q", direction)
+	}
+
+	if value := r.URL.Query().Get("minConfirmations"); value != "" {
+		confirmations, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minConfirmations %q", value)
+		}
+
+		opts = append(opts, blockparser.MinConfirmations(confirmations))
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		if _, err := blockchain.ParseAddress(token); err != nil {
+			return nil, fmt.Errorf("invalid token %q", token)
+		}
+
+		opts = append(opts, blockparser.OfToken(token))
+	}
+
+	return opts, nil
+}
+
+// transactionViews renders the transactions of the activity with their addresses in their
+// checksummed form and optionally attaches the primary names of the counterparties and
+// the decoded calldata. names that cannot be looked up and calldata that cannot be decoded
+// are left out.
+func (s *Server) transactionViews(activity []blockparser.Activity, withNames, withCalls bool) []transactionView {
+	views := make([]transactionView, 0, len(activity))
+
+	for _, entry := range activity {
+		if entry.Kind != blockparser.ActivityKindTransaction {
+			continue
+		}
+
+		transaction := *entry.Transaction
+		view := transactionView{
+			Transaction:   transaction,
+			Confirmations: entry.Confirmations,
+			Status:        entry.Status,
+			Direction:     entry.Direction,
+			Counterparty:  blockchain.ChecksumAddress(entry.Counterparty),
+		}
+		view.From = blockchain.ChecksumAddress(transaction.From)
+		view.To = blockchain.ChecksumAddress(transaction.To)
+
+		if withNames {
+			view.FromName = s.lookupName(transaction.From)
+			view.ToName = s.lookupName(transaction.To)
+		}
+
+		if withCalls {
+			view.Call = s.decodeCall(transaction)
+		}
+
+		views = append(views, view)
+	}
+
+	return views
+}
+
+// getTransfersByAddress returns the ERC-20 token transfers sent or received by an address,
+// optionally of a single token.
+func (s *Server) getTransfersByAddress(w http.ResponseWriter, r *http.Request) {
+	opts, err := queryOptions(r)
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	views := make([]transferView, 0)
+
+	for _, entry := range s.parser.GetActivity(r.PathValue("address"), opts...) {
+		if entry.Kind != blockparser.ActivityKindTokenTransfer {
+			continue
+		}
+
+		entry = checksummedActivity(entry)
+
+		views = append(views, transferView{
+			TokenTransfer: *entry.TokenTransfer,
+			BlockNumber:   entry.BlockNumber,
+			BlockHash:     entry.BlockHash,
+			Confirmations: entry.Confirmations,
+			Status:        entry.Status,
+			Direction:     entry.Direction,
+			Counterparty:  entry.Counterparty,
+		})
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    views,
+		Error:   "",
+	})
+}
+
+// getNFTTransfersByAddress returns the ERC-721 and ERC-1155 transfers sent or received by an
+// address, optionally of a single contract.
+func (s *Server) getNFTTransfersByAddress(w http.ResponseWriter, r *http.Request) {
+	opts, err := queryOptions(r)
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	views := make([]nftTransferView, 0)
+
+	for _, entry := range s.parser.GetActivity(r.PathValue("address"), opts...) {
+		if entry.Kind != blockparser.ActivityKindNFTTransfer {
+			continue
+		}
+
+		entry = checksummedActivity(entry)
+
+		views = append(views, nftTransferView{
+			NFTTransfer:   *entry.NFTTransfer,
+			BlockNumber:   entry.BlockNumber,
+			BlockHash:     entry.BlockHash,
+			Confirmations: entry.Confirmations,
+			Status:        entry.Status,
+			Direction:     entry.Direction,
+			Counterparty:  entry.Counterparty,
+		})
+	}
+
 	respond(w, http.StatusOK, response{
 		Message: "success",
-		Data:    s.parser.GetTransactions(address),
+		Data:    views,
 		Error:   "",
 	})
 }
 
+// getNFTHoldings returns the NFTs held by an address according to the transfers scanned.
+func (s *Server) getNFTHoldings(w http.ResponseWriter, r *http.Request) {
+	holdings := s.parser.GetNFTHoldings(r.PathValue("address"))
+	if holdings == nil {
+		holdings = []blockparser.NFTHolding{}
+	}
+
+	for i := range holdings {
+		holdings[i].Contract = blockchain.ChecksumAddress(holdings[i].Contract)
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    holdings,
+		Error:   "",
+	})
+}
+
+// getActivityByAddress returns the transactions and withdrawals of an address. the
+// optional kind query parameter limits the response to one kind of activity.
+func (s *Server) getActivityByAddress(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	kind := blockparser.ActivityKind(r.URL.Query().Get("kind"))
+
+	opts, err := queryOptions(r)
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	activity := make([]blockparser.Activity, 0)
+
+	for _, entry := range s.parser.GetActivity(address, opts...) {
+		if kind != "" && entry.Kind != kind {
+			continue
+		}
+
+		activity = append(activity, checksummedActivity(entry))
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    activity,
+		Error:   "",
+	})
+}
+
+// checksummedActivity returns a copy of the activity with its addresses checksummed.
+func checksummedActivity(activity blockparser.Activity) blockparser.Activity {
+	if activity.Transaction != nil {
+		transaction := *activity.Transaction
+		transaction.From = blockchain.ChecksumAddress(transaction.From)
+		transaction.To = blockchain.ChecksumAddress(transaction.To)
+		activity.Transaction = &transaction
+	}
+
+	if activity.Withdrawal != nil {
+		withdrawal := *activity.Withdrawal
+		withdrawal.Address = blockchain.ChecksumAddress(withdrawal.Address)
+		activity.Withdrawal = &withdrawal
+	}
+
+	if activity.ContractAddress != "" {
+		activity.ContractAddress = blockchain.ChecksumAddress(activity.ContractAddress)
+	}
+
+	if activity.Counterparty != "" {
+		activity.Counterparty = blockchain.ChecksumAddress(activity.Counterparty)
+	}
+
+	if activity.Log != nil {
+		log := *activity.Log
+		log.Address = blockchain.ChecksumAddress(log.Address)
+		activity.Log = &log
+	}
+
+	if activity.TokenTransfer != nil {
+		transfer := *activity.TokenTransfer
+		transfer.Token = blockchain.ChecksumAddress(transfer.Token)
+		transfer.From = blockchain.ChecksumAddress(transfer.From)
+		transfer.To = blockchain.ChecksumAddress(transfer.To)
+		activity.TokenTransfer = &transfer
+	}
+
+	if activity.NFTTransfer != nil {
+		transfer := *activity.NFTTransfer
+		transfer.Contract = blockchain.ChecksumAddress(transfer.Contract)
+		transfer.From = blockchain.ChecksumAddress(transfer.From)
+		transfer.To = blockchain.ChecksumAddress(transfer.To)
+
+		if transfer.Operator != "" {
+			transfer.Operator = blockchain.ChecksumAddress(transfer.Operator)
+		}
+
+		activity.NFTTransfer = &transfer
+	}
+
+	return activity
+}
+
+// getBloomStats returns how often the logs bloom of scanned blocks let the parser skip
+// fetching their receipts.
+func (s *Server) getBloomStats(w http.ResponseWriter, _ *http.Request) {
+	stats := s.parser.BloomStats()
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data: map[string]any{
+			"stats":             stats,
+			"hitRate":           stats.HitRate(),
+			"falsePositiveRate": stats.FalsePositiveRate(),
+		},
+		Error: "",
+	})
+}
+
+// getGaps returns the scanned blocks that could not be fetched yet.
+func (s *Server) getGaps(w http.ResponseWriter, _ *http.Request) {
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    s.parser.Gaps(),
+		Error:   "",
+	})
+}
+
+func (s *Server) lookupName(address string) string {
+	if s.names == nil || address == "" {
+		return ""
+	}
+
+	name, err := s.names.LookupAddress(address)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("could not lookup name of %s: %v", address, err))
+		return ""
+	}
+
+	return name
+}
+
+func (s *Server) decodeCall(transaction blockchain.Transaction) *abi.Call {
+	if s.calls == nil || transaction.Input == "" || transaction.Input == "0x" {
+		return nil
+	}
+
+	call, err := s.calls.DecodeCalldata(transaction.Input)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("could not decode calldata of %s: %v", transaction.Hash, err))
+		return nil
+	}
+
+	return call
+}
+
 func (s *Server) subscribeToAddress(responseWriter http.ResponseWriter, request *http.Request) {
 	address := request.PathValue("address")
-	if !s.parser.Subscribe(address) {
+
+	opts := make([]blockparser.SubscriptionOption, 0)
+
+	if value := request.URL.Query().Get("ttl"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			respond(responseWriter, http.StatusBadRequest, response{
+				Message: "",
+				Data:    "",
+				Error:   fmt.Sprintf("invalid ttl %q", value),
+			})
+
+			return
+		}
+
+		opts = append(opts, blockparser.ExpiresAfter(ttl))
+	}
+
+	// the history of the address is backfilled when the request asks for it.
+	start, err := backfillStart(request)
+	if err != nil {
+		respond(responseWriter, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	if !s.parser.Subscribe(address, opts...) {
 		respond(responseWriter, http.StatusBadRequest, response{
 			Message: "",
 			Data:    "",
@@ -83,9 +577,382 @@ func (s *Server) subscribeToAddress(responseWriter http.ResponseWriter, request
 		return
 	}
 
+	if start == nil {
+		respond(responseWriter, http.StatusOK, response{
+			Message: "Subscribed to address: " + address,
+			Data:    "",
+			Error:   "",
+		})
+
+		return
+	}
+
+	progress, err := s.parser.Backfill(address, start)
+	if err != nil {
+		// the address stays subscribed, only its history is not backfilled.
+		respond(responseWriter, backfillErrorStatus(err), response{
+			Message: "Subscribed to address: " + address,
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
 	respond(responseWriter, http.StatusOK, response{
 		Message: "Subscribed to address: " + address,
+		Data:    progress,
+		Error:   "",
+	})
+}
+
+// backfillStart reads where a backfill starts from the request: the fromBlock, blocks or
+// since (RFC 3339) query parameter. it returns nil when none is set.
+func backfillStart(r *http.Request) (blockparser.BackfillStart, error) {
+	query := r.URL.Query()
+
+	switch {
+	case query.Get("fromBlock") != "":
+		blockNumber, err := strconv.ParseInt(query.Get("fromBlock"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fromBlock %q", query.Get("fromBlock"))
+		}
+
+		return blockparser.BackfillFromBlock(blockNumber), nil
+	case query.Get("blocks") != "":
+		blocks, err := strconv.ParseInt(query.Get("blocks"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocks %q", query.Get("blocks"))
+		}
+
+		return blockparser.BackfillBlocks(blocks), nil
+	case query.Get("since") != "":
+		since, err := time.Parse(time.RFC3339, query.Get("since"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid since %q", query.Get("since"))
+		}
+
+		return blockparser.BackfillSince(since), nil
+	default:
+		return nil, nil
+	}
+}
+
+// startBackfill starts scanning past blocks for the activity of a subscribed address.
+func (s *Server) startBackfill(w http.ResponseWriter, r *http.Request) {
+	start, err := backfillStart(r)
+	if err == nil && start == nil {
+		err = errors.New("one of fromBlock, blocks or since is required")
+	}
+
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	progress, err := s.parser.Backfill(r.PathValue("address"), start)
+	if err != nil {
+		respond(w, backfillErrorStatus(err), response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusAccepted, response{
+		Message: "backfill started",
+		Data:    progress,
+		Error:   "",
+	})
+}
+
+// getBackfill returns the progress of the last backfill of an address.
+func (s *Server) getBackfill(w http.ResponseWriter, r *http.Request) {
+	progress, err := s.parser.BackfillProgress(r.PathValue("address"))
+	if err != nil {
+		respond(w, backfillErrorStatus(err), response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    progress,
+		Error:   "",
+	})
+}
+
+// cancelBackfill stops the running backfill of an address.
+func (s *Server) cancelBackfill(w http.ResponseWriter, r *http.Request) {
+	progress, err := s.parser.CancelBackfill(r.PathValue("address"))
+	if err != nil {
+		respond(w, backfillErrorStatus(err), response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "backfill cancelled",
+		Data:    progress,
+		Error:   "",
+	})
+}
+
+func backfillErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, blockparser.ErrNotSubscribed), errors.Is(err, blockparser.ErrBackfillNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, blockparser.ErrBackfillRunning):
+		return http.StatusConflict
+	case errors.Is(err, blockparser.ErrInvalidBackfill):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// subscriptionPatch is the body of a subscription update, unset fields are left unchanged.
+type subscriptionPatch struct {
+	Paused *bool `json:"paused"`
+	// TTL is a duration such as "24h" after which the subscription expires, "0" to never expire.
+	TTL *string `json:"ttl"`
+}
+
+// updateSubscription pauses, resumes or changes the expiry of the subscription of an address.
+func (s *Server) updateSubscription(w http.ResponseWriter, r *http.Request) {
+	var patch subscriptionPatch
+
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   fmt.Sprintf("invalid subscription update: %v", err),
+		})
+
+		return
+	}
+
+	opts := make([]blockparser.SubscriptionOption, 0)
+
+	if patch.Paused != nil {
+		opts = append(opts, blockparser.Paused(*patch.Paused))
+	}
+
+	if patch.TTL != nil {
+		ttl, err := time.ParseDuration(*patch.TTL)
+		if err != nil || ttl < 0 {
+			respond(w, http.StatusBadRequest, response{
+				Message: "",
+				Data:    "",
+				Error:   fmt.Sprintf("invalid ttl %q", *patch.TTL),
+			})
+
+			return
+		}
+
+		opts = append(opts, blockparser.ExpiresAfter(ttl))
+	}
+
+	subscription, err := s.parser.UpdateSubscription(r.PathValue("address"), opts...)
+	if err != nil {
+		respond(w, http.StatusNotFound, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "subscription updated",
+		Data:    checksummedSubscription(subscription),
+		Error:   "",
+	})
+}
+
+func (s *Server) unsubscribeFromAddress(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if !s.parser.Unsubscribe(address) {
+		respond(w, http.StatusNotFound, response{
+			Message: "",
+			Data:    "",
+			Error:   "address is not subscribed",
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "Unsubscribed from address: " + address,
+		Data:    "",
+		Error:   "",
+	})
+}
+
+func (s *Server) getSubscriptions(w http.ResponseWriter, _ *http.Request) {
+	subscriptions := s.parser.Subscriptions()
+	for i := range subscriptions {
+		subscriptions[i] = checksummedSubscription(subscriptions[i])
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    subscriptions,
+		Error:   "",
+	})
+}
+
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, err := s.parser.GetSubscription(r.PathValue("address"))
+	if err != nil {
+		respond(w, http.StatusNotFound, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    checksummedSubscription(subscription),
+		Error:   "",
+	})
+}
+
+func checksummedSubscription(subscription blockparser.Subscription) blockparser.Subscription {
+	subscription.Address = blockchain.ChecksumAddress(subscription.Address)
+
+	return subscription
+}
+
+// admin requires the admin token as a bearer token.
+func (s *Server) admin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			respond(w, http.StatusUnauthorized, response{
+				Message: "",
+				Data:    "",
+				Error:   "unauthorized",
+			})
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// addWebhook registers an endpoint the activity of an address is posted to. the secret
+// signing the payloads is only returned here.
+func (s *Server) addWebhook(w http.ResponseWriter, r *http.Request) {
+	var endpoint webhook.Endpoint
+
+	if err := json.NewDecoder(r.Body).Decode(&endpoint); err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   fmt.Sprintf("invalid webhook: %v", err),
+		})
+
+		return
+	}
+
+	endpoint, err := s.webhooks.AddEndpoint(endpoint)
+	if err != nil {
+		respond(w, http.StatusBadRequest, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	endpoint.Address = blockchain.ChecksumAddress(endpoint.Address)
+
+	respond(w, http.StatusCreated, response{
+		Message: "webhook added",
+		Data:    endpoint,
+		Error:   "",
+	})
+}
+
+func (s *Server) getWebhooks(w http.ResponseWriter, _ *http.Request) {
+	endpoints := s.webhooks.Endpoints()
+	for i := range endpoints {
+		endpoints[i].Address = blockchain.ChecksumAddress(endpoints[i].Address)
+		endpoints[i].Secret = ""
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    endpoints,
+		Error:   "",
+	})
+}
+
+func (s *Server) removeWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := s.webhooks.RemoveEndpoint(r.PathValue("id")); err != nil {
+		respond(w, http.StatusNotFound, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusOK, response{
+		Message: "webhook removed",
 		Data:    "",
 		Error:   "",
 	})
 }
+
+// getDeadLetters returns the webhook deliveries that exhausted their attempts.
+func (s *Server) getDeadLetters(w http.ResponseWriter, _ *http.Request) {
+	respond(w, http.StatusOK, response{
+		Message: "success",
+		Data:    s.webhooks.DeadLetters(),
+		Error:   "",
+	})
+}
+
+// redeliverWebhook queues a dead lettered delivery again.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.webhooks.Redeliver(r.PathValue("id"))
+	if err != nil {
+		respond(w, http.StatusNotFound, response{
+			Message: "",
+			Data:    "",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	respond(w, http.StatusAccepted, response{
+		Message: "delivery queued",
+		Data:    delivery,
+		Error:   "",
+	})
+}
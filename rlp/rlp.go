@@ -0,0 +1,256 @@
+// Package rlp implements ethereum's recursive length prefix (RLP) serialization.
+//
+// An RLP item is either a string (a byte slice) or a list of items. Encode accepts
+// byte slices, strings, unsigned integers, *big.Int, RawValue and []any lists of
+// those. Decode returns []byte for strings and []any for lists.
+package rlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrUnsupportedType = errors.New("rlp: unsupported type")
+	ErrNegativeInteger = errors.New("rlp: cannot encode negative integer")
+	ErrUnexpectedEOF   = errors.New("rlp: unexpected end of input")
+	ErrTrailingBytes   = errors.New("rlp: trailing bytes after item")
+	ErrNonCanonical    = errors.New("rlp: non-canonical encoding")
+	ErrExpectedString  = errors.New("rlp: expected string")
+	ErrExpectedList    = errors.New("rlp: expected list")
+)
+
+const (
+	shortStringOffset = 0x80
+	longStringOffset  = 0xb7
+	shortListOffset   = 0xc0
+	longListOffset    = 0xf7
+	maxShortLength    = 55
+)
+
+// RawValue is an already RLP encoded item that is copied into the output as is.
+type RawValue []byte
+
+// EmptyString is the encoding of the empty string (and of the integer 0).
+var EmptyString = []byte{shortStringOffset}
+
+// EmptyList is the encoding of the empty list.
+var EmptyList = []byte{shortListOffset}
+
+// Encode returns the RLP encoding of item.
+func Encode(item any) ([]byte, error) {
+	return appendItem(nil, item)
+}
+
+func appendItem(dst []byte, item any) ([]byte, error) {
+	switch value := item.(type) {
+	case RawValue:
+		return append(dst, value...), nil
+	case []byte:
+		return appendString(dst, value), nil
+	case string:
+		return appendString(dst, []byte(value)), nil
+	case uint64:
+		return appendString(dst, uintBytes(value)), nil
+	case uint:
+		return appendString(dst, uintBytes(uint64(value))), nil
+	case uint8:
+		return appendString(dst, uintBytes(uint64(value))), nil
+	case int:
+		if value < 0 {
+			return nil, ErrNegativeInteger
+		}
+
+		return appendString(dst, uintBytes(uint64(value))), nil
+	case *big.Int:
+		if value == nil {
+			return appendString(dst, nil), nil
+		}
+
+		if value.Sign() < 0 {
+			return nil, ErrNegativeInteger
+		}
+
+		return appendString(dst, value.Bytes()), nil
+	case []any:
+		return appendList(dst, value)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, item)
+	}
+}
+
+func appendList(dst []byte, items []any) ([]byte, error) {
+	var (
+		payload []byte
+		err     error
+	)
+
+	for _, item := range items {
+		payload, err = appendItem(payload, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dst = appendHeader(dst, shortListOffset, longListOffset, len(payload))
+
+	return append(dst, payload...), nil
+}
+
+func appendString(dst []byte, value []byte) []byte {
+	if len(value) == 1 && value[0] < shortStringOffset {
+		return append(dst, value[0])
+	}
+
+	dst = appendHeader(dst, shortStringOffset, longStringOffset, len(value))
+
+	return append(dst, value...)
+}
+
+func appendHeader(dst []byte, shortOffset, longOffset byte, length int) []byte {
+	if length <= maxShortLength {
+		return append(dst, shortOffset+byte(length))
+	}
+
+	lengthBytes := uintBytes(uint64(length))
+	dst = append(dst, longOffset+byte(len(lengthBytes)))
+
+	return append(dst, lengthBytes...)
+}
+
+// uintBytes returns the minimal big endian encoding of value (empty for zero).
+func uintBytes(value uint64) []byte {
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], value)
+
+	i := 0
+	for i < len(buf) && buf[i] == 0 {
+		i++
+	}
+
+	return buf[i:]
+}
+
+// Decode decodes a single RLP item that must span all of data.
+func Decode(data []byte) (any, error) {
+	item, rest, err := decodeItem(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrTrailingBytes
+	}
+
+	return item, nil
+}
+
+// DecodeList decodes data that must be the encoding of a list.
+func DecodeList(data []byte) ([]any, error) {
+	item, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := item.([]any)
+	if !ok {
+		return nil, ErrExpectedList
+	}
+
+	return list, nil
+}
+
+func decodeItem(data []byte) (any, []byte, error) {
+	isList, payload, rest, err := split(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !isList {
+		return payload, rest, nil
+	}
+
+	items := make([]any, 0)
+
+	for len(payload) > 0 {
+		var item any
+
+		item, payload, err = decodeItem(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rest, nil
+}
+
+// split reads the header of the first item of data and returns its payload and the bytes after it.
+func split(data []byte) (bool, []byte, []byte, error) {
+	if len(data) == 0 {
+		return false, nil, nil, ErrUnexpectedEOF
+	}
+
+	prefix := data[0]
+
+	switch {
+	case prefix < shortStringOffset:
+		return false, data[:1], data[1:], nil
+	case prefix <= longStringOffset:
+		payload, rest, err := readPayload(data[1:], int(prefix-shortStringOffset))
+		if err == nil && len(payload) == 1 && payload[0] < shortStringOffset {
+			return false, nil, nil, fmt.Errorf("%w: single byte encoded as string", ErrNonCanonical)
+		}
+
+		return false, payload, rest, err
+	case prefix < shortListOffset:
+		payload, rest, err := readLongPayload(data[1:], int(prefix-longStringOffset))
+
+		return false, payload, rest, err
+	case prefix <= longListOffset:
+		payload, rest, err := readPayload(data[1:], int(prefix-shortListOffset))
+
+		return true, payload, rest, err
+	default:
+		payload, rest, err := readLongPayload(data[1:], int(prefix-longListOffset))
+
+		return true, payload, rest, err
+	}
+}
+
+func readLongPayload(data []byte, lengthSize int) ([]byte, []byte, error) {
+	if len(data) < lengthSize {
+		return nil, nil, ErrUnexpectedEOF
+	}
+
+	if data[0] == 0 {
+		return nil, nil, fmt.Errorf("%w: leading zero in length", ErrNonCanonical)
+	}
+
+	var buf [8]byte
+
+	copy(buf[8-lengthSize:], data[:lengthSize])
+
+	length := binary.BigEndian.Uint64(buf[:])
+	if length <= maxShortLength {
+		return nil, nil, fmt.Errorf("%w: long form used for short item", ErrNonCanonical)
+	}
+
+	if length > uint64(len(data)-lengthSize) {
+		return nil, nil, ErrUnexpectedEOF
+	}
+
+	return readPayload(data[lengthSize:], int(length))
+}
+
+func readPayload(data []byte, length int) ([]byte, []byte, error) {
+	if len(data) < length {
+		return nil, nil, ErrUnexpectedEOF
+	}
+
+	return data[:length], data[length:], nil
+}
@@ -0,0 +1,104 @@
+package rlp
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+const loremIpsum = "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		item any
+		want string
+	}{
+		{name: "empty string", item: "", want: "80"},
+		{name: "short string", item: "dog", want: "83646f67"},
+		{name: "long string", item: loremIpsum, want: "b838" + hex.EncodeToString([]byte(loremIpsum))},
+		{name: "single byte", item: []byte{0x0f}, want: "0f"},
+		{name: "zero", item: uint64(0), want: "80"},
+		{name: "small integer", item: uint64(15), want: "0f"},
+		{name: "integer", item: uint64(1024), want: "820400"},
+		{name: "big integer", item: new(big.Int).Lsh(big.NewInt(1), 64), want: "89010000000000000000"},
+		{name: "empty list", item: []any{}, want: "c0"},
+		{name: "list of strings", item: []any{"cat", "dog"}, want: "c88363617483646f67"},
+		{
+			name: "set theoretic representation of three",
+			item: []any{[]any{}, []any{[]any{}}, []any{[]any{}, []any{[]any{}}}},
+			want: "c7c0c1c0c3c0c1c0",
+		},
+		{name: "raw value", item: []any{RawValue{0xc0}, "a"}, want: "c2c061"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.item)
+			if err != nil {
+				t.Fatalf("Encode() error = %v, want nil", err)
+			}
+
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Encode() = %x, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{name: "empty string", input: "80", want: []byte{}},
+		{name: "short string", input: "83646f67", want: []byte("dog")},
+		{name: "long string", input: "b838" + hex.EncodeToString([]byte(loremIpsum)), want: []byte(loremIpsum)},
+		{name: "list of strings", input: "c88363617483646f67", want: []any{[]byte("cat"), []byte("dog")}},
+		{
+			name:  "nested lists",
+			input: "c7c0c1c0c3c0c1c0",
+			want:  []any{[]any{}, []any{[]any{}}, []any{[]any{}, []any{[]any{}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+
+			got, err := Decode(input)
+			if err != nil {
+				t.Fatalf("Decode() error = %v, want nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "empty input", input: "", wantErr: ErrUnexpectedEOF},
+		{name: "truncated string", input: "83646f", wantErr: ErrUnexpectedEOF},
+		{name: "trailing bytes", input: "0f00", wantErr: ErrTrailingBytes},
+		{name: "single byte as string", input: "8105", wantErr: ErrNonCanonical},
+		{name: "long form for short string", input: "b803646f67", wantErr: ErrNonCanonical},
+		{name: "truncated list", input: "c88363617483646f", wantErr: ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+
+			if _, err := Decode(input); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxAttempts = 8
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = 10 * time.Minute
+	defaultQueueSize   = 256
+)
+
+// Logger is an interface for logging.
+type Logger interface {
+	Error(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+type Config struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	queueSize   int
+	deadLetters DeadLetterStore
+	logger      Logger
+}
+
+type ConfigOptionResolver func(*Config)
+
+func LoadDefaultConfig(config *Config) {
+	if config.client == nil {
+		config.client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	if config.maxAttempts <= 0 {
+		config.maxAttempts = defaultMaxAttempts
+	}
+
+	if config.backoff <= 0 {
+		config.backoff = defaultBackoff
+	}
+
+	if config.maxBackoff < config.backoff {
+		config.maxBackoff = max(defaultMaxBackoff, config.backoff)
+	}
+
+	if config.queueSize <= 0 {
+		config.queueSize = defaultQueueSize
+	}
+
+	if config.deadLetters == nil {
+		config.deadLetters = NewMemoryDeadLetterStore()
+	}
+
+	if config.logger == nil {
+		config.logger = slog.Default()
+	}
+}
+
+// WithHTTPClient sets the client used to post payloads to endpoints.
+func WithHTTPClient(client *http.Client) ConfigOptionResolver {
+	return func(c *Config) {
+		c.client = client
+	}
+}
+
+// WithMaxAttempts sets how many times a payload is posted before it is dead lettered.
+func WithMaxAttempts(attempts int) ConfigOptionResolver {
+	return func(c *Config) {
+		c.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a failed delivery, doubled after
+// every failed retry up to maxBackoff.
+func WithBackoff(backoff, maxBackoff time.Duration) ConfigOptionResolver {
+	return func(c *Config) {
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithQueueSize sets how many deliveries are queued per endpoint. deliveries to an endpoint
+// whose queue is full are dead lettered instead of waiting.
+func WithQueueSize(size int) ConfigOptionResolver {
+	return func(c *Config) {
+		c.queueSize = size
+	}
+}
+
+// WithDeadLetterStore sets where the deliveries that exhausted their attempts are kept.
+func WithDeadLetterStore(store DeadLetterStore) ConfigOptionResolver {
+	return func(c *Config) {
+		c.deadLetters = store
+	}
+}
+
+func WithLogger(logger Logger) ConfigOptionResolver {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
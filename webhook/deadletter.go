@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"sort"
+	"sync"
+)
+
+// DeadLetterStore is an interface for storing the deliveries that exhausted their attempts
+// until they are redelivered.
+type DeadLetterStore interface {
+	Add(delivery Delivery) error
+	List() []Delivery
+	// Remove returns the removed delivery, false when there is no delivery with the id.
+	Remove(id string) (Delivery, bool)
+}
+
+type memoryDeadLetterStore struct {
+	mu         sync.Mutex
+	deliveries map[string]Delivery
+}
+
+// NewMemoryDeadLetterStore creates a dead letter store that keeps the deliveries in memory.
+func NewMemoryDeadLetterStore() DeadLetterStore {
+	return &memoryDeadLetterStore{deliveries: make(map[string]Delivery)}
+}
+
+func (s *memoryDeadLetterStore) Add(delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries[delivery.ID] = delivery
+
+	return nil
+}
+
+// List returns the dead lettered deliveries, oldest first.
+func (s *memoryDeadLetterStore) List() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(s.deliveries))
+	for _, delivery := range s.deliveries {
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+
+	return deliveries
+}
+
+func (s *memoryDeadLetterStore) Remove(id string) (Delivery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if ok {
+		delete(s.deliveries, id)
+	}
+
+	return delivery, ok
+}
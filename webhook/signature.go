@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader is the header carrying the signature of the payload.
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader is the header carrying the unix time the payload was signed at.
+	TimestampHeader = "X-Webhook-Timestamp"
+	// DeliveryHeader is the header carrying the id of the delivery, the same for every attempt.
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("expired webhook signature")
+)
+
+// Sign returns the signature of a payload sent at the given unix time: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret of the endpoint, prefixed with
+// "sha256=". signing the timestamp lets receivers reject replayed payloads.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a payload received by an endpoint.
+// payloads signed more than tolerance ago are rejected, a tolerance of 0 accepts any time.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, timestamp)
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("%w: unsupported scheme", ErrInvalidSignature)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, signedAt, body))) {
+		return ErrInvalidSignature
+	}
+
+	if age := time.Since(time.Unix(signedAt, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return fmt.Errorf("%w: signed %s ago", ErrExpiredSignature, age.Round(time.Second))
+	}
+
+	return nil
+}
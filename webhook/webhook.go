@@ -0,0 +1,481 @@
+// Package webhook delivers the activity matched by the block parser to customer endpoints
+// as signed JSON payloads, retrying failed deliveries and keeping the ones that exhausted
+// their attempts in a dead letter store until they are redelivered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+	"github.com/spankie/tw-interview/blockparser"
+)
+
+var (
+	ErrInvalidEndpoint   = errors.New("invalid webhook endpoint")
+	ErrEndpointNotFound  = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound  = errors.New("dead lettered delivery not found")
+	ErrQueueFull         = errors.New("webhook endpoint queue is full")
+	ErrDispatcherStopped = errors.New("webhook dispatcher was stopped")
+	errEndpointRemoved   = errors.New("endpoint was removed")
+)
+
+// Endpoint is a URL the activity of a subscribed address is posted to.
+type Endpoint struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	URL     string `json:"url"`
+	// Secret keys the signature of the payloads, generated when the endpoint is added without one.
+	Secret string `json:"secret,omitempty"`
+	// Direction only posts activity in the direction when it is set, e.g. incoming transfers.
+	Direction blockparser.Direction `json:"direction,omitempty"`
+	// ChainID only posts activity on the chain when it is set, activity on every chain otherwise.
+	ChainID   uint64    `json:"chainId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Payload is the JSON body posted to endpoints. the activity carries the transaction and
+// its confirmations and status when it was matched.
+type Payload struct {
+	// ID is the id of the delivery, receivers can use it to ignore duplicate deliveries.
+	ID          string                `json:"id"`
+	Event       blockparser.EventKind `json:"event"`
+	ChainID     uint64                `json:"chainId,omitempty"`
+	Address     string                `json:"address"`
+	BlockNumber int64                 `json:"blockNumber"`
+	BlockHash   string                `json:"blockHash"`
+	Activity    *blockparser.Activity `json:"activity"`
+	CreatedAt   time.Time             `json:"createdAt"`
+}
+
+// Delivery is a payload to post to an endpoint and the outcome of its attempts.
+type Delivery struct {
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpointId"`
+	URL            string          `json:"url"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"lastError,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Dispatcher posts the activity published by the parser to the endpoints registered for
+// the addresses involved. each endpoint has its own bounded queue and sender, so a slow or
+// failing endpoint only holds back its own deliveries.
+type Dispatcher struct {
+	config Config
+
+	mu        sync.RWMutex
+	endpoints map[string]Endpoint
+	queues    map[string]*endpointQueue
+
+	started   chan struct{}
+	stopped   chan struct{}
+	startOnce sync.Once
+}
+
+// endpointQueue holds the deliveries of an endpoint until they are posted.
+type endpointQueue struct {
+	deliveries chan Delivery
+	removed    chan struct{}
+}
+
+// NewDispatcher creates a webhook dispatcher. deliveries are posted once it is started.
+func NewDispatcher(cfgOpts ...ConfigOptionResolver) *Dispatcher {
+	cfg := Config{}
+
+	for _, opt := range cfgOpts {
+		opt(&cfg)
+	}
+
+	LoadDefaultConfig(&cfg)
+
+	return &Dispatcher{
+		config:    cfg,
+		endpoints: make(map[string]Endpoint),
+		queues:    make(map[string]*endpointQueue),
+		started:   make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+// Start starts posting deliveries until the context is done. the deliveries still queued
+// or waiting for a retry then are dead lettered, so they can be redelivered.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.startOnce.Do(func() {
+		close(d.started)
+
+		go func() {
+			<-ctx.Done()
+			close(d.stopped)
+		}()
+	})
+}
+
+// Observe feeds the dispatcher with the activity matched by the parser. scanning waits for
+// the dispatcher to queue the deliveries rather than dropping them, which never waits for
+// endpoints (see Handle). calling the returned function stops feeding the dispatcher. one
+// dispatcher can observe the parsers of several chains, payloads carry the chain id of their
+// parser.
+func (d *Dispatcher) Observe(parser blockparser.BlockParser) func() {
+	return parser.OnEvent(d.Handle, blockparser.ForEvents(blockparser.EventActivityMatched),
+		blockparser.OnSlowConsumer(blockparser.BlockOnSlowConsumer))
+}
+
+// AddEndpoint registers an endpoint for the activity of an address.
+func (d *Dispatcher) AddEndpoint(endpoint Endpoint) (Endpoint, error) {
+	address, err := blockchain.NormalizeAddress(endpoint.Address)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("%w: %w", ErrInvalidEndpoint, err)
+	}
+
+	target, err := url.Parse(endpoint.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Endpoint{}, fmt.Errorf("%w: url %q", ErrInvalidEndpoint, endpoint.URL)
+	}
+
+	switch endpoint.Direction {
+	case "", blockparser.DirectionIn, blockparser.DirectionOut, blockparser.DirectionSelf:
+	default:
+		return Endpoint{}, fmt.Errorf("%w: direction %q", ErrInvalidEndpoint, endpoint.Direction)
+	}
+
+	endpoint.ID = newID()
+	endpoint.Address = address
+	endpoint.CreatedAt = time.Now().UTC()
+
+	if endpoint.Secret == "" {
+		endpoint.Secret = newID() + newID()
+	}
+
+	queue := &endpointQueue{
+		deliveries: make(chan Delivery, d.config.queueSize),
+		removed:    make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.endpoints[endpoint.ID] = endpoint
+	d.queues[endpoint.ID] = queue
+
+	go d.work(queue)
+
+	return endpoint, nil
+}
+
+// RemoveEndpoint unregisters an endpoint. its pending deliveries are dead lettered.
+func (d *Dispatcher) RemoveEndpoint(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.endpoints[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+
+	close(d.queues[id].removed)
+	delete(d.endpoints, id)
+	delete(d.queues, id)
+
+	return nil
+}
+
+// Endpoints returns the registered endpoints, oldest first.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	endpoints := make([]Endpoint, 0, len(d.endpoints))
+	for _, endpoint := range d.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt) })
+
+	return endpoints
+}
+
+// DeadLetters returns the deliveries that exhausted their attempts.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	return d.config.deadLetters.List()
+}
+
+// Redeliver queues a dead lettered delivery again with a fresh set of attempts. it returns
+// an error and keeps the delivery dead lettered when it cannot be queued.
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	delivery, ok := d.config.deadLetters.Remove(id)
+	if !ok {
+		return Delivery{}, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.LastStatusCode = 0
+
+	if err := d.enqueue(delivery); err != nil {
+		return Delivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// Handle queues a delivery of the matched activity of the event to every endpoint
+// registered for its address. it never waits for an endpoint: deliveries that do not fit in
+// the queue of their endpoint are dead lettered.
+func (d *Dispatcher) Handle(event blockparser.Event) {
+	if event.Kind != blockparser.EventActivityMatched || event.Activity == nil {
+		return
+	}
+
+	for _, endpoint := range d.endpointsFor(event.ChainID, event.Address, event.Activity.Direction) {
+		payload := Payload{
+			ID:          newID(),
+			Event:       event.Kind,
+			ChainID:     event.ChainID,
+			Address:     blockchain.ChecksumAddress(event.Address),
+			BlockNumber: event.BlockNumber,
+			BlockHash:   event.BlockHash,
+			Activity:    event.Activity,
+			CreatedAt:   event.Time,
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			d.config.logger.Error(fmt.Sprintf("could not encode webhook payload for %s: %v", endpoint.URL, err))
+			continue
+		}
+
+		_ = d.enqueue(Delivery{
+			ID:         payload.ID,
+			EndpointID: endpoint.ID,
+			URL:        endpoint.URL,
+			Payload:    body,
+			CreatedAt:  time.Now().UTC(),
+		})
+	}
+}
+
+func (d *Dispatcher) endpointsFor(chainID uint64, address string, direction blockparser.Direction) []Endpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	endpoints := make([]Endpoint, 0)
+
+	for _, endpoint := range d.endpoints {
+		if endpoint.Address != address || (endpoint.ChainID != 0 && endpoint.ChainID != chainID) {
+			continue
+		}
+
+		if endpoint.Direction != "" && direction != endpoint.Direction && direction != blockparser.DirectionSelf {
+			continue
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+func (d *Dispatcher) endpoint(id string) (Endpoint, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	endpoint, ok := d.endpoints[id]
+
+	return endpoint, ok
+}
+
+// enqueue queues the delivery to its endpoint without waiting. the delivery is dead
+// lettered when the endpoint was removed, its queue is full or the dispatcher was stopped.
+func (d *Dispatcher) enqueue(delivery Delivery) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	queue, ok := d.queues[delivery.EndpointID]
+	if !ok {
+		delivery.LastError = errEndpointRemoved.Error()
+		d.deadLetter(delivery)
+
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, delivery.EndpointID)
+	}
+
+	select {
+	case <-d.stopped:
+		delivery.LastError = ErrDispatcherStopped.Error()
+		d.deadLetter(delivery)
+
+		return fmt.Errorf("%w: %s", ErrDispatcherStopped, delivery.URL)
+	default:
+	}
+
+	select {
+	case queue.deliveries <- delivery:
+		return nil
+	default:
+		delivery.LastError = ErrQueueFull.Error()
+		d.deadLetter(delivery)
+
+		return fmt.Errorf("%w: %s", ErrQueueFull, delivery.URL)
+	}
+}
+
+// work posts the deliveries of an endpoint one at a time once the dispatcher is started,
+// until the dispatcher is stopped or the endpoint removed. the deliveries left in the queue
+// are then dead lettered.
+func (d *Dispatcher) work(queue *endpointQueue) {
+	select {
+	case <-d.started:
+	case <-queue.removed:
+		d.drain(queue, errEndpointRemoved)
+		return
+	}
+
+	for {
+		select {
+		case delivery := <-queue.deliveries:
+			d.attempt(queue, delivery)
+		case <-queue.removed:
+			d.drain(queue, errEndpointRemoved)
+			return
+		case <-d.stopped:
+			d.drain(queue, ErrDispatcherStopped)
+			return
+		}
+	}
+}
+
+// drain dead letters the deliveries left in the queue with the reason they were not posted.
+// deliveries are not queued while it drains, and none are queued once the endpoint is removed
+// or the dispatcher stopped.
+func (d *Dispatcher) drain(queue *endpointQueue, reason error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		select {
+		case delivery := <-queue.deliveries:
+			delivery.LastError = reason.Error()
+			d.deadLetter(delivery)
+		default:
+			return
+		}
+	}
+}
+
+// attempt posts the delivery until it succeeds, retrying it with a backoff, and dead letters
+// it once it exhausted its attempts, its endpoint was removed or the dispatcher was stopped
+// while it waited for a retry.
+func (d *Dispatcher) attempt(queue *endpointQueue, delivery Delivery) {
+	for {
+		endpoint, ok := d.endpoint(delivery.EndpointID)
+		if !ok {
+			delivery.LastError = errEndpointRemoved.Error()
+			d.deadLetter(delivery)
+
+			return
+		}
+
+		delivery.Attempts++
+
+		statusCode, err := d.post(endpoint, delivery)
+		if err == nil {
+			return
+		}
+
+		delivery.LastError = err.Error()
+		delivery.LastStatusCode = statusCode
+
+		if delivery.Attempts >= d.config.maxAttempts {
+			d.deadLetter(delivery)
+			return
+		}
+
+		delay := d.retryDelay(delivery.Attempts)
+		d.config.logger.Warn(fmt.Sprintf("webhook delivery %s to %s failed (attempt %d), retrying in %s: %v",
+			delivery.ID, delivery.URL, delivery.Attempts, delay, err))
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-queue.removed:
+			timer.Stop()
+		case <-d.stopped:
+			timer.Stop()
+			d.deadLetter(delivery)
+
+			return
+		}
+	}
+}
+
+// retryDelay doubles the backoff with every failed attempt, up to the maximum backoff.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.backoff
+
+	for i := 1; i < attempts && delay < d.config.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.config.maxBackoff)
+}
+
+func (d *Dispatcher) deadLetter(delivery Delivery) {
+	d.config.logger.Error(fmt.Sprintf("webhook delivery %s to %s dead lettered after %d attempts: %s",
+		delivery.ID, delivery.URL, delivery.Attempts, delivery.LastError))
+
+	if err := d.config.deadLetters.Add(delivery); err != nil {
+		d.config.logger.Error(fmt.Sprintf("could not dead letter webhook delivery %s: %v", delivery.ID, err))
+	}
+}
+
+// post signs and posts the payload of the delivery to the endpoint. responses other than
+// 2xx are failures.
+func (d *Dispatcher) post(endpoint Endpoint, delivery Delivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("could not create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, delivery.Payload))
+
+	res, err := d.config.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("could not post webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	// drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("endpoint responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
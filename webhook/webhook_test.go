@@ -0,0 +1,411 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spankie/tw-interview/blockchain"
+	"github.com/spankie/tw-interview/blockparser"
+)
+
+const (
+	sender    = "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d"
+	recipient = "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb"
+)
+
+var transfer = blockchain.Transaction{
+	Hash:  "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+	From:  sender,
+	To:    recipient,
+	Value: "0xf3dbb76162000",
+}
+
+// receiver is a local endpoint that fails the first failures requests and records the
+// payloads it accepts with their verification error.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	requests int
+	payloads []Payload
+	errs     []error
+	received chan struct{}
+}
+
+func newReceiver(t *testing.T, failures int) (*receiver, *httptest.Server) {
+	t.Helper()
+
+	r := &receiver{failures: failures, received: make(chan struct{}, 16)}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return r, server
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.payloads = append(r.payloads, payload)
+	r.errs = append(r.errs, Verify(r.secret, req.Header.Get(SignatureHeader), req.Header.Get(TimestampHeader),
+		body, time.Minute))
+
+	if req.Header.Get(DeliveryHeader) != payload.ID {
+		r.errs[len(r.errs)-1] = errors.New("delivery header does not match the payload id")
+	}
+
+	r.received <- struct{}{}
+}
+
+func (r *receiver) wait(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-r.received:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the endpoint did not receive the payload")
+	}
+}
+
+func newTestDispatcher(t *testing.T, opts ...ConfigOptionResolver) *Dispatcher {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dispatcher := NewDispatcher(append([]ConfigOptionResolver{
+		WithBackoff(time.Millisecond, 4*time.Millisecond),
+	}, opts...)...)
+	dispatcher.Start(ctx)
+
+	return dispatcher
+}
+
+func matchedEvent(address string, direction blockparser.Direction) blockparser.Event {
+	transaction := transfer
+
+	return blockparser.Event{
+		Kind:        blockparser.EventActivityMatched,
+		Time:        time.Now().UTC(),
+		BlockNumber: 436,
+		BlockHash:   "0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae",
+		Address:     address,
+		Activity: &blockparser.Activity{
+			Kind:          blockparser.ActivityKindTransaction,
+			BlockNumber:   436,
+			Transaction:   &transaction,
+			Direction:     direction,
+			Confirmations: 1,
+			Status:        blockparser.StatusUnconfirmed,
+		},
+	}
+}
+
+func TestDispatcherDeliversSignedPayloads(t *testing.T) {
+	endpointReceiver, server := newReceiver(t, 2)
+	dispatcher := newTestDispatcher(t)
+
+	endpoint, err := dispatcher.AddEndpoint(Endpoint{Address: recipient, URL: server.URL,
+		Direction: blockparser.DirectionIn})
+	if err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	endpointReceiver.secret = endpoint.Secret
+
+	// only the incoming transfer of the recipient is posted.
+	dispatcher.Handle(matchedEvent(sender, blockparser.DirectionOut))
+	dispatcher.Handle(matchedEvent(recipient, blockparser.DirectionOut))
+	dispatcher.Handle(matchedEvent(recipient, blockparser.DirectionIn))
+	endpointReceiver.wait(t)
+
+	endpointReceiver.mu.Lock()
+	defer endpointReceiver.mu.Unlock()
+
+	if endpointReceiver.requests != 3 || len(endpointReceiver.payloads) != 1 {
+		t.Fatalf("endpoint received %d requests and %d payloads, want 3 and 1 after 2 retries",
+			endpointReceiver.requests, len(endpointReceiver.payloads))
+	}
+
+	if err := endpointReceiver.errs[0]; err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+
+	payload := endpointReceiver.payloads[0]
+	if payload.Address != blockchain.ChecksumAddress(recipient) || payload.Activity.Transaction.Hash != transfer.Hash ||
+		payload.Activity.Status != blockparser.StatusUnconfirmed || payload.Activity.Confirmations != 1 {
+		t.Errorf("payload = %+v, want the transfer to the recipient with its confirmations", payload)
+	}
+}
+
+func TestDispatcherDeadLettersAndRedelivers(t *testing.T) {
+	endpointReceiver, server := newReceiver(t, 3)
+	dispatcher := newTestDispatcher(t, WithMaxAttempts(3))
+
+	endpoint, err := dispatcher.AddEndpoint(Endpoint{Address: sender, URL: server.URL, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	endpointReceiver.secret = endpoint.Secret
+
+	dispatcher.Handle(matchedEvent(sender, blockparser.DirectionOut))
+
+	var deadLetters []Delivery
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline) && len(deadLetters) == 0; {
+		time.Sleep(time.Millisecond)
+
+		deadLetters = dispatcher.DeadLetters()
+	}
+
+	if len(deadLetters) != 1 || deadLetters[0].Attempts != 3 ||
+		deadLetters[0].LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("DeadLetters() = %+v, want the delivery after 3 attempts", deadLetters)
+	}
+
+	if _, err := dispatcher.Redeliver("unknown"); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("Redeliver() error = %v, want %v", err, ErrDeliveryNotFound)
+	}
+
+	if _, err := dispatcher.Redeliver(deadLetters[0].ID); err != nil {
+		t.Fatalf("Redeliver() error = %v, want nil", err)
+	}
+
+	endpointReceiver.wait(t)
+
+	if len(dispatcher.DeadLetters()) != 0 {
+		t.Errorf("DeadLetters() = %v, want none after redelivery", dispatcher.DeadLetters())
+	}
+
+	endpointReceiver.mu.Lock()
+	defer endpointReceiver.mu.Unlock()
+
+	if len(endpointReceiver.payloads) != 1 || endpointReceiver.payloads[0].ID != deadLetters[0].ID ||
+		endpointReceiver.errs[0] != nil {
+		t.Errorf("endpoint received %+v (%v), want the redelivered payload", endpointReceiver.payloads, endpointReceiver.errs)
+	}
+}
+
+func TestDispatcherEndpoints(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	invalid := []Endpoint{
+		{Address: "0xabc", URL: "https://example.com"},
+		{Address: sender, URL: "ftp://example.com"},
+		{Address: sender, URL: "https://example.com", Direction: "sideways"},
+	}
+	for _, endpoint := range invalid {
+		if _, err := dispatcher.AddEndpoint(endpoint); !errors.Is(err, ErrInvalidEndpoint) {
+			t.Errorf("AddEndpoint(%+v) error = %v, want %v", endpoint, err, ErrInvalidEndpoint)
+		}
+	}
+
+	endpoint, err := dispatcher.AddEndpoint(Endpoint{
+		Address: blockchain.ChecksumAddress(sender),
+		URL:     "https://example.com",
+	})
+	if err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	if endpoint.Address != sender || endpoint.Secret == "" || endpoint.ID == "" {
+		t.Errorf("AddEndpoint() = %+v, want a normalized address, an id and a generated secret", endpoint)
+	}
+
+	if endpoints := dispatcher.Endpoints(); len(endpoints) != 1 {
+		t.Errorf("Endpoints() = %v, want 1 endpoint", endpoints)
+	}
+
+	if err := dispatcher.RemoveEndpoint(endpoint.ID); err != nil {
+		t.Errorf("RemoveEndpoint() error = %v, want nil", err)
+	}
+
+	if err := dispatcher.RemoveEndpoint(endpoint.ID); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("RemoveEndpoint() error = %v, want %v", err, ErrEndpointNotFound)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+	signature := Sign("secret", now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp int64
+		body      []byte
+		wantErr   error
+	}{
+		{name: "valid", secret: "secret", signature: signature, timestamp: now, body: body},
+		{name: "wrong secret", secret: "other", signature: signature, timestamp: now, body: body,
+			wantErr: ErrInvalidSignature},
+		{name: "tampered body", secret: "secret", signature: signature, timestamp: now, body: []byte(`{"id":"2"}`),
+			wantErr: ErrInvalidSignature},
+		{name: "tampered timestamp", secret: "secret", signature: signature, timestamp: now + 1, body: body,
+			wantErr: ErrInvalidSignature},
+		{name: "expired", secret: "secret", signature: Sign("secret", now-3600, body), timestamp: now - 3600, body: body,
+			wantErr: ErrExpiredSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, strconv.FormatInt(tt.timestamp, 10), tt.body, time.Minute)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatcherEndpointsForChain(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	anyChain, err := dispatcher.AddEndpoint(Endpoint{Address: sender, URL: "https://example.com/any"})
+	if err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	polygon, err := dispatcher.AddEndpoint(Endpoint{Address: sender, URL: "https://example.com/polygon",
+		ChainID: 137})
+	if err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		want    []string
+	}{
+		{name: "chain of the endpoint", chainID: 137, want: []string{anyChain.ID, polygon.ID}},
+		{name: "other chain", chainID: 1, want: []string{anyChain.ID}},
+		{name: "parser without chain id", chainID: 0, want: []string{anyChain.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, endpoint := range dispatcher.endpointsFor(tt.chainID, sender, blockparser.DirectionOut) {
+				got = append(got, endpoint.ID)
+			}
+
+			sort.Strings(got)
+			sort.Strings(tt.want)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("endpointsFor(%d) = %v, want %v", tt.chainID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherDoesNotWaitForStalledEndpoints(t *testing.T) {
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) { <-release }))
+	t.Cleanup(stalled.Close)
+	t.Cleanup(func() { close(release) })
+
+	endpointReceiver, server := newReceiver(t, 0)
+	dispatcher := newTestDispatcher(t, WithQueueSize(1))
+
+	if _, err := dispatcher.AddEndpoint(Endpoint{Address: sender, URL: stalled.URL}); err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	if _, err := dispatcher.AddEndpoint(Endpoint{Address: recipient, URL: server.URL, Secret: "secret"}); err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	endpointReceiver.secret = "secret"
+
+	handled := make(chan struct{})
+
+	go func() {
+		for range 5 {
+			dispatcher.Handle(matchedEvent(sender, blockparser.DirectionOut))
+		}
+
+		close(handled)
+	}()
+
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatalf("Handle() waited for the stalled endpoint")
+	}
+
+	// at most one delivery is being posted and one is queued, the others are dead lettered.
+	deadLetters := dispatcher.DeadLetters()
+	if len(deadLetters) < 3 || deadLetters[0].LastError != ErrQueueFull.Error() {
+		t.Errorf("DeadLetters() = %+v, want at least 3 deliveries dead lettered as %v", deadLetters, ErrQueueFull)
+	}
+
+	// the other endpoints are still delivered to.
+	dispatcher.Handle(matchedEvent(recipient, blockparser.DirectionIn))
+	endpointReceiver.wait(t)
+}
+
+func TestDispatcherDeadLettersQueuedDeliveriesOnStop(t *testing.T) {
+	_, server := newReceiver(t, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dispatcher := NewDispatcher(WithBackoff(time.Hour, time.Hour))
+	dispatcher.Start(ctx)
+
+	if _, err := dispatcher.AddEndpoint(Endpoint{Address: sender, URL: server.URL}); err != nil {
+		t.Fatalf("AddEndpoint() error = %v, want nil", err)
+	}
+
+	// the first delivery waits for its retry, the others are queued behind it.
+	for range 3 {
+		dispatcher.Handle(matchedEvent(sender, blockparser.DirectionOut))
+	}
+
+	cancel()
+
+	var deadLetters []Delivery
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline) && len(deadLetters) < 3; {
+		time.Sleep(time.Millisecond)
+
+		deadLetters = dispatcher.DeadLetters()
+	}
+
+	if len(deadLetters) != 3 {
+		t.Fatalf("DeadLetters() = %+v, want the 3 deliveries dead lettered on stop", deadLetters)
+	}
+
+	dispatcher.Handle(matchedEvent(sender, blockparser.DirectionOut))
+
+	deadLetters = dispatcher.DeadLetters()
+	if len(deadLetters) != 4 || deadLetters[3].LastError != ErrDispatcherStopped.Error() {
+		t.Errorf("DeadLetters() = %+v, want the delivery handled after stop dead lettered as %v",
+			deadLetters, ErrDispatcherStopped)
+	}
+}